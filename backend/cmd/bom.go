@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fasim/backend/internal/planner"
+	"github.com/spf13/cobra"
+)
+
+var (
+	bomRate float64
+)
+
+func init() {
+	bomCmd.Flags().Float64VarP(&bomRate, "rate", "r", 60, "Target production rate in items per minute")
+	rootCmd.AddCommand(bomCmd)
+}
+
+var bomCmd = &cobra.Command{
+	Use:   "bom ITEM",
+	Short: "Expand an item into its bill of materials",
+	Long: `Expand an item, given by ID or name, into the tree of intermediate
+items and raw resources it is made of, with the quantities needed per
+unit and per minute at the target rate, followed by the total of each
+raw resource.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repos, err := openRepositories()
+		if err != nil {
+			return err
+		}
+
+		item, err := findItem(cmd.Context(), repos.Items, args[0])
+		if err != nil {
+			return err
+		}
+
+		facilities, err := repos.Facilities.List(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("failed to list facilities: %w", err)
+		}
+
+		bom, err := planner.NewRecipeBook(facilities).BillOfMaterials(item.ID(), bomRate)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "%s at %.2f/min\n\n", bom.Root.Item.Name(), bom.PerMinute)
+		fmt.Fprintln(w, "ITEM\tFACILITY\tPER UNIT\tPER MINUTE")
+		printMaterialNode(w, bom.Root, 0)
+		if len(bom.RawResources) > 0 {
+			fmt.Fprintln(w, "\nRAW RESOURCE\t\tPER UNIT\tPER MINUTE")
+			for _, total := range bom.RawResources {
+				fmt.Fprintf(w, "%s\t\t%.2f\t%.2f\n", total.Item.Name(), total.PerUnit, total.PerMinute)
+			}
+		}
+		return w.Flush()
+	},
+}
+
+// printMaterialNode prints the node and its inputs, indented by their depth in the tree
+func printMaterialNode(w io.Writer, node *planner.MaterialNode, depth int) {
+	facility := "-"
+	switch {
+	case node.Loop:
+		facility = "(loop)"
+	case node.Facility != nil:
+		facility = node.Facility.Name()
+	}
+	fmt.Fprintf(w, "%s%s\t%s\t%.2f\t%.2f\n", strings.Repeat("  ", depth), node.Item.Name(), facility, node.PerUnit, node.PerMinute)
+	for _, input := range node.Inputs {
+		printMaterialNode(w, input, depth+1)
+	}
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/fasim/backend/internal/models"
+	"github.com/fasim/backend/internal/repositories"
+	"github.com/fasim/backend/internal/repositories/db"
+	"github.com/fasim/backend/internal/repositories/sqlite"
+)
+
+// openRepositories connects to the database and creates the SQLite-backed repositories
+func openRepositories() (*repositories.Repositories, error) {
+	database, err := db.New("fasim.db")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create database connection: %w", err)
+	}
+
+	return &repositories.Repositories{
+		Items:        sqlite.NewItemRepository(database),
+		Facilities:   sqlite.NewFacilityRepository(database),
+		MachineTypes: sqlite.NewMachineTypeRepository(database),
+		Recipes:      sqlite.NewRecipeRepository(database),
+		Transports:   sqlite.NewTransportRepository(database),
+		Pipelines:    sqlite.NewPipelineRepository(database),
+		Simulations:  sqlite.NewSimulationRunRepository(database),
+	}, nil
+}
+
+// findItem resolves an item given either its ID or its name
+func findItem(ctx context.Context, repo repositories.ItemRepository, ref string) (*models.Item, error) {
+	if id, err := strconv.Atoi(ref); err == nil {
+		item, err := repo.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if item != nil {
+			return item, nil
+		}
+	}
+
+	items, err := repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		if item.Name() == ref {
+			return item, nil
+		}
+	}
+	return nil, fmt.Errorf("item %q not found", ref)
+}
+
+// findPipeline resolves a pipeline given either its ID or its name
+func findPipeline(ctx context.Context, repo repositories.PipelineRepository, ref string) (*models.Pipeline, error) {
+	if id, err := strconv.Atoi(ref); err == nil {
+		pipeline, err := repo.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if pipeline != nil {
+			return pipeline, nil
+		}
+	}
+
+	pipelines, err := repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, pipeline := range pipelines {
+		if pipeline.Name() == ref {
+			return pipeline, nil
+		}
+	}
+	return nil, fmt.Errorf("pipeline %q not found", ref)
+}
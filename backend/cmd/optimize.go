@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fasim/backend/internal/optimizer"
+	"github.com/spf13/cobra"
+)
+
+var (
+	optimizeObjective string
+)
+
+func init() {
+	optimizeCmd.Flags().StringVar(&optimizeObjective, "objective", string(optimizer.ObjectiveMachines), "Quantity to minimize: machines, power or rawResources")
+	rootCmd.AddCommand(optimizeCmd)
+}
+
+var optimizeCmd = &cobra.Command{
+	Use:   "optimize ITEM=RATE...",
+	Short: "Find the cheapest facilities to produce items",
+	Long: `Find the facilities and rates producing each item, given by ID or name,
+at its target rate in items per minute while minimizing the number of
+machines, their power draw or the raw resources consumed. Alternative
+facilities producing the same item are weighed against each other.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		objective, err := optimizer.ParseObjective(optimizeObjective)
+		if err != nil {
+			return err
+		}
+
+		repos, err := openRepositories()
+		if err != nil {
+			return err
+		}
+
+		targets := make(map[int]float64, len(args))
+		for _, arg := range args {
+			separator := strings.LastIndex(arg, "=")
+			if separator < 0 {
+				return fmt.Errorf("target %q must have the form ITEM=RATE", arg)
+			}
+			rate, err := strconv.ParseFloat(arg[separator+1:], 64)
+			if err != nil {
+				return fmt.Errorf("invalid rate in target %q", arg)
+			}
+			item, err := findItem(cmd.Context(), repos.Items, arg[:separator])
+			if err != nil {
+				return err
+			}
+			targets[item.ID()] += rate
+		}
+
+		facilities, err := repos.Facilities.List(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("failed to list facilities: %w", err)
+		}
+
+		solution, err := optimizer.Optimize(facilities, targets, objective)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "TARGET\tPER MINUTE")
+		for _, target := range solution.Targets {
+			fmt.Fprintf(w, "%s\t%.2f\n", target.Item.Name(), target.PerMinute)
+		}
+		fmt.Fprintf(w, "\nMinimized %s: %.2f (%.2f machines, %.2f MW)\n\n", solution.Objective, solution.Value, solution.Machines, solution.Power)
+		fmt.Fprintln(w, "FACILITY\tCOUNT\tREQUIRED")
+		for _, demand := range solution.Facilities {
+			fmt.Fprintf(w, "%s\t%.2f\t%d\n", demand.Facility.Name(), demand.Count, demand.RequiredCount)
+		}
+		if len(solution.RawResources) > 0 {
+			fmt.Fprintln(w, "\nRAW RESOURCE\tPER MINUTE")
+			for _, demand := range solution.RawResources {
+				fmt.Fprintf(w, "%s\t%.2f\n", demand.Item.Name(), demand.PerMinute)
+			}
+		}
+		if len(solution.Byproducts) > 0 {
+			fmt.Fprintln(w, "\nBYPRODUCT\tPER MINUTE")
+			for _, byproduct := range solution.Byproducts {
+				fmt.Fprintf(w, "%s\t%.2f\n", byproduct.Item.Name(), byproduct.PerMinute)
+			}
+		}
+		return w.Flush()
+	},
+}
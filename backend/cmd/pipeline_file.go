@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/fasim/backend/internal/models"
+	"github.com/fasim/backend/internal/repositories"
+)
+
+// pipelineFile is a pipeline stored as JSON in the shape the pipelines API accepts and
+// returns, so that a pipeline fetched from the API can be simulated from a file. Facilities,
+// items and transports are referenced by their IDs in the database.
+type pipelineFile struct {
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Nodes       []pipelineFileNode `json:"nodes"`
+}
+
+// pipelineFileNode keeps its ID, which must be positive and unique within the file. An
+// omitted instance count or clock speed defaults to a single machine at normal speed.
+type pipelineFileNode struct {
+	ID            int                      `json:"id"`
+	Kind          string                   `json:"kind"`
+	FacilityID    int                      `json:"facilityId"`
+	ItemID        int                      `json:"itemId"`
+	PerMinute     float64                  `json:"perMinute"`
+	InstanceCount *int                     `json:"instanceCount"`
+	ClockSpeed    *float64                 `json:"clockSpeed"`
+	Buffers       pipelineFileBuffers      `json:"buffers"`
+	NextNodeIDs   []int                    `json:"nextNodeIds"`
+	Connections   []pipelineFileConnection `json:"connections"`
+}
+
+type pipelineFileBuffers struct {
+	Input  int `json:"input"`
+	Output int `json:"output"`
+}
+
+type pipelineFileConnection struct {
+	TargetNodeID  int     `json:"targetNodeId"`
+	ItemID        int     `json:"itemId"`
+	Ratio         float64 `json:"ratio"`
+	Priority      int     `json:"priority"`
+	MaxThroughput float64 `json:"maxThroughput"`
+	TransportID   int     `json:"transportId"`
+}
+
+// loadPipelineFile reads a pipeline from a JSON file, resolving its references through the
+// repositories. The pipeline is not stored.
+func loadPipelineFile(ctx context.Context, repos *repositories.Repositories, path string) (*models.Pipeline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file pipelineFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	nodeIDs := make(map[int]bool, len(file.Nodes))
+	for _, node := range file.Nodes {
+		if node.ID <= 0 || nodeIDs[node.ID] {
+			return nil, fmt.Errorf("node IDs must be positive and unique, got %d", node.ID)
+		}
+		nodeIDs[node.ID] = true
+	}
+
+	pipeline := models.NewPipeline(file.Name, file.Description)
+	for _, fileNode := range file.Nodes {
+		node, err := newFileNode(ctx, repos, fileNode)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, conn := range fileNode.Connections {
+			if !nodeIDs[conn.TargetNodeID] {
+				return nil, fmt.Errorf("node %d: invalid target node ID %d", fileNode.ID, conn.TargetNodeID)
+			}
+
+			var item *models.Item
+			if conn.ItemID != 0 {
+				for _, output := range node.OutputDefinitions() {
+					if output.Item().ID() == conn.ItemID {
+						item = output.Item()
+					}
+				}
+				if item == nil {
+					return nil, fmt.Errorf("node %d: item %d is not an output", fileNode.ID, conn.ItemID)
+				}
+			}
+
+			var transport *models.Transport
+			if conn.TransportID != 0 {
+				transport, err = repos.Transports.Get(ctx, conn.TransportID)
+				if err != nil {
+					return nil, err
+				}
+				if transport == nil {
+					return nil, fmt.Errorf("node %d: transport %d not found", fileNode.ID, conn.TransportID)
+				}
+			}
+
+			node.AddConnection(models.NewPipelineConnection(conn.TargetNodeID, item, conn.Ratio, conn.Priority, conn.MaxThroughput, transport))
+		}
+
+		// Pipelines returned by the API list the targets of their connections as next nodes too
+		for _, nextID := range fileNode.NextNodeIDs {
+			if !nodeIDs[nextID] {
+				return nil, fmt.Errorf("node %d: invalid next node ID %d", fileNode.ID, nextID)
+			}
+			if !slices.Contains(node.NextNodeIDs(), nextID) {
+				node.AddNextNodeID(nextID)
+			}
+		}
+		pipeline.AddNode(node)
+	}
+
+	if err := pipeline.EnsureValid(); err != nil {
+		return nil, err
+	}
+	return pipeline, nil
+}
+
+// newFileNode creates the facility, source or sink node described in a pipeline file
+func newFileNode(ctx context.Context, repos *repositories.Repositories, fileNode pipelineFileNode) (*models.PipelineNode, error) {
+	buffers := models.NewBufferCapacity(fileNode.Buffers.Input, fileNode.Buffers.Output)
+	switch kind := models.NodeKind(fileNode.Kind); kind {
+	case "", models.NodeKindFacility:
+		facility, err := repos.Facilities.Get(ctx, fileNode.FacilityID)
+		if err != nil {
+			return nil, err
+		}
+		if facility == nil {
+			return nil, fmt.Errorf("node %d: facility %d not found", fileNode.ID, fileNode.FacilityID)
+		}
+
+		instanceCount := 1
+		if fileNode.InstanceCount != nil {
+			instanceCount = *fileNode.InstanceCount
+		}
+		clockSpeed := 1.0
+		if fileNode.ClockSpeed != nil {
+			clockSpeed = *fileNode.ClockSpeed
+		}
+		return models.NewPipelineNodeFromParams(fileNode.ID, facility, instanceCount, clockSpeed, buffers, nil), nil
+	case models.NodeKindSource, models.NodeKindSink:
+		item, err := repos.Items.Get(ctx, fileNode.ItemID)
+		if err != nil {
+			return nil, err
+		}
+		if item == nil {
+			return nil, fmt.Errorf("node %d: item %d not found", fileNode.ID, fileNode.ItemID)
+		}
+		return models.NewBoundaryNodeFromParams(fileNode.ID, kind, item, fileNode.PerMinute, buffers, nil), nil
+	default:
+		return nil, fmt.Errorf("node %d: invalid node kind %q", fileNode.ID, fileNode.Kind)
+	}
+}
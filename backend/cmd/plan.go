@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fasim/backend/internal/planner"
+	"github.com/spf13/cobra"
+)
+
+var (
+	planRate float64
+)
+
+func init() {
+	planCmd.Flags().Float64VarP(&planRate, "rate", "r", 60, "Target production rate in items per minute")
+	rootCmd.AddCommand(planCmd)
+}
+
+var planCmd = &cobra.Command{
+	Use:   "plan ITEM",
+	Short: "Calculate the facilities needed to produce an item",
+	Long: `Calculate how many of each facility are needed to produce an item,
+given by ID or name, at the target rate, along with the raw resources
+consumed per minute.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repos, err := openRepositories()
+		if err != nil {
+			return err
+		}
+
+		item, err := findItem(cmd.Context(), repos.Items, args[0])
+		if err != nil {
+			return err
+		}
+
+		facilities, err := repos.Facilities.List(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("failed to list facilities: %w", err)
+		}
+
+		plan, err := planner.NewRecipeBook(facilities).Plan(item.ID(), planRate)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "%s at %.2f/min\n\n", plan.Item.Name(), plan.PerMinute)
+		fmt.Fprintln(w, "FACILITY\tCOUNT\tREQUIRED")
+		for _, demand := range plan.Facilities {
+			fmt.Fprintf(w, "%s\t%.2f\t%d\n", demand.Facility.Name(), demand.Count, demand.RequiredCount)
+		}
+		if len(plan.RawResources) > 0 {
+			fmt.Fprintln(w, "\nRAW RESOURCE\tPER MINUTE")
+			for _, demand := range plan.RawResources {
+				fmt.Fprintf(w, "%s\t%.2f\n", demand.Item.Name(), demand.PerMinute)
+			}
+		}
+		if len(plan.Byproducts) > 0 {
+			fmt.Fprintln(w, "\nBYPRODUCT\tPER MINUTE")
+			for _, byproduct := range plan.Byproducts {
+				fmt.Fprintf(w, "%s\t%.2f\n", byproduct.Item.Name(), byproduct.PerMinute)
+			}
+		}
+		if len(plan.Loops) > 0 {
+			fmt.Fprintln(w, "\nRECIPE LOOP")
+			for _, loop := range plan.Loops {
+				names := make([]string, len(loop))
+				for i, item := range loop {
+					names[i] = item.Name()
+				}
+				fmt.Fprintln(w, strings.Join(names, ", "))
+			}
+		}
+		return w.Flush()
+	},
+}
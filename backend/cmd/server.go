@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -13,6 +14,7 @@ import (
 	"github.com/fasim/backend/internal/api/routes"
 	"github.com/fasim/backend/internal/repositories/db"
 	"github.com/fasim/backend/internal/repositories/sqlite"
+	"github.com/fasim/backend/internal/runner"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/spf13/cobra"
@@ -45,6 +47,11 @@ func startServer() {
 	e.Use(middleware.Logger())
 	e.Use(middleware.Recover())
 	e.Use(middleware.TimeoutWithConfig(middleware.TimeoutConfig{
+		// Simulation runs execute in the background, but requests waiting on them and
+		// their event streams must not be cut off
+		Skipper: func(c echo.Context) bool {
+			return strings.HasPrefix(c.Path(), "/api/simulations/")
+		},
 		Timeout: 60 * time.Second,
 	}))
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
@@ -62,10 +69,29 @@ func startServer() {
 	// Initialize repositories
 	itemRepo := sqlite.NewItemRepository(database)
 	facilityRepo := sqlite.NewFacilityRepository(database)
+	machineTypeRepo := sqlite.NewMachineTypeRepository(database)
+	recipeRepo := sqlite.NewRecipeRepository(database)
+	transportRepo := sqlite.NewTransportRepository(database)
+	pipelineRepo := sqlite.NewPipelineRepository(database)
+	simulationRepo := sqlite.NewSimulationRunRepository(database)
+
+	// Runs left unfinished by a previous process can no longer complete
+	simulationRunner := runner.New(pipelineRepo, simulationRepo)
+	if err := simulationRunner.Recover(context.Background()); err != nil {
+		e.Logger.Fatal("Failed to recover simulation runs: ", err)
+	}
 
 	// Initialize handlers
 	itemHandler := handlers.NewItemHandler(itemRepo)
-	facilityHandler := handlers.NewFacilityHandler(facilityRepo, itemRepo)
+	facilityHandler := handlers.NewFacilityHandler(facilityRepo, itemRepo, machineTypeRepo, recipeRepo)
+	machineTypeHandler := handlers.NewMachineTypeHandler(machineTypeRepo)
+	recipeHandler := handlers.NewRecipeHandler(recipeRepo, itemRepo)
+	transportHandler := handlers.NewTransportHandler(transportRepo)
+	pipelineHandler := handlers.NewPipelineHandler(pipelineRepo, facilityRepo, itemRepo, transportRepo)
+	analysisHandler := handlers.NewAnalysisHandler(pipelineRepo)
+	plannerHandler := handlers.NewPlannerHandler(facilityRepo, itemRepo)
+	optimizerHandler := handlers.NewOptimizerHandler(facilityRepo, itemRepo)
+	simulationHandler := handlers.NewSimulationHandler(simulationRunner, simulationRepo, pipelineRepo)
 
 	// Route configuration
 	e.GET("/", func(c echo.Context) error {
@@ -79,6 +105,14 @@ func startServer() {
 	// Register routes
 	routes.RegisterItemRoutes(e, itemHandler)
 	routes.RegisterFacilityRoutes(e, facilityHandler)
+	routes.RegisterMachineTypeRoutes(e, machineTypeHandler)
+	routes.RegisterRecipeRoutes(e, recipeHandler)
+	routes.RegisterTransportRoutes(e, transportHandler)
+	routes.RegisterPipelineRoutes(e, pipelineHandler)
+	routes.RegisterAnalysisRoutes(e, analysisHandler)
+	routes.RegisterPlannerRoutes(e, plannerHandler)
+	routes.RegisterOptimizerRoutes(e, optimizerHandler)
+	routes.RegisterSimulationRoutes(e, simulationHandler)
 
 	// Start server
 	server := &http.Server{
@@ -101,6 +135,10 @@ func startServer() {
 	// Shutdown handling
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
+	// Runs are stopped first, which ends the event streams the server would wait for
+	if err := simulationRunner.Shutdown(ctx); err != nil {
+		e.Logger.Fatal("Failed to stop simulation runs: ", err)
+	}
 	if err := e.Shutdown(ctx); err != nil {
 		e.Logger.Fatal("Failed to shutdown server: ", err)
 	}
@@ -0,0 +1,263 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fasim/backend/internal/export"
+	"github.com/fasim/backend/internal/models"
+	"github.com/fasim/backend/internal/simulation"
+	"github.com/spf13/cobra"
+)
+
+// saturatedUtilization is the utilization from which a node counts as running at capacity
+const saturatedUtilization = 0.95
+
+var (
+	simulateDuration       time.Duration
+	simulateStep           time.Duration
+	simulateSeed           uint64
+	simulateBufferCapacity int
+	simulateFile           string
+	simulateJSON           bool
+	simulateOut            string
+	simulateFormat         string
+)
+
+func init() {
+	simulateCmd.Flags().DurationVarP(&simulateDuration, "duration", "d", time.Hour, "Simulated time to run for")
+	simulateCmd.Flags().DurationVar(&simulateStep, "step", time.Minute, "Interval between samples of the node counters")
+	simulateCmd.Flags().Uint64Var(&simulateSeed, "seed", 0, "Seed deciding the outcome of outputs with a probability")
+	simulateCmd.Flags().IntVar(&simulateBufferCapacity, "buffer", 0, "Input buffer of nodes without one of their own, 0 for unlimited")
+	simulateCmd.Flags().StringVarP(&simulateFile, "file", "f", "", "JSON file to load the pipeline from instead of the database")
+	simulateCmd.Flags().BoolVar(&simulateJSON, "json", false, "Print the summary as JSON")
+	simulateCmd.Flags().StringVarP(&simulateOut, "out", "o", "", "File to export the sampled counters to")
+	simulateCmd.Flags().StringVar(&simulateFormat, "format", "", "Export format, csv or jsonl (default from the file extension)")
+	rootCmd.AddCommand(simulateCmd)
+}
+
+// itemRateSummary is the rate an item is produced at, in items per minute
+type itemRateSummary struct {
+	ItemID    int     `json:"itemId"`
+	Item      string  `json:"item"`
+	PerMinute float64 `json:"perMinute"`
+}
+
+// nodeSummary reports times in seconds, averaged over the machines of the node
+type nodeSummary struct {
+	NodeID      int               `json:"nodeId"`
+	Name        string            `json:"name"`
+	Cycles      int               `json:"cycles"`
+	Utilization float64           `json:"utilization"`
+	StarvedTime float64           `json:"starvedTime"`
+	BlockedTime float64           `json:"blockedTime"`
+	Throughput  []itemRateSummary `json:"throughput"`
+	Bottleneck  bool              `json:"bottleneck"`
+}
+
+// simulationSummary is the outcome of a simulation as printed by the simulate command,
+// with the duration in seconds and power in megawatts
+type simulationSummary struct {
+	PipelineID      int           `json:"pipelineId,omitempty"`
+	Pipeline        string        `json:"pipeline"`
+	Duration        float64       `json:"duration"`
+	PowerDemand     float64       `json:"powerDemand"`
+	PowerGeneration float64       `json:"powerGeneration"`
+	Nodes           []nodeSummary `json:"nodes"`
+	Bottlenecks     []int         `json:"bottlenecks"`
+}
+
+var simulateCmd = &cobra.Command{
+	Use:   "simulate [PIPELINE]",
+	Short: "Simulate a pipeline",
+	Long: `Simulate a pipeline, given by ID or name or loaded from a JSON file with
+--file, for the given duration of simulated time and print the throughput
+and utilization of every node along with the bottlenecks.
+
+Bottlenecks are the nodes running at capacity that feed no other node
+running at capacity. With --out, the per-node, per-item counters sampled
+every step are exported as CSV or JSON Lines.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if (len(args) == 1) == (simulateFile != "") {
+			return errors.New("specify either a pipeline or --file")
+		}
+
+		format := export.FormatCSV
+		if simulateFormat != "" {
+			var err error
+			if format, err = export.ParseFormat(simulateFormat); err != nil {
+				return err
+			}
+		} else if strings.EqualFold(filepath.Ext(simulateOut), ".jsonl") {
+			format = export.FormatJSONL
+		}
+
+		repos, err := openRepositories()
+		if err != nil {
+			return err
+		}
+		var pipeline *models.Pipeline
+		if simulateFile != "" {
+			pipeline, err = loadPipelineFile(cmd.Context(), repos, simulateFile)
+		} else {
+			pipeline, err = findPipeline(cmd.Context(), repos.Pipelines, args[0])
+		}
+		if err != nil {
+			return err
+		}
+
+		engine, err := simulation.New(pipeline, simulation.Config{
+			Duration:       simulateDuration,
+			BufferCapacity: simulateBufferCapacity,
+			SampleInterval: simulateStep,
+			Seed:           simulateSeed,
+		})
+		if err != nil {
+			return err
+		}
+		result, err := engine.Run(cmd.Context())
+		if err != nil {
+			return err
+		}
+
+		if simulateOut != "" {
+			if err := exportResult(result, pipeline, format); err != nil {
+				return err
+			}
+		}
+
+		summary := summarize(pipeline, result)
+		if simulateJSON {
+			encoder := json.NewEncoder(cmd.OutOrStdout())
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(summary)
+		}
+		return printSummary(cmd, summary)
+	},
+}
+
+// exportResult writes the sampled counters of the result to the --out file
+func exportResult(result *simulation.Result, pipeline *models.Pipeline, format export.Format) error {
+	file, err := os.Create(simulateOut)
+	if err != nil {
+		return err
+	}
+
+	rows := export.Rows(result.ToModel(), pipeline, 0)
+	if err := export.Write(file, format, rows); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to export %s: %w", simulateOut, err)
+	}
+	return file.Close()
+}
+
+// summarize collects the throughput, utilization and bottlenecks of a simulation
+func summarize(pipeline *models.Pipeline, result *simulation.Result) simulationSummary {
+	summary := simulationSummary{
+		PipelineID:      pipeline.ID(),
+		Pipeline:        pipeline.Name(),
+		Duration:        result.Duration.Seconds(),
+		PowerDemand:     result.PowerDemand,
+		PowerGeneration: result.PowerGeneration,
+		Nodes:           make([]nodeSummary, 0, len(result.Nodes)),
+		Bottlenecks:     make([]int, 0),
+	}
+
+	itemNames := make(map[int]string)
+	for _, node := range pipeline.Nodes() {
+		for _, output := range node.OutputDefinitions() {
+			itemNames[output.Item().ID()] = output.Item().Name()
+		}
+	}
+
+	saturated := make(map[int]bool)
+	for id, node := range result.Nodes {
+		saturated[id] = node.Utilization() >= saturatedUtilization
+	}
+
+	for id, node := range result.Nodes {
+		throughput := make([]itemRateSummary, 0, len(node.Produced))
+		for itemID, quantity := range node.Produced {
+			throughput = append(throughput, itemRateSummary{
+				ItemID:    itemID,
+				Item:      itemNames[itemID],
+				PerMinute: quantity / result.Duration.Minutes(),
+			})
+		}
+		sort.Slice(throughput, func(i, j int) bool {
+			return throughput[i].ItemID < throughput[j].ItemID
+		})
+
+		// Saturated nodes feeding a saturated node are held back by it rather than limiting the pipeline
+		bottleneck := saturated[id]
+		for _, conn := range pipeline.Nodes()[id].Connections() {
+			if saturated[conn.TargetNodeID()] {
+				bottleneck = false
+			}
+		}
+		if bottleneck {
+			summary.Bottlenecks = append(summary.Bottlenecks, id)
+		}
+
+		summary.Nodes = append(summary.Nodes, nodeSummary{
+			NodeID:      id,
+			Name:        pipeline.Nodes()[id].Name(),
+			Cycles:      node.Cycles,
+			Utilization: node.Utilization(),
+			StarvedTime: node.StarvedTime.Seconds(),
+			BlockedTime: node.BlockedTime.Seconds(),
+			Throughput:  throughput,
+			Bottleneck:  bottleneck,
+		})
+	}
+	sort.Slice(summary.Nodes, func(i, j int) bool {
+		return summary.Nodes[i].NodeID < summary.Nodes[j].NodeID
+	})
+	sort.Ints(summary.Bottlenecks)
+	return summary
+}
+
+// printSummary prints the summary as tables
+func printSummary(cmd *cobra.Command, summary simulationSummary) error {
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	duration := time.Duration(summary.Duration * float64(time.Second))
+	fmt.Fprintf(w, "%s over %s\n\n", summary.Pipeline, duration)
+	fmt.Fprintln(w, "NODE\tNAME\tCYCLES\tUTILIZATION\tSTARVED\tBLOCKED\tOUTPUT PER MINUTE")
+	for _, node := range summary.Nodes {
+		outputs := make([]string, len(node.Throughput))
+		for i, rate := range node.Throughput {
+			outputs[i] = fmt.Sprintf("%s %.2f", rate.Item, rate.PerMinute)
+		}
+		name := node.Name
+		if node.Bottleneck {
+			name += " *"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%d\t%.1f%%\t%.1f%%\t%.1f%%\t%s\n",
+			node.NodeID,
+			name,
+			node.Cycles,
+			node.Utilization*100,
+			node.StarvedTime/summary.Duration*100,
+			node.BlockedTime/summary.Duration*100,
+			strings.Join(outputs, ", "),
+		)
+	}
+
+	if len(summary.Bottlenecks) > 0 {
+		fmt.Fprintln(w, "\n* Bottleneck: running at capacity without being held back downstream")
+	} else {
+		fmt.Fprintln(w, "\nNo node runs at capacity")
+	}
+	if summary.PowerDemand > 0 || summary.PowerGeneration > 0 {
+		fmt.Fprintf(w, "Power: %.2f MW demand, %.2f MW generation\n", summary.PowerDemand, summary.PowerGeneration)
+	}
+	return w.Flush()
+}
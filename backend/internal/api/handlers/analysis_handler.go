@@ -0,0 +1,494 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/fasim/backend/internal/power"
+	"github.com/fasim/backend/internal/repositories"
+	"github.com/fasim/backend/internal/throughput"
+	"github.com/fasim/backend/internal/whatif"
+	"github.com/labstack/echo/v4"
+)
+
+type AnalysisHandler struct {
+	pipelineRepo repositories.PipelineRepository
+}
+
+func NewAnalysisHandler(pipelineRepo repositories.PipelineRepository) *AnalysisHandler {
+	return &AnalysisHandler{pipelineRepo: pipelineRepo}
+}
+
+type itemRateResponse struct {
+	ItemID    int     `json:"itemId"`
+	PerMinute float64 `json:"perMinute"`
+}
+
+// nodeRateResponse leaves out the maximum rate of sources and sinks without a rate limit
+type nodeRateResponse struct {
+	NodeID             int                `json:"nodeId"`
+	FacilityID         int                `json:"facilityId,omitempty"`
+	MaxCyclesPerMinute *float64           `json:"maxCyclesPerMinute"`
+	CyclesPerMinute    float64            `json:"cyclesPerMinute"`
+	Utilization        float64            `json:"utilization"`
+	LimitingItemID     int                `json:"limitingItemId,omitempty"`
+	LimitedByNodeID    int                `json:"limitedByNodeId"`
+	Produced           []itemRateResponse `json:"produced"`
+	Consumed           []itemRateResponse `json:"consumed"`
+}
+
+type edgeFlowResponse struct {
+	SourceNodeID int     `json:"sourceNodeId"`
+	TargetNodeID int     `json:"targetNodeId"`
+	ItemID       int     `json:"itemId"`
+	PerMinute    float64 `json:"perMinute"`
+}
+
+type linkLoadResponse struct {
+	SourceNodeID      int     `json:"sourceNodeId"`
+	TargetNodeID      int     `json:"targetNodeId"`
+	Kind              string  `json:"kind"`
+	PerMinute         float64 `json:"perMinute"`
+	CapacityPerMinute float64 `json:"capacityPerMinute"`
+	Saturated         bool    `json:"saturated"`
+}
+
+type itemBalanceResponse struct {
+	ItemID            int     `json:"itemId"`
+	ProducedPerMinute float64 `json:"producedPerMinute"`
+	ConsumedPerMinute float64 `json:"consumedPerMinute"`
+	SurplusPerMinute  float64 `json:"surplusPerMinute"`
+	DeficitPerMinute  float64 `json:"deficitPerMinute"`
+}
+
+type throughputResponse struct {
+	PipelineID     int                   `json:"pipelineId"`
+	LimitingNodeID int                   `json:"limitingNodeId"`
+	Nodes          []nodeRateResponse    `json:"nodes"`
+	Edges          []edgeFlowResponse    `json:"edges"`
+	Links          []linkLoadResponse    `json:"links"`
+	Items          []itemBalanceResponse `json:"items"`
+}
+
+// nodeProvisioningResponse leaves out the required and spare machines of nodes with unbounded supply
+type nodeProvisioningResponse struct {
+	NodeID            int                `json:"nodeId"`
+	FacilityID        int                `json:"facilityId"`
+	InstanceCount     int                `json:"instanceCount"`
+	Utilization       float64            `json:"utilization"`
+	RequiredInstances *float64           `json:"requiredInstances"`
+	SpareInstances    *float64           `json:"spareInstances"`
+	OutputGain        []itemRateResponse `json:"outputGain"`
+}
+
+type bottleneckResponse struct {
+	PipelineID     int                        `json:"pipelineId"`
+	LimitingNodeID int                        `json:"limitingNodeId"`
+	Output         []itemRateResponse         `json:"output"`
+	Nodes          []nodeProvisioningResponse `json:"nodes"`
+}
+
+type nodePowerResponse struct {
+	NodeID     int     `json:"nodeId"`
+	FacilityID int     `json:"facilityId"`
+	Demand     float64 `json:"demand"`
+	PeakDemand float64 `json:"peakDemand"`
+	Generation float64 `json:"generation"`
+}
+
+// pipelinePowerResponse reports power in megawatts
+type pipelinePowerResponse struct {
+	PipelineID   int                 `json:"pipelineId"`
+	Demand       float64             `json:"demand"`
+	PeakDemand   float64             `json:"peakDemand"`
+	Generation   float64             `json:"generation"`
+	Satisfaction float64             `json:"satisfaction"`
+	Nodes        []nodePowerResponse `json:"nodes"`
+}
+
+func toItemRateResponses(rates map[int]float64) []itemRateResponse {
+	responses := make([]itemRateResponse, 0, len(rates))
+	for itemID, perMinute := range rates {
+		responses = append(responses, itemRateResponse{ItemID: itemID, PerMinute: perMinute})
+	}
+	sort.Slice(responses, func(i, j int) bool {
+		return responses[i].ItemID < responses[j].ItemID
+	})
+	return responses
+}
+
+func toThroughputResponse(pipelineID int, result *throughput.Result) throughputResponse {
+	nodes := make([]nodeRateResponse, 0, len(result.Nodes))
+	for _, node := range result.Nodes {
+		response := nodeRateResponse{
+			NodeID:          node.NodeID,
+			FacilityID:      node.FacilityID,
+			CyclesPerMinute: node.CyclesPerMinute,
+			Utilization:     node.Utilization,
+			LimitingItemID:  node.LimitingItemID,
+			LimitedByNodeID: node.LimitedByNodeID,
+			Produced:        toItemRateResponses(node.Produced),
+			Consumed:        toItemRateResponses(node.Consumed),
+		}
+		if !math.IsInf(node.MaxCyclesPerMinute, 1) {
+			maxCyclesPerMinute := node.MaxCyclesPerMinute
+			response.MaxCyclesPerMinute = &maxCyclesPerMinute
+		}
+		nodes = append(nodes, response)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].NodeID < nodes[j].NodeID
+	})
+
+	edges := make([]edgeFlowResponse, len(result.Edges))
+	for i, edge := range result.Edges {
+		edges[i] = edgeFlowResponse{
+			SourceNodeID: edge.SourceNodeID,
+			TargetNodeID: edge.TargetNodeID,
+			ItemID:       edge.ItemID,
+			PerMinute:    edge.PerMinute,
+		}
+	}
+
+	links := make([]linkLoadResponse, len(result.Links))
+	for i, link := range result.Links {
+		links[i] = linkLoadResponse{
+			SourceNodeID:      link.SourceNodeID,
+			TargetNodeID:      link.TargetNodeID,
+			Kind:              string(link.Kind),
+			PerMinute:         link.PerMinute,
+			CapacityPerMinute: link.CapacityPerMinute,
+			Saturated:         link.Saturated,
+		}
+	}
+
+	items := make([]itemBalanceResponse, 0, len(result.Items))
+	for _, item := range result.Items {
+		items = append(items, itemBalanceResponse{
+			ItemID:            item.ItemID,
+			ProducedPerMinute: item.ProducedPerMinute,
+			ConsumedPerMinute: item.ConsumedPerMinute,
+			SurplusPerMinute:  item.SurplusPerMinute,
+			DeficitPerMinute:  item.DeficitPerMinute,
+		})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].ItemID < items[j].ItemID
+	})
+
+	return throughputResponse{
+		PipelineID:     pipelineID,
+		LimitingNodeID: result.LimitingNodeID,
+		Nodes:          nodes,
+		Edges:          edges,
+		Links:          links,
+		Items:          items,
+	}
+}
+
+// finite returns a pointer to the value, or nil if it is infinite
+func finite(value float64) *float64 {
+	if math.IsInf(value, 0) {
+		return nil
+	}
+	return &value
+}
+
+func toBottleneckResponse(pipelineID int, report *throughput.BottleneckReport) bottleneckResponse {
+	nodes := make([]nodeProvisioningResponse, len(report.Nodes))
+	for i, node := range report.Nodes {
+		nodes[i] = nodeProvisioningResponse{
+			NodeID:            node.NodeID,
+			FacilityID:        node.FacilityID,
+			InstanceCount:     node.InstanceCount,
+			Utilization:       node.Utilization,
+			RequiredInstances: finite(node.RequiredInstances),
+			SpareInstances:    finite(node.SpareInstances),
+			OutputGain:        toItemRateResponses(node.OutputGain),
+		}
+	}
+
+	return bottleneckResponse{
+		PipelineID:     pipelineID,
+		LimitingNodeID: report.LimitingNodeID,
+		Output:         toItemRateResponses(report.Output),
+		Nodes:          nodes,
+	}
+}
+
+// optionalRate parses an optional non-negative rate in units per minute from the query,
+// returning zero when it is omitted
+func optionalRate(c echo.Context, name string) (float64, error) {
+	value := c.QueryParam(name)
+	if value == "" {
+		return 0, nil
+	}
+	rate, err := strconv.ParseFloat(value, 64)
+	if err != nil || rate < 0 {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, "Invalid "+name)
+	}
+	return rate, nil
+}
+
+// Throughput handles GET /api/pipelines/:id/throughput?beltCapacity=&pipeCapacity=
+func (h *AnalysisHandler) Throughput(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid pipeline ID")
+	}
+
+	var capacity throughput.Capacity
+	if capacity.Belt, err = optionalRate(c, "beltCapacity"); err != nil {
+		return err
+	}
+	if capacity.Pipe, err = optionalRate(c, "pipeCapacity"); err != nil {
+		return err
+	}
+
+	pipeline, err := h.pipelineRepo.Get(c.Request().Context(), id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if pipeline == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Pipeline not found")
+	}
+
+	result, err := throughput.CalculateWithCapacity(pipeline, capacity)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, toThroughputResponse(pipeline.ID(), result))
+}
+
+// Bottlenecks handles GET /api/pipelines/:id/bottlenecks?beltCapacity=&pipeCapacity=
+func (h *AnalysisHandler) Bottlenecks(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid pipeline ID")
+	}
+
+	var capacity throughput.Capacity
+	if capacity.Belt, err = optionalRate(c, "beltCapacity"); err != nil {
+		return err
+	}
+	if capacity.Pipe, err = optionalRate(c, "pipeCapacity"); err != nil {
+		return err
+	}
+
+	pipeline, err := h.pipelineRepo.Get(c.Request().Context(), id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if pipeline == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Pipeline not found")
+	}
+
+	report, err := throughput.AnalyzeBottlenecks(pipeline, capacity)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, toBottleneckResponse(pipeline.ID(), report))
+}
+
+func toPipelinePowerResponse(pipelineID int, result *power.Result) pipelinePowerResponse {
+	nodes := make([]nodePowerResponse, 0, len(result.Nodes))
+	for _, node := range result.Nodes {
+		nodes = append(nodes, nodePowerResponse{
+			NodeID:     node.NodeID,
+			FacilityID: node.FacilityID,
+			Demand:     node.Demand,
+			PeakDemand: node.PeakDemand,
+			Generation: node.Generation,
+		})
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].NodeID < nodes[j].NodeID
+	})
+
+	return pipelinePowerResponse{
+		PipelineID:   pipelineID,
+		Demand:       result.Demand,
+		PeakDemand:   result.PeakDemand,
+		Generation:   result.Generation,
+		Satisfaction: result.Satisfaction,
+		Nodes:        nodes,
+	}
+}
+
+// Power handles GET /api/pipelines/:id/power
+func (h *AnalysisHandler) Power(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid pipeline ID")
+	}
+
+	pipeline, err := h.pipelineRepo.Get(c.Request().Context(), id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if pipeline == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Pipeline not found")
+	}
+
+	rates, err := throughput.Calculate(pipeline)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+	}
+	result, err := power.Calculate(pipeline, rates)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, toPipelinePowerResponse(pipeline.ID(), result))
+}
+
+type processingTimeRequest struct {
+	FacilityID     int   `json:"facilityId"`
+	ProcessingTime int64 `json:"processingTime"`
+}
+
+type addedInstancesRequest struct {
+	NodeID int `json:"nodeId"`
+	Count  int `json:"count"`
+}
+
+type removedConnectionRequest struct {
+	SourceNodeID int `json:"sourceNodeId"`
+	TargetNodeID int `json:"targetNodeId"`
+}
+
+type whatIfRequest struct {
+	ProcessingTimes    []processingTimeRequest    `json:"processingTimes"`
+	AddedInstances     []addedInstancesRequest    `json:"addedInstances"`
+	RemovedConnections []removedConnectionRequest `json:"removedConnections"`
+}
+
+type outputChangeResponse struct {
+	ItemID   int     `json:"itemId"`
+	Baseline float64 `json:"baseline"`
+	Modified float64 `json:"modified"`
+	Change   float64 `json:"change"`
+}
+
+type nodeChangeResponse struct {
+	NodeID                  int     `json:"nodeId"`
+	BaselineCyclesPerMinute float64 `json:"baselineCyclesPerMinute"`
+	CyclesPerMinute         float64 `json:"cyclesPerMinute"`
+	CyclesChange            float64 `json:"cyclesChange"`
+	BaselineUtilization     float64 `json:"baselineUtilization"`
+	Utilization             float64 `json:"utilization"`
+	UtilizationChange       float64 `json:"utilizationChange"`
+	BaselineInstanceCount   int     `json:"baselineInstanceCount"`
+	InstanceCount           int     `json:"instanceCount"`
+	BaselineLimitedByNodeID int     `json:"baselineLimitedByNodeId"`
+	LimitedByNodeID         int     `json:"limitedByNodeId"`
+}
+
+type whatIfResponse struct {
+	PipelineID             int                    `json:"pipelineId"`
+	BaselineLimitingNodeID int                    `json:"baselineLimitingNodeId"`
+	LimitingNodeID         int                    `json:"limitingNodeId"`
+	BottleneckMoved        bool                   `json:"bottleneckMoved"`
+	Output                 []outputChangeResponse `json:"output"`
+	Nodes                  []nodeChangeResponse   `json:"nodes"`
+}
+
+func (req whatIfRequest) toScenario() whatif.Scenario {
+	scenario := whatif.Scenario{
+		ProcessingTimes:    make(map[int]int64, len(req.ProcessingTimes)),
+		AddedInstances:     make(map[int]int, len(req.AddedInstances)),
+		RemovedConnections: make([]whatif.Connection, len(req.RemovedConnections)),
+	}
+	for _, change := range req.ProcessingTimes {
+		scenario.ProcessingTimes[change.FacilityID] = change.ProcessingTime
+	}
+	for _, change := range req.AddedInstances {
+		scenario.AddedInstances[change.NodeID] += change.Count
+	}
+	for i, change := range req.RemovedConnections {
+		scenario.RemovedConnections[i] = whatif.Connection{SourceNodeID: change.SourceNodeID, TargetNodeID: change.TargetNodeID}
+	}
+	return scenario
+}
+
+func toWhatIfResponse(pipelineID int, report *whatif.Report) whatIfResponse {
+	output := make([]outputChangeResponse, len(report.Output))
+	for i, change := range report.Output {
+		output[i] = outputChangeResponse{
+			ItemID:   change.ItemID,
+			Baseline: change.Baseline,
+			Modified: change.Modified,
+			Change:   change.Modified - change.Baseline,
+		}
+	}
+
+	nodes := make([]nodeChangeResponse, len(report.Nodes))
+	for i, change := range report.Nodes {
+		nodes[i] = nodeChangeResponse{
+			NodeID:                  change.NodeID,
+			BaselineCyclesPerMinute: change.BaselineCyclesPerMinute,
+			CyclesPerMinute:         change.CyclesPerMinute,
+			CyclesChange:            change.CyclesPerMinute - change.BaselineCyclesPerMinute,
+			BaselineUtilization:     change.BaselineUtilization,
+			Utilization:             change.Utilization,
+			UtilizationChange:       change.Utilization - change.BaselineUtilization,
+			BaselineInstanceCount:   change.BaselineInstanceCount,
+			InstanceCount:           change.InstanceCount,
+			BaselineLimitedByNodeID: change.BaselineLimitedByNodeID,
+			LimitedByNodeID:         change.LimitedByNodeID,
+		}
+	}
+
+	return whatIfResponse{
+		PipelineID:             pipelineID,
+		BaselineLimitingNodeID: report.BaselineLimitingNodeID,
+		LimitingNodeID:         report.LimitingNodeID,
+		BottleneckMoved:        report.BaselineLimitingNodeID != report.LimitingNodeID,
+		Output:                 output,
+		Nodes:                  nodes,
+	}
+}
+
+// WhatIf handles POST /api/pipelines/:id/whatif?beltCapacity=&pipeCapacity=
+func (h *AnalysisHandler) WhatIf(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid pipeline ID")
+	}
+
+	var capacity throughput.Capacity
+	if capacity.Belt, err = optionalRate(c, "beltCapacity"); err != nil {
+		return err
+	}
+	if capacity.Pipe, err = optionalRate(c, "pipeCapacity"); err != nil {
+		return err
+	}
+
+	var req whatIfRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	pipeline, err := h.pipelineRepo.Get(c.Request().Context(), id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if pipeline == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Pipeline not found")
+	}
+
+	report, err := whatif.Analyze(pipeline, req.toScenario(), capacity)
+	switch {
+	case errors.Is(err, whatif.ErrInvalidScenario):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case err != nil:
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, toWhatIfResponse(pipeline.ID(), report))
+}
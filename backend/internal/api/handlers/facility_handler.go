@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"net/http"
 	"strconv"
 
@@ -10,33 +11,44 @@ import (
 )
 
 type FacilityHandler struct {
-	facilityRepo repositories.FacilityRepository
-	itemRepo     repositories.ItemRepository
+	facilityRepo    repositories.FacilityRepository
+	itemRepo        repositories.ItemRepository
+	machineTypeRepo repositories.MachineTypeRepository
+	recipeRepo      repositories.RecipeRepository
 }
 
-func NewFacilityHandler(facilityRepo repositories.FacilityRepository, itemRepo repositories.ItemRepository) *FacilityHandler {
+func NewFacilityHandler(facilityRepo repositories.FacilityRepository, itemRepo repositories.ItemRepository, machineTypeRepo repositories.MachineTypeRepository, recipeRepo repositories.RecipeRepository) *FacilityHandler {
 	return &FacilityHandler{
-		facilityRepo: facilityRepo,
-		itemRepo:     itemRepo,
+		facilityRepo:    facilityRepo,
+		itemRepo:        itemRepo,
+		machineTypeRepo: machineTypeRepo,
+		recipeRepo:      recipeRepo,
 	}
 }
 
 type inputRequirementRequest struct {
-	ItemID   int `json:"itemId"`
-	Quantity int `json:"quantity"`
+	ItemID   int     `json:"itemId"`
+	Quantity float64 `json:"quantity"`
 }
 
+// outputDefinitionRequest yields the output every cycle when Probability is omitted
 type outputDefinitionRequest struct {
-	ItemID   int `json:"itemId"`
-	Quantity int `json:"quantity"`
+	ItemID      int      `json:"itemId"`
+	Quantity    float64  `json:"quantity"`
+	Probability *float64 `json:"probability"`
 }
 
+// createFacilityRequest either defines the recipe inline through ProcessingTime, Inputs and
+// Outputs, or binds a machine type to a recipe through MachineTypeID and RecipeID
 type createFacilityRequest struct {
 	Name           string                   `json:"name"`
 	Description    string                   `json:"description"`
 	ProcessingTime int64                    `json:"processingTime"`
 	Inputs         []inputRequirementRequest  `json:"inputs"`
 	Outputs        []outputDefinitionRequest  `json:"outputs"`
+	MachineTypeID  int                      `json:"machineTypeId"`
+	RecipeID       int                      `json:"recipeId"`
+	Power          powerRequest             `json:"power"`
 }
 
 type updateFacilityRequest struct {
@@ -45,16 +57,27 @@ type updateFacilityRequest struct {
 	ProcessingTime int64                    `json:"processingTime"`
 	Inputs         []inputRequirementRequest  `json:"inputs"`
 	Outputs        []outputDefinitionRequest  `json:"outputs"`
+	MachineTypeID  int                      `json:"machineTypeId"`
+	RecipeID       int                      `json:"recipeId"`
+	Power          powerRequest             `json:"power"`
+}
+
+// powerRequest holds the power draw and generation of one machine in megawatts
+type powerRequest struct {
+	ActiveDraw float64 `json:"activeDraw"`
+	IdleDraw   float64 `json:"idleDraw"`
+	Generation float64 `json:"generation"`
 }
 
 type inputRequirementResponse struct {
-	ItemID   int `json:"itemId"`
-	Quantity int `json:"quantity"`
+	ItemID   int     `json:"itemId"`
+	Quantity float64 `json:"quantity"`
 }
 
 type outputDefinitionResponse struct {
-	ItemID   int `json:"itemId"`
-	Quantity int `json:"quantity"`
+	ItemID      int     `json:"itemId"`
+	Quantity    float64 `json:"quantity"`
+	Probability float64 `json:"probability"`
 }
 
 type facilityResponse struct {
@@ -64,6 +87,15 @@ type facilityResponse struct {
 	ProcessingTime int64                     `json:"processingTime"`
 	Inputs         []inputRequirementResponse  `json:"inputs"`
 	Outputs        []outputDefinitionResponse  `json:"outputs"`
+	MachineTypeID  int                       `json:"machineTypeId,omitempty"`
+	RecipeID       int                       `json:"recipeId,omitempty"`
+	Power          powerResponse             `json:"power"`
+}
+
+type powerResponse struct {
+	ActiveDraw float64 `json:"activeDraw"`
+	IdleDraw   float64 `json:"idleDraw"`
+	Generation float64 `json:"generation"`
 }
 
 func toInputRequirementResponse(req *models.InputRequirement) inputRequirementResponse {
@@ -75,11 +107,23 @@ func toInputRequirementResponse(req *models.InputRequirement) inputRequirementRe
 
 func toOutputDefinitionResponse(def *models.OutputDefinition) outputDefinitionResponse {
 	return outputDefinitionResponse{
-		ItemID:   def.Item().ID(),
-		Quantity: def.Quantity(),
+		ItemID:      def.Item().ID(),
+		Quantity:    def.Quantity(),
+		Probability: def.Probability(),
 	}
 }
 
+// toOutputDefinition creates the requested output of the item
+func toOutputDefinition(item *models.Item, req outputDefinitionRequest) (*models.OutputDefinition, error) {
+	if req.Probability == nil {
+		return models.NewOutputDefinition(item, req.Quantity), nil
+	}
+	if *req.Probability <= 0 || *req.Probability > 1 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Output probability must be greater than 0 and at most 1")
+	}
+	return models.NewChanceOutputDefinition(item, req.Quantity, *req.Probability), nil
+}
+
 func toFacilityResponse(facility *models.Facility) facilityResponse {
 	inputs := make([]inputRequirementResponse, len(facility.InputRequirements()))
 	for i, req := range facility.InputRequirements() {
@@ -91,14 +135,65 @@ func toFacilityResponse(facility *models.Facility) facilityResponse {
 		outputs[i] = toOutputDefinitionResponse(def)
 	}
 
-	return facilityResponse{
+	response := facilityResponse{
 		ID:             facility.ID(),
 		Name:           facility.Name(),
 		Description:    facility.Description(),
 		ProcessingTime: facility.ProcessingTime(),
 		Inputs:         inputs,
 		Outputs:        outputs,
+		Power: powerResponse{
+			ActiveDraw: facility.Power().ActiveDraw(),
+			IdleDraw:   facility.Power().IdleDraw(),
+			Generation: facility.Power().Generation(),
+		},
+	}
+	if facility.MachineType() != nil {
+		response.MachineTypeID = facility.MachineType().ID()
 	}
+	if facility.Recipe() != nil {
+		response.RecipeID = facility.Recipe().ID()
+	}
+	return response
+}
+
+// resolveRecipeBinding looks up the machine type and recipe a facility is bound to.
+// Both are nil for facilities defining their own inputs, outputs and processing time.
+func (h *FacilityHandler) resolveRecipeBinding(ctx context.Context, machineTypeID int, recipeID int, inlineRecipe bool) (*models.MachineType, *models.Recipe, error) {
+	if machineTypeID == 0 && recipeID == 0 {
+		return nil, nil, nil
+	}
+	if machineTypeID == 0 || recipeID == 0 {
+		return nil, nil, echo.NewHTTPError(http.StatusBadRequest, "Machine type and recipe must be specified together")
+	}
+	if inlineRecipe {
+		return nil, nil, echo.NewHTTPError(http.StatusBadRequest, "Inputs, outputs and processing time are defined by the recipe")
+	}
+
+	machineType, err := h.machineTypeRepo.Get(ctx, machineTypeID)
+	if err != nil {
+		return nil, nil, echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if machineType == nil {
+		return nil, nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid machine type ID")
+	}
+
+	recipe, err := h.recipeRepo.Get(ctx, recipeID)
+	if err != nil {
+		return nil, nil, echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if recipe == nil {
+		return nil, nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid recipe ID")
+	}
+
+	return machineType, recipe, nil
+}
+
+func toPowerProfile(req powerRequest) (models.PowerProfile, error) {
+	if req.ActiveDraw < 0 || req.IdleDraw < 0 || req.Generation < 0 {
+		return models.PowerProfile{}, echo.NewHTTPError(http.StatusBadRequest, "Power draw and generation must not be negative")
+	}
+	return models.NewPowerProfile(req.ActiveDraw, req.IdleDraw, req.Generation), nil
 }
 
 // List handles GET /api/facilities
@@ -138,7 +233,21 @@ func (h *FacilityHandler) Create(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 
+	inlineRecipe := req.ProcessingTime != 0 || len(req.Inputs) > 0 || len(req.Outputs) > 0
+	machineType, recipe, err := h.resolveRecipeBinding(c.Request().Context(), req.MachineTypeID, req.RecipeID, inlineRecipe)
+	if err != nil {
+		return err
+	}
+	power, err := toPowerProfile(req.Power)
+	if err != nil {
+		return err
+	}
+
 	facility := models.NewFacility(req.Name, req.Description, req.ProcessingTime)
+	if recipe != nil {
+		facility = models.NewRecipeFacility(req.Name, req.Description, machineType, recipe)
+	}
+	facility.SetPower(power)
 
 	// Add input requirements
 	for _, input := range req.Inputs {
@@ -155,7 +264,11 @@ func (h *FacilityHandler) Create(c echo.Context) error {
 		if err != nil {
 			return echo.NewHTTPError(http.StatusBadRequest, "Invalid output item ID")
 		}
-		facility.AddOutputDefinition(models.NewOutputDefinition(item, output.Quantity))
+		def, err := toOutputDefinition(item, output)
+		if err != nil {
+			return err
+		}
+		facility.AddOutputDefinition(def)
 	}
 
 	if err := h.facilityRepo.Create(c.Request().Context(), facility); err != nil {
@@ -191,6 +304,16 @@ func (h *FacilityHandler) Update(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "Facility not found")
 	}
 
+	inlineRecipe := req.ProcessingTime != 0 || len(req.Inputs) > 0 || len(req.Outputs) > 0
+	machineType, recipe, err := h.resolveRecipeBinding(c.Request().Context(), req.MachineTypeID, req.RecipeID, inlineRecipe)
+	if err != nil {
+		return err
+	}
+	power, err := toPowerProfile(req.Power)
+	if err != nil {
+		return err
+	}
+
 	// Create input requirements
 	inputReqs := make([]*models.InputRequirement, len(req.Inputs))
 	for i, input := range req.Inputs {
@@ -208,7 +331,10 @@ func (h *FacilityHandler) Update(c echo.Context) error {
 		if err != nil {
 			return echo.NewHTTPError(http.StatusBadRequest, "Invalid output item ID")
 		}
-		outputDefs[i] = models.NewOutputDefinition(item, output.Quantity)
+		outputDefs[i], err = toOutputDefinition(item, output)
+		if err != nil {
+			return err
+		}
 	}
 
 	updatedFacility := models.NewFacilityFromParams(
@@ -218,6 +344,9 @@ func (h *FacilityHandler) Update(c echo.Context) error {
 		inputReqs,
 		outputDefs,
 		req.ProcessingTime,
+		machineType,
+		recipe,
+		power,
 	)
 
 	if err := h.facilityRepo.Update(c.Request().Context(), updatedFacility); err != nil {
@@ -235,7 +364,7 @@ func (h *FacilityHandler) Delete(c echo.Context) error {
 	}
 
 	if err := h.facilityRepo.Delete(c.Request().Context(), id); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		return deleteError(err, "Facility not found")
 	}
 
 	return c.NoContent(http.StatusNoContent)
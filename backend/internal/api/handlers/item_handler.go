@@ -18,20 +18,24 @@ func NewItemHandler(repo repositories.ItemRepository) *ItemHandler {
 	return &ItemHandler{repo: repo}
 }
 
+// createItemRequest defaults Kind to solid when omitted
 type createItemRequest struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
+	Kind        string `json:"kind"`
 }
 
 type updateItemRequest struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
+	Kind        string `json:"kind"`
 }
 
 type itemResponse struct {
 	ID          int    `json:"id"`
 	Name        string `json:"name"`
 	Description string `json:"description"`
+	Kind        string `json:"kind"`
 }
 
 func toItemResponse(item *models.Item) itemResponse {
@@ -39,9 +43,20 @@ func toItemResponse(item *models.Item) itemResponse {
 		ID:          item.ID(),
 		Name:        item.Name(),
 		Description: item.Description(),
+		Kind:        string(item.Kind()),
 	}
 }
 
+func toItemKind(kind string) (models.ItemKind, error) {
+	switch models.ItemKind(kind) {
+	case "", models.ItemKindSolid:
+		return models.ItemKindSolid, nil
+	case models.ItemKindFluid:
+		return models.ItemKindFluid, nil
+	}
+	return "", echo.NewHTTPError(http.StatusBadRequest, "Item kind must be solid or fluid")
+}
+
 // List handles GET /api/items
 func (h *ItemHandler) List(c echo.Context) error {
 	items, err := h.repo.List(c.Request().Context())
@@ -79,8 +94,13 @@ func (h *ItemHandler) Create(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 
+	kind, err := toItemKind(req.Kind)
+	if err != nil {
+		return err
+	}
+
 	fmt.Printf("Request: name=%s, description=%s\n", req.Name, req.Description)
-	item := models.NewItem(req.Name, req.Description)
+	item := models.NewItem(req.Name, req.Description, kind)
 	fmt.Printf("Created item: name=%s, description=%s\n", item.Name(), item.Description())
 	if err := h.repo.Create(c.Request().Context(), item); err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
@@ -109,7 +129,15 @@ func (h *ItemHandler) Update(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "Item not found")
 	}
 
-	updatedItem := models.NewItemFromParams(id, req.Name, req.Description)
+	// Clients that leave the kind out keep the kind the item has
+	kind := existingItem.Kind()
+	if req.Kind != "" {
+		if kind, err = toItemKind(req.Kind); err != nil {
+			return err
+		}
+	}
+
+	updatedItem := models.NewItemFromParams(id, req.Name, req.Description, kind)
 	if err := h.repo.Update(c.Request().Context(), updatedItem); err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/fasim/backend/internal/models"
+	"github.com/fasim/backend/internal/repositories"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+type MachineTypeHandler struct {
+	repo repositories.MachineTypeRepository
+}
+
+func NewMachineTypeHandler(repo repositories.MachineTypeRepository) *MachineTypeHandler {
+	return &MachineTypeHandler{repo: repo}
+}
+
+type createMachineTypeRequest struct {
+	Name          string  `json:"name"`
+	Description   string  `json:"description"`
+	CraftingSpeed float64 `json:"craftingSpeed"`
+}
+
+type updateMachineTypeRequest struct {
+	Name          string  `json:"name"`
+	Description   string  `json:"description"`
+	CraftingSpeed float64 `json:"craftingSpeed"`
+}
+
+type machineTypeResponse struct {
+	ID            int     `json:"id"`
+	Name          string  `json:"name"`
+	Description   string  `json:"description"`
+	CraftingSpeed float64 `json:"craftingSpeed"`
+}
+
+func toMachineTypeResponse(machineType *models.MachineType) machineTypeResponse {
+	return machineTypeResponse{
+		ID:            machineType.ID(),
+		Name:          machineType.Name(),
+		Description:   machineType.Description(),
+		CraftingSpeed: machineType.CraftingSpeed(),
+	}
+}
+
+// deleteError converts an error from deleting a record into an HTTP error
+func deleteError(err error, notFoundMessage string) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, notFoundMessage)
+	case errors.Is(err, repositories.ErrInUse):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	default:
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+}
+
+// List handles GET /api/machine-types
+func (h *MachineTypeHandler) List(c echo.Context) error {
+	machineTypes, err := h.repo.List(c.Request().Context())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	responses := make([]machineTypeResponse, len(machineTypes))
+	for i, machineType := range machineTypes {
+		responses[i] = toMachineTypeResponse(machineType)
+	}
+
+	return c.JSON(http.StatusOK, responses)
+}
+
+// Get handles GET /api/machine-types/:id
+func (h *MachineTypeHandler) Get(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid machine type ID")
+	}
+
+	machineType, err := h.repo.Get(c.Request().Context(), id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if machineType == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Machine type not found")
+	}
+
+	return c.JSON(http.StatusOK, toMachineTypeResponse(machineType))
+}
+
+// Create handles POST /api/machine-types
+func (h *MachineTypeHandler) Create(c echo.Context) error {
+	var req createMachineTypeRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if req.CraftingSpeed <= 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Crafting speed must be positive")
+	}
+
+	machineType := models.NewMachineType(req.Name, req.Description, req.CraftingSpeed)
+	if err := h.repo.Create(c.Request().Context(), machineType); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusCreated, toMachineTypeResponse(machineType))
+}
+
+// Update handles PUT /api/machine-types/:id
+func (h *MachineTypeHandler) Update(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid machine type ID")
+	}
+
+	var req updateMachineTypeRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if req.CraftingSpeed <= 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Crafting speed must be positive")
+	}
+
+	existingMachineType, err := h.repo.Get(c.Request().Context(), id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if existingMachineType == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Machine type not found")
+	}
+
+	updatedMachineType := models.NewMachineTypeFromParams(id, req.Name, req.Description, req.CraftingSpeed)
+	if err := h.repo.Update(c.Request().Context(), updatedMachineType); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, toMachineTypeResponse(updatedMachineType))
+}
+
+// Delete handles DELETE /api/machine-types/:id
+func (h *MachineTypeHandler) Delete(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid machine type ID")
+	}
+
+	if err := h.repo.Delete(c.Request().Context(), id); err != nil {
+		return deleteError(err, "Machine type not found")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
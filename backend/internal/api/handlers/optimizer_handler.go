@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/fasim/backend/internal/optimizer"
+	"github.com/fasim/backend/internal/planner"
+	"github.com/fasim/backend/internal/repositories"
+	"github.com/labstack/echo/v4"
+)
+
+type OptimizerHandler struct {
+	facilityRepo repositories.FacilityRepository
+	itemRepo     repositories.ItemRepository
+}
+
+func NewOptimizerHandler(facilityRepo repositories.FacilityRepository, itemRepo repositories.ItemRepository) *OptimizerHandler {
+	return &OptimizerHandler{
+		facilityRepo: facilityRepo,
+		itemRepo:     itemRepo,
+	}
+}
+
+type optimizationTargetRequest struct {
+	ItemID    int     `json:"itemId"`
+	PerMinute float64 `json:"perMinute"`
+}
+
+// optimizeRequest minimizes the machine count unless another objective is given
+type optimizeRequest struct {
+	Targets   []optimizationTargetRequest `json:"targets"`
+	Objective string                      `json:"objective"`
+}
+
+type optimizationResponse struct {
+	Objective    string                    `json:"objective"`
+	Value        float64                   `json:"value"`
+	Machines     float64                   `json:"machines"`
+	Power        float64                   `json:"power"`
+	Targets      []itemDemandResponse      `json:"targets"`
+	Facilities   []plannedFacilityResponse `json:"facilities"`
+	RawResources []itemDemandResponse      `json:"rawResources"`
+	Byproducts   []itemDemandResponse      `json:"byproducts"`
+}
+
+func toOptimizationResponse(solution *optimizer.Solution) optimizationResponse {
+	facilities := make([]plannedFacilityResponse, len(solution.Facilities))
+	for i, demand := range solution.Facilities {
+		facilities[i] = plannedFacilityResponse{
+			FacilityID:    demand.Facility.ID(),
+			Name:          demand.Facility.Name(),
+			Count:         demand.Count,
+			RequiredCount: demand.RequiredCount,
+		}
+	}
+
+	return optimizationResponse{
+		Objective:    string(solution.Objective),
+		Value:        solution.Value,
+		Machines:     solution.Machines,
+		Power:        solution.Power,
+		Targets:      toItemDemandResponses(solution.Targets),
+		Facilities:   facilities,
+		RawResources: toItemDemandResponses(solution.RawResources),
+		Byproducts:   toItemDemandResponses(solution.Byproducts),
+	}
+}
+
+// Optimize handles POST /api/optimize
+func (h *OptimizerHandler) Optimize(c echo.Context) error {
+	var req optimizeRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	objective := optimizer.ObjectiveMachines
+	if req.Objective != "" {
+		parsed, err := optimizer.ParseObjective(req.Objective)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		objective = parsed
+	}
+
+	targets := make(map[int]float64, len(req.Targets))
+	for _, target := range req.Targets {
+		if target.PerMinute <= 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "Target rates must be positive")
+		}
+		item, err := h.itemRepo.Get(c.Request().Context(), target.ItemID)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+		if item == nil {
+			return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Item %d not found", target.ItemID))
+		}
+		targets[item.ID()] += target.PerMinute
+	}
+
+	facilities, err := h.facilityRepo.List(c.Request().Context())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	solution, err := optimizer.Optimize(facilities, targets, objective)
+	switch {
+	case errors.Is(err, optimizer.ErrNoTargets):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case errors.Is(err, planner.ErrNoProducer), errors.Is(err, optimizer.ErrInfeasible):
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+	case err != nil:
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, toOptimizationResponse(solution))
+}
@@ -0,0 +1,577 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/fasim/backend/internal/models"
+	"github.com/fasim/backend/internal/planner"
+	"github.com/fasim/backend/internal/repositories"
+	"github.com/labstack/echo/v4"
+)
+
+type PipelineHandler struct {
+	pipelineRepo  repositories.PipelineRepository
+	facilityRepo  repositories.FacilityRepository
+	itemRepo      repositories.ItemRepository
+	transportRepo repositories.TransportRepository
+}
+
+func NewPipelineHandler(pipelineRepo repositories.PipelineRepository, facilityRepo repositories.FacilityRepository, itemRepo repositories.ItemRepository, transportRepo repositories.TransportRepository) *PipelineHandler {
+	return &PipelineHandler{
+		pipelineRepo:  pipelineRepo,
+		facilityRepo:  facilityRepo,
+		itemRepo:      itemRepo,
+		transportRepo: transportRepo,
+	}
+}
+
+// pipelineConnectionRequest routes items to the node with the given client-side ID.
+// A zero item ID carries every output the target node consumes, and a zero transport ID
+// leaves the connection without a belt or pipe limiting its flow.
+type pipelineConnectionRequest struct {
+	TargetNodeID  int     `json:"targetNodeId"`
+	ItemID        int     `json:"itemId"`
+	Ratio         float64 `json:"ratio"`
+	Priority      int     `json:"priority"`
+	MaxThroughput float64 `json:"maxThroughput"`
+	TransportID   int     `json:"transportId"`
+}
+
+// bufferRequest sets the number of units of each item a node holds. A zero input buffer
+// uses the default of the simulation, and a zero output buffer holds no finished outputs.
+type bufferRequest struct {
+	Input  int `json:"input"`
+	Output int `json:"output"`
+}
+
+// pipelineNodeRequest describes a node by a client-side ID, which is only used to
+// resolve connection targets within the same request and is replaced by the persisted ID.
+// NextNodeIDs adds connections without routing constraints. An omitted instance count or
+// clock speed defaults to a single machine running at normal speed.
+//
+// An omitted kind places the facility with the given ID. Source and sink nodes instead
+// supply or take the item with the given ID at up to PerMinute items per minute, where
+// zero means without limit.
+type pipelineNodeRequest struct {
+	ID            int                         `json:"id"`
+	Kind          string                      `json:"kind"`
+	FacilityID    int                         `json:"facilityId"`
+	ItemID        int                         `json:"itemId"`
+	PerMinute     float64                     `json:"perMinute"`
+	InstanceCount *int                        `json:"instanceCount"`
+	ClockSpeed    *float64                    `json:"clockSpeed"`
+	Buffers       bufferRequest               `json:"buffers"`
+	NextNodeIDs   []int                       `json:"nextNodeIds"`
+	Connections   []pipelineConnectionRequest `json:"connections"`
+}
+
+type createPipelineRequest struct {
+	Name        string                `json:"name"`
+	Description string                `json:"description"`
+	Nodes       []pipelineNodeRequest `json:"nodes"`
+}
+
+type updatePipelineRequest struct {
+	Name        string                `json:"name"`
+	Description string                `json:"description"`
+	Nodes       []pipelineNodeRequest `json:"nodes"`
+}
+
+// alternativeRequest pins the facility producing an item
+type alternativeRequest struct {
+	ItemID     int `json:"itemId"`
+	FacilityID int `json:"facilityId"`
+}
+
+// generatePipelineRequest makes every item with the producer of the lowest facility ID
+// unless an alternative is pinned for it
+type generatePipelineRequest struct {
+	Name         string               `json:"name"`
+	Description  string               `json:"description"`
+	ItemID       int                  `json:"itemId"`
+	Rate         float64              `json:"rate"`
+	Alternatives []alternativeRequest `json:"alternatives"`
+}
+
+// nodeAlternativeRequest replaces the facility of a node with another producing the same items
+type nodeAlternativeRequest struct {
+	FacilityID int `json:"facilityId"`
+}
+
+type pipelineConnectionResponse struct {
+	TargetNodeID  int     `json:"targetNodeId"`
+	ItemID        int     `json:"itemId,omitempty"`
+	Ratio         float64 `json:"ratio"`
+	Priority      int     `json:"priority"`
+	MaxThroughput float64 `json:"maxThroughput"`
+	TransportID   int     `json:"transportId,omitempty"`
+}
+
+type bufferResponse struct {
+	Input  int `json:"input"`
+	Output int `json:"output"`
+}
+
+type pipelineNodeResponse struct {
+	ID            int                          `json:"id"`
+	Kind          string                       `json:"kind"`
+	FacilityID    int                          `json:"facilityId,omitempty"`
+	ItemID        int                          `json:"itemId,omitempty"`
+	PerMinute     float64                      `json:"perMinute,omitempty"`
+	InstanceCount int                          `json:"instanceCount"`
+	ClockSpeed    float64                      `json:"clockSpeed"`
+	Buffers       bufferResponse               `json:"buffers"`
+	NextNodeIDs   []int                        `json:"nextNodeIds"`
+	Connections   []pipelineConnectionResponse `json:"connections"`
+}
+
+type pipelineResponse struct {
+	ID          int                    `json:"id"`
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Nodes       []pipelineNodeResponse `json:"nodes"`
+}
+
+type diagnosticResponse struct {
+	Severity string `json:"severity"`
+	NodeID   int    `json:"nodeId,omitempty"`
+	ItemID   int    `json:"itemId,omitempty"`
+	Message  string `json:"message"`
+}
+
+type validationResponse struct {
+	Valid       bool                 `json:"valid"`
+	Diagnostics []diagnosticResponse `json:"diagnostics"`
+}
+
+type validationErrorResponse struct {
+	Message     string               `json:"message"`
+	Diagnostics []diagnosticResponse `json:"diagnostics"`
+}
+
+func toDiagnosticResponses(diagnostics []*models.Diagnostic) []diagnosticResponse {
+	responses := make([]diagnosticResponse, len(diagnostics))
+	for i, diagnostic := range diagnostics {
+		responses[i] = diagnosticResponse{
+			Severity: string(diagnostic.Severity()),
+			NodeID:   diagnostic.NodeID(),
+			ItemID:   diagnostic.ItemID(),
+			Message:  diagnostic.Message(),
+		}
+	}
+	return responses
+}
+
+// pipelineSaveError converts an error from storing a pipeline into an HTTP error,
+// exposing the diagnostics of pipelines rejected by validation
+func pipelineSaveError(err error) error {
+	var validationErr *models.ValidationError
+	if errors.As(err, &validationErr) {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, validationErrorResponse{
+			Message:     validationErr.Error(),
+			Diagnostics: toDiagnosticResponses(validationErr.Diagnostics()),
+		})
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+}
+
+func toPipelineNodeResponse(node *models.PipelineNode) pipelineNodeResponse {
+	nextNodeIDs := make([]int, len(node.NextNodeIDs()))
+	copy(nextNodeIDs, node.NextNodeIDs())
+	sort.Ints(nextNodeIDs)
+
+	connections := make([]pipelineConnectionResponse, len(node.Connections()))
+	for i, conn := range node.Connections() {
+		connections[i] = pipelineConnectionResponse{
+			TargetNodeID:  conn.TargetNodeID(),
+			Ratio:         conn.Ratio(),
+			Priority:      conn.Priority(),
+			MaxThroughput: conn.MaxThroughput(),
+		}
+		if conn.Item() != nil {
+			connections[i].ItemID = conn.Item().ID()
+		}
+		if conn.Transport() != nil {
+			connections[i].TransportID = conn.Transport().ID()
+		}
+	}
+
+	response := pipelineNodeResponse{
+		ID:            node.ID(),
+		Kind:          string(node.Kind()),
+		PerMinute:     node.PerMinute(),
+		InstanceCount: node.InstanceCount(),
+		ClockSpeed:    node.ClockSpeed(),
+		Buffers: bufferResponse{
+			Input:  node.Buffers().Input(),
+			Output: node.Buffers().Output(),
+		},
+		NextNodeIDs: nextNodeIDs,
+		Connections: connections,
+	}
+	if node.Facility() != nil {
+		response.FacilityID = node.Facility().ID()
+	}
+	if node.Item() != nil {
+		response.ItemID = node.Item().ID()
+	}
+	return response
+}
+
+func toPipelineResponse(pipeline *models.Pipeline) pipelineResponse {
+	nodes := make([]pipelineNodeResponse, 0, len(pipeline.Nodes()))
+	for _, node := range pipeline.Nodes() {
+		nodes = append(nodes, toPipelineNodeResponse(node))
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].ID < nodes[j].ID
+	})
+
+	return pipelineResponse{
+		ID:          pipeline.ID(),
+		Name:        pipeline.Name(),
+		Description: pipeline.Description(),
+		Nodes:       nodes,
+	}
+}
+
+// addPipelineNodes adds the requested nodes to the pipeline, translating client-side
+// node IDs into the temporary IDs assigned by the pipeline
+func (h *PipelineHandler) addPipelineNodes(ctx context.Context, pipeline *models.Pipeline, reqs []pipelineNodeRequest) error {
+	nodes := make([]*models.PipelineNode, len(reqs))
+	nodeIDMap := make(map[int]int) // Map from client-side ID to temporary ID
+	for i, req := range reqs {
+		if _, exists := nodeIDMap[req.ID]; exists {
+			return echo.NewHTTPError(http.StatusBadRequest, "Duplicate node ID: "+strconv.Itoa(req.ID))
+		}
+
+		node, err := h.newPipelineNode(ctx, req)
+		if err != nil {
+			return err
+		}
+		nodes[i] = node
+		nodes[i].SetBuffers(models.NewBufferCapacity(req.Buffers.Input, req.Buffers.Output))
+		pipeline.AddNode(nodes[i])
+		nodeIDMap[req.ID] = nodes[i].ID()
+	}
+
+	for i, req := range reqs {
+		for _, nextID := range req.NextNodeIDs {
+			targetID, ok := nodeIDMap[nextID]
+			if !ok {
+				return echo.NewHTTPError(http.StatusBadRequest, "Invalid next node ID: "+strconv.Itoa(nextID))
+			}
+			nodes[i].AddNextNodeID(targetID)
+		}
+
+		for _, connReq := range req.Connections {
+			targetID, ok := nodeIDMap[connReq.TargetNodeID]
+			if !ok {
+				return echo.NewHTTPError(http.StatusBadRequest, "Invalid target node ID: "+strconv.Itoa(connReq.TargetNodeID))
+			}
+
+			var item *models.Item
+			if connReq.ItemID != 0 {
+				item = outputItem(nodes[i], connReq.ItemID)
+				if item == nil {
+					return echo.NewHTTPError(http.StatusBadRequest, "Item "+strconv.Itoa(connReq.ItemID)+" is not output by node "+strconv.Itoa(req.ID))
+				}
+			}
+
+			var transport *models.Transport
+			if connReq.TransportID != 0 {
+				var err error
+				transport, err = h.transportRepo.Get(ctx, connReq.TransportID)
+				if err != nil {
+					return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+				}
+				if transport == nil {
+					return echo.NewHTTPError(http.StatusBadRequest, "Invalid transport ID: "+strconv.Itoa(connReq.TransportID))
+				}
+			}
+
+			nodes[i].AddConnection(models.NewPipelineConnection(targetID, item, connReq.Ratio, connReq.Priority, connReq.MaxThroughput, transport))
+		}
+	}
+
+	return nil
+}
+
+// newPipelineNode creates the facility, source or sink node described by the request
+func (h *PipelineHandler) newPipelineNode(ctx context.Context, req pipelineNodeRequest) (*models.PipelineNode, error) {
+	switch kind := models.NodeKind(req.Kind); kind {
+	case "", models.NodeKindFacility:
+		facility, err := h.facilityRepo.Get(ctx, req.FacilityID)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+		if facility == nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid facility ID: "+strconv.Itoa(req.FacilityID))
+		}
+
+		instanceCount := 1
+		if req.InstanceCount != nil {
+			instanceCount = *req.InstanceCount
+		}
+		clockSpeed := 1.0
+		if req.ClockSpeed != nil {
+			clockSpeed = *req.ClockSpeed
+		}
+		return models.NewPipelineNode(facility, instanceCount, clockSpeed), nil
+	case models.NodeKindSource, models.NodeKindSink:
+		item, err := h.itemRepo.Get(ctx, req.ItemID)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+		if item == nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid item ID: "+strconv.Itoa(req.ItemID))
+		}
+		if kind == models.NodeKindSource {
+			return models.NewSourceNode(item, req.PerMinute), nil
+		}
+		return models.NewSinkNode(item, req.PerMinute), nil
+	default:
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid node kind: "+req.Kind)
+	}
+}
+
+// outputItem returns the item output by the node with the given ID, or nil if there is none
+func outputItem(node *models.PipelineNode, itemID int) *models.Item {
+	for _, output := range node.OutputDefinitions() {
+		if output.Item().ID() == itemID {
+			return output.Item()
+		}
+	}
+	return nil
+}
+
+// List handles GET /api/pipelines
+func (h *PipelineHandler) List(c echo.Context) error {
+	pipelines, err := h.pipelineRepo.List(c.Request().Context())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	responses := make([]pipelineResponse, len(pipelines))
+	for i, pipeline := range pipelines {
+		responses[i] = toPipelineResponse(pipeline)
+	}
+
+	return c.JSON(http.StatusOK, responses)
+}
+
+// Get handles GET /api/pipelines/:id
+func (h *PipelineHandler) Get(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid pipeline ID")
+	}
+
+	pipeline, err := h.pipelineRepo.Get(c.Request().Context(), id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if pipeline == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Pipeline not found")
+	}
+
+	return c.JSON(http.StatusOK, toPipelineResponse(pipeline))
+}
+
+// Create handles POST /api/pipelines
+func (h *PipelineHandler) Create(c echo.Context) error {
+	var req createPipelineRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	pipeline := models.NewPipeline(req.Name, req.Description)
+	if err := h.addPipelineNodes(c.Request().Context(), pipeline, req.Nodes); err != nil {
+		return err
+	}
+
+	if err := h.pipelineRepo.Create(c.Request().Context(), pipeline); err != nil {
+		return pipelineSaveError(err)
+	}
+
+	return c.JSON(http.StatusCreated, toPipelineResponse(pipeline))
+}
+
+// Generate handles POST /api/pipelines/generate
+func (h *PipelineHandler) Generate(c echo.Context) error {
+	var req generatePipelineRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	facilities, err := h.facilityRepo.List(c.Request().Context())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	book := planner.NewRecipeBook(facilities)
+	for _, alternative := range req.Alternatives {
+		if err := book.Pin(alternative.ItemID, alternative.FacilityID); err != nil {
+			return echo.NewHTTPError(plannerErrorStatus(err), err.Error())
+		}
+	}
+	plan, err := book.Plan(req.ItemID, req.Rate)
+	if err != nil {
+		return echo.NewHTTPError(plannerErrorStatus(err), err.Error())
+	}
+
+	pipeline := book.GeneratePipeline(req.Name, req.Description, plan)
+	if err := h.pipelineRepo.Create(c.Request().Context(), pipeline); err != nil {
+		return pipelineSaveError(err)
+	}
+
+	return c.JSON(http.StatusCreated, toPipelineResponse(pipeline))
+}
+
+// Update handles PUT /api/pipelines/:id
+func (h *PipelineHandler) Update(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid pipeline ID")
+	}
+
+	var req updatePipelineRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	existingPipeline, err := h.pipelineRepo.Get(c.Request().Context(), id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if existingPipeline == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Pipeline not found")
+	}
+
+	// Nodes are always recreated on update, so existing node IDs are treated as client-side IDs
+	updatedPipeline := models.NewPipelineFromParams(
+		id,
+		req.Name,
+		req.Description,
+		make(map[int]*models.PipelineNode),
+	)
+	if err := h.addPipelineNodes(c.Request().Context(), updatedPipeline, req.Nodes); err != nil {
+		return err
+	}
+
+	if err := h.pipelineRepo.Update(c.Request().Context(), updatedPipeline); err != nil {
+		return pipelineSaveError(err)
+	}
+
+	return c.JSON(http.StatusOK, toPipelineResponse(updatedPipeline))
+}
+
+// PinAlternative handles PUT /api/pipelines/:id/nodes/:nodeId/alternative
+func (h *PipelineHandler) PinAlternative(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid pipeline ID")
+	}
+	nodeID, err := strconv.Atoi(c.Param("nodeId"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid node ID")
+	}
+
+	var req nodeAlternativeRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	pipeline, err := h.pipelineRepo.Get(c.Request().Context(), id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if pipeline == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Pipeline not found")
+	}
+	node, ok := pipeline.Nodes()[nodeID]
+	if !ok {
+		return echo.NewHTTPError(http.StatusNotFound, "Node not found")
+	}
+	if node.Facility() == nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, "Only facility nodes have alternatives")
+	}
+
+	facility, err := h.facilityRepo.Get(c.Request().Context(), req.FacilityID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if facility == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid facility ID: "+strconv.Itoa(req.FacilityID))
+	}
+
+	facilities, err := h.facilityRepo.List(c.Request().Context())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if !planner.NewRecipeBook(facilities).IsAlternative(node.Facility(), facility) {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, "Facility produces none of the items of the node")
+	}
+
+	node.SetFacility(facility)
+	if err := h.pipelineRepo.Update(c.Request().Context(), pipeline); err != nil {
+		return pipelineSaveError(err)
+	}
+
+	return c.JSON(http.StatusOK, toPipelineResponse(pipeline))
+}
+
+// Validate handles POST /api/pipelines/:id/validate
+func (h *PipelineHandler) Validate(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid pipeline ID")
+	}
+
+	pipeline, err := h.pipelineRepo.Get(c.Request().Context(), id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if pipeline == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Pipeline not found")
+	}
+
+	diagnostics := pipeline.Validate()
+	valid := true
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Severity() == models.SeverityError {
+			valid = false
+		}
+	}
+
+	return c.JSON(http.StatusOK, validationResponse{
+		Valid:       valid,
+		Diagnostics: toDiagnosticResponses(diagnostics),
+	})
+}
+
+// Delete handles DELETE /api/pipelines/:id
+func (h *PipelineHandler) Delete(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid pipeline ID")
+	}
+
+	existingPipeline, err := h.pipelineRepo.Get(c.Request().Context(), id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if existingPipeline == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Pipeline not found")
+	}
+
+	if err := h.pipelineRepo.Delete(c.Request().Context(), id); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
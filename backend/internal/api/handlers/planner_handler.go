@@ -0,0 +1,282 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/fasim/backend/internal/planner"
+	"github.com/fasim/backend/internal/repositories"
+	"github.com/labstack/echo/v4"
+)
+
+type PlannerHandler struct {
+	facilityRepo repositories.FacilityRepository
+	itemRepo     repositories.ItemRepository
+}
+
+func NewPlannerHandler(facilityRepo repositories.FacilityRepository, itemRepo repositories.ItemRepository) *PlannerHandler {
+	return &PlannerHandler{
+		facilityRepo: facilityRepo,
+		itemRepo:     itemRepo,
+	}
+}
+
+type plannedFacilityResponse struct {
+	FacilityID    int     `json:"facilityId"`
+	Name          string  `json:"name"`
+	Count         float64 `json:"count"`
+	RequiredCount int     `json:"requiredCount"`
+}
+
+type itemDemandResponse struct {
+	ItemID    int     `json:"itemId"`
+	Name      string  `json:"name"`
+	PerMinute float64 `json:"perMinute"`
+}
+
+type planResponse struct {
+	ItemID       int                       `json:"itemId"`
+	PerMinute    float64                   `json:"perMinute"`
+	Facilities   []plannedFacilityResponse `json:"facilities"`
+	RawResources []itemDemandResponse      `json:"rawResources"`
+	Byproducts   []itemDemandResponse      `json:"byproducts"`
+	// Loops lists the item IDs of each recipe cycle balanced by the plan
+	Loops [][]int `json:"loops"`
+}
+
+func toItemDemandResponses(demands []*planner.ItemDemand) []itemDemandResponse {
+	responses := make([]itemDemandResponse, len(demands))
+	for i, demand := range demands {
+		responses[i] = itemDemandResponse{
+			ItemID:    demand.Item.ID(),
+			Name:      demand.Item.Name(),
+			PerMinute: demand.PerMinute,
+		}
+	}
+	return responses
+}
+
+func toPlanResponse(plan *planner.Plan) planResponse {
+	facilities := make([]plannedFacilityResponse, len(plan.Facilities))
+	for i, demand := range plan.Facilities {
+		facilities[i] = plannedFacilityResponse{
+			FacilityID:    demand.Facility.ID(),
+			Name:          demand.Facility.Name(),
+			Count:         demand.Count,
+			RequiredCount: demand.RequiredCount,
+		}
+	}
+
+	loops := make([][]int, len(plan.Loops))
+	for i, loop := range plan.Loops {
+		loops[i] = make([]int, len(loop))
+		for j, item := range loop {
+			loops[i][j] = item.ID()
+		}
+	}
+
+	return planResponse{
+		ItemID:       plan.Item.ID(),
+		PerMinute:    plan.PerMinute,
+		Facilities:   facilities,
+		RawResources: toItemDemandResponses(plan.RawResources),
+		Byproducts:   toItemDemandResponses(plan.Byproducts),
+		Loops:        loops,
+	}
+}
+
+type alternativeResponse struct {
+	FacilityID     int                  `json:"facilityId"`
+	Name           string               `json:"name"`
+	MachineSeconds float64              `json:"machineSeconds"`
+	Energy         float64              `json:"energy"`
+	RawTotal       float64              `json:"rawTotal"`
+	RawResources   []itemDemandResponse `json:"rawResources"`
+	Byproducts     []itemDemandResponse `json:"byproducts"`
+}
+
+type alternativesResponse struct {
+	ItemID       int                   `json:"itemId"`
+	Alternatives []alternativeResponse `json:"alternatives"`
+}
+
+func toAlternativesResponse(itemID int, alternatives []*planner.Alternative) alternativesResponse {
+	responses := make([]alternativeResponse, len(alternatives))
+	for i, alternative := range alternatives {
+		responses[i] = alternativeResponse{
+			FacilityID:     alternative.Facility.ID(),
+			Name:           alternative.Facility.Name(),
+			MachineSeconds: alternative.MachineSeconds,
+			Energy:         alternative.Energy,
+			RawTotal:       alternative.RawTotal,
+			RawResources:   toItemDemandResponses(alternative.RawResources),
+			Byproducts:     toItemDemandResponses(alternative.Byproducts),
+		}
+	}
+	return alternativesResponse{ItemID: itemID, Alternatives: responses}
+}
+
+type materialNodeResponse struct {
+	ItemID     int                    `json:"itemId"`
+	Name       string                 `json:"name"`
+	FacilityID int                    `json:"facilityId,omitempty"`
+	PerUnit    float64                `json:"perUnit"`
+	PerMinute  float64                `json:"perMinute"`
+	Loop       bool                   `json:"loop"`
+	Inputs     []materialNodeResponse `json:"inputs"`
+}
+
+type materialTotalResponse struct {
+	ItemID    int     `json:"itemId"`
+	Name      string  `json:"name"`
+	PerUnit   float64 `json:"perUnit"`
+	PerMinute float64 `json:"perMinute"`
+}
+
+type billOfMaterialsResponse struct {
+	ItemID       int                     `json:"itemId"`
+	PerMinute    float64                 `json:"perMinute"`
+	Tree         materialNodeResponse    `json:"tree"`
+	RawResources []materialTotalResponse `json:"rawResources"`
+}
+
+func toMaterialNodeResponse(node *planner.MaterialNode) materialNodeResponse {
+	response := materialNodeResponse{
+		ItemID:    node.Item.ID(),
+		Name:      node.Item.Name(),
+		PerUnit:   node.PerUnit,
+		PerMinute: node.PerMinute,
+		Loop:      node.Loop,
+		Inputs:    make([]materialNodeResponse, len(node.Inputs)),
+	}
+	if node.Facility != nil {
+		response.FacilityID = node.Facility.ID()
+	}
+	for i, input := range node.Inputs {
+		response.Inputs[i] = toMaterialNodeResponse(input)
+	}
+	return response
+}
+
+func toBillOfMaterialsResponse(bom *planner.BillOfMaterials) billOfMaterialsResponse {
+	rawResources := make([]materialTotalResponse, len(bom.RawResources))
+	for i, total := range bom.RawResources {
+		rawResources[i] = materialTotalResponse{
+			ItemID:    total.Item.ID(),
+			Name:      total.Item.Name(),
+			PerUnit:   total.PerUnit,
+			PerMinute: total.PerMinute,
+		}
+	}
+
+	return billOfMaterialsResponse{
+		ItemID:       bom.Root.Item.ID(),
+		PerMinute:    bom.PerMinute,
+		Tree:         toMaterialNodeResponse(bom.Root),
+		RawResources: rawResources,
+	}
+}
+
+// plannerErrorStatus maps planning errors caused by the recipe data to a client error
+func plannerErrorStatus(err error) int {
+	if errors.Is(err, planner.ErrNoProducer) || errors.Is(err, planner.ErrCyclicRecipe) || errors.Is(err, planner.ErrNotProducer) {
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusBadRequest
+}
+
+// Plan handles GET /api/items/:id/plan?rate=
+func (h *PlannerHandler) Plan(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid item ID")
+	}
+
+	rate, err := strconv.ParseFloat(c.QueryParam("rate"), 64)
+	if err != nil || math.IsNaN(rate) || math.IsInf(rate, 0) {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid rate")
+	}
+
+	item, err := h.itemRepo.Get(c.Request().Context(), id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if item == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Item not found")
+	}
+
+	facilities, err := h.facilityRepo.List(c.Request().Context())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	plan, err := planner.NewRecipeBook(facilities).Plan(item.ID(), rate)
+	if err != nil {
+		return echo.NewHTTPError(plannerErrorStatus(err), err.Error())
+	}
+
+	return c.JSON(http.StatusOK, toPlanResponse(plan))
+}
+
+// Alternatives handles GET /api/items/:id/alternatives
+func (h *PlannerHandler) Alternatives(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid item ID")
+	}
+
+	item, err := h.itemRepo.Get(c.Request().Context(), id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if item == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Item not found")
+	}
+
+	facilities, err := h.facilityRepo.List(c.Request().Context())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	alternatives, err := planner.NewRecipeBook(facilities).Compare(item.ID())
+	if err != nil {
+		return echo.NewHTTPError(plannerErrorStatus(err), err.Error())
+	}
+
+	return c.JSON(http.StatusOK, toAlternativesResponse(item.ID(), alternatives))
+}
+
+// BillOfMaterials handles GET /api/items/:id/bom?rate=
+func (h *PlannerHandler) BillOfMaterials(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid item ID")
+	}
+
+	rate, err := strconv.ParseFloat(c.QueryParam("rate"), 64)
+	if err != nil || math.IsNaN(rate) || math.IsInf(rate, 0) {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid rate")
+	}
+
+	item, err := h.itemRepo.Get(c.Request().Context(), id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if item == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Item not found")
+	}
+
+	facilities, err := h.facilityRepo.List(c.Request().Context())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	bom, err := planner.NewRecipeBook(facilities).BillOfMaterials(item.ID(), rate)
+	if err != nil {
+		return echo.NewHTTPError(plannerErrorStatus(err), err.Error())
+	}
+
+	return c.JSON(http.StatusOK, toBillOfMaterialsResponse(bom))
+}
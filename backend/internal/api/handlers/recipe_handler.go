@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/fasim/backend/internal/models"
+	"github.com/fasim/backend/internal/repositories"
+	"github.com/labstack/echo/v4"
+)
+
+type RecipeHandler struct {
+	recipeRepo repositories.RecipeRepository
+	itemRepo   repositories.ItemRepository
+}
+
+func NewRecipeHandler(recipeRepo repositories.RecipeRepository, itemRepo repositories.ItemRepository) *RecipeHandler {
+	return &RecipeHandler{
+		recipeRepo: recipeRepo,
+		itemRepo:   itemRepo,
+	}
+}
+
+type createRecipeRequest struct {
+	Name         string                    `json:"name"`
+	Description  string                    `json:"description"`
+	CraftingTime int64                     `json:"craftingTime"`
+	Inputs       []inputRequirementRequest `json:"inputs"`
+	Outputs      []outputDefinitionRequest `json:"outputs"`
+}
+
+type updateRecipeRequest struct {
+	Name         string                    `json:"name"`
+	Description  string                    `json:"description"`
+	CraftingTime int64                     `json:"craftingTime"`
+	Inputs       []inputRequirementRequest `json:"inputs"`
+	Outputs      []outputDefinitionRequest `json:"outputs"`
+}
+
+type recipeResponse struct {
+	ID           int                        `json:"id"`
+	Name         string                     `json:"name"`
+	Description  string                     `json:"description"`
+	CraftingTime int64                      `json:"craftingTime"`
+	Inputs       []inputRequirementResponse `json:"inputs"`
+	Outputs      []outputDefinitionResponse `json:"outputs"`
+}
+
+func toRecipeResponse(recipe *models.Recipe) recipeResponse {
+	inputs := make([]inputRequirementResponse, len(recipe.InputRequirements()))
+	for i, req := range recipe.InputRequirements() {
+		inputs[i] = toInputRequirementResponse(req)
+	}
+
+	outputs := make([]outputDefinitionResponse, len(recipe.OutputDefinitions()))
+	for i, def := range recipe.OutputDefinitions() {
+		outputs[i] = toOutputDefinitionResponse(def)
+	}
+
+	return recipeResponse{
+		ID:           recipe.ID(),
+		Name:         recipe.Name(),
+		Description:  recipe.Description(),
+		CraftingTime: recipe.CraftingTime(),
+		Inputs:       inputs,
+		Outputs:      outputs,
+	}
+}
+
+// resolveItems looks up the items of the requested inputs and outputs
+func (h *RecipeHandler) resolveItems(ctx context.Context, inputs []inputRequirementRequest, outputs []outputDefinitionRequest) ([]*models.InputRequirement, []*models.OutputDefinition, error) {
+	inputReqs := make([]*models.InputRequirement, len(inputs))
+	for i, input := range inputs {
+		item, err := h.itemRepo.Get(ctx, input.ItemID)
+		if err != nil {
+			return nil, nil, echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+		if item == nil {
+			return nil, nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid input item ID")
+		}
+		inputReqs[i] = models.NewInputRequirement(item, input.Quantity)
+	}
+
+	outputDefs := make([]*models.OutputDefinition, len(outputs))
+	for i, output := range outputs {
+		item, err := h.itemRepo.Get(ctx, output.ItemID)
+		if err != nil {
+			return nil, nil, echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+		if item == nil {
+			return nil, nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid output item ID")
+		}
+		outputDefs[i], err = toOutputDefinition(item, output)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return inputReqs, outputDefs, nil
+}
+
+// List handles GET /api/recipes
+func (h *RecipeHandler) List(c echo.Context) error {
+	recipes, err := h.recipeRepo.List(c.Request().Context())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	responses := make([]recipeResponse, len(recipes))
+	for i, recipe := range recipes {
+		responses[i] = toRecipeResponse(recipe)
+	}
+
+	return c.JSON(http.StatusOK, responses)
+}
+
+// Get handles GET /api/recipes/:id
+func (h *RecipeHandler) Get(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid recipe ID")
+	}
+
+	recipe, err := h.recipeRepo.Get(c.Request().Context(), id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if recipe == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Recipe not found")
+	}
+
+	return c.JSON(http.StatusOK, toRecipeResponse(recipe))
+}
+
+// Create handles POST /api/recipes
+func (h *RecipeHandler) Create(c echo.Context) error {
+	var req createRecipeRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if req.CraftingTime <= 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Crafting time must be positive")
+	}
+
+	inputReqs, outputDefs, err := h.resolveItems(c.Request().Context(), req.Inputs, req.Outputs)
+	if err != nil {
+		return err
+	}
+
+	recipe := models.NewRecipe(req.Name, req.Description, req.CraftingTime)
+	for _, input := range inputReqs {
+		recipe.AddInputRequirement(input)
+	}
+	for _, output := range outputDefs {
+		recipe.AddOutputDefinition(output)
+	}
+
+	if err := h.recipeRepo.Create(c.Request().Context(), recipe); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	// Get the created recipe to ensure we have the correct ID and relationships
+	createdRecipe, err := h.recipeRepo.Get(c.Request().Context(), recipe.ID())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusCreated, toRecipeResponse(createdRecipe))
+}
+
+// Update handles PUT /api/recipes/:id
+func (h *RecipeHandler) Update(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid recipe ID")
+	}
+
+	var req updateRecipeRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if req.CraftingTime <= 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Crafting time must be positive")
+	}
+
+	existingRecipe, err := h.recipeRepo.Get(c.Request().Context(), id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if existingRecipe == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Recipe not found")
+	}
+
+	inputReqs, outputDefs, err := h.resolveItems(c.Request().Context(), req.Inputs, req.Outputs)
+	if err != nil {
+		return err
+	}
+
+	updatedRecipe := models.NewRecipeFromParams(
+		id,
+		req.Name,
+		req.Description,
+		inputReqs,
+		outputDefs,
+		req.CraftingTime,
+	)
+
+	if err := h.recipeRepo.Update(c.Request().Context(), updatedRecipe); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, toRecipeResponse(updatedRecipe))
+}
+
+// Delete handles DELETE /api/recipes/:id
+func (h *RecipeHandler) Delete(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid recipe ID")
+	}
+
+	if err := h.recipeRepo.Delete(c.Request().Context(), id); err != nil {
+		return deleteError(err, "Recipe not found")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,533 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/fasim/backend/internal/export"
+	"github.com/fasim/backend/internal/models"
+	"github.com/fasim/backend/internal/repositories"
+	"github.com/fasim/backend/internal/runner"
+	"github.com/fasim/backend/internal/simulation"
+	"github.com/labstack/echo/v4"
+)
+
+type SimulationHandler struct {
+	runner       *runner.Runner
+	runRepo      repositories.SimulationRunRepository
+	pipelineRepo repositories.PipelineRepository
+}
+
+func NewSimulationHandler(runner *runner.Runner, runRepo repositories.SimulationRunRepository, pipelineRepo repositories.PipelineRepository) *SimulationHandler {
+	return &SimulationHandler{
+		runner:       runner,
+		runRepo:      runRepo,
+		pipelineRepo: pipelineRepo,
+	}
+}
+
+// heartbeatInterval is the time between comments keeping idle event streams open
+const heartbeatInterval = 15 * time.Second
+
+// startSimulationRequest holds the settings of a run, with durations in seconds of
+// simulated time. TimeStep is the interval between samples of the node buffers; zero takes
+// no samples. A zero buffer capacity leaves the input buffers of nodes without one of
+// their own unlimited.
+//
+// Live runs stream a tick every time step, played at Speed seconds of simulated time per
+// second, which defaults to real time. Paused live runs wait for playback to be resumed.
+type startSimulationRequest struct {
+	Duration       float64 `json:"duration"`
+	TimeStep       float64 `json:"timeStep"`
+	Seed           uint64  `json:"seed"`
+	BufferCapacity int     `json:"bufferCapacity"`
+	Live           bool    `json:"live"`
+	Speed          float64 `json:"speed"`
+	Paused         bool    `json:"paused"`
+}
+
+// playbackRequest changes the pace of a live run, leaving omitted fields unchanged
+type playbackRequest struct {
+	Paused *bool    `json:"paused"`
+	Speed  *float64 `json:"speed"`
+}
+
+type playbackResponse struct {
+	Paused bool    `json:"paused"`
+	Speed  float64 `json:"speed"`
+}
+
+type simulationSettingsResponse struct {
+	Duration       float64 `json:"duration"`
+	TimeStep       float64 `json:"timeStep"`
+	Seed           uint64  `json:"seed"`
+	BufferCapacity int     `json:"bufferCapacity"`
+}
+
+type itemAmountResponse struct {
+	ItemID   int     `json:"itemId"`
+	Quantity float64 `json:"quantity"`
+}
+
+type simulationSampleResponse struct {
+	Time         float64              `json:"time"`
+	InputBuffer  []itemAmountResponse `json:"inputBuffer"`
+	OutputBuffer []itemAmountResponse `json:"outputBuffer"`
+	Produced     []itemAmountResponse `json:"produced"`
+	Consumed     []itemAmountResponse `json:"consumed"`
+}
+
+// simulationNodeResponse reports times in seconds, averaged over the machines of the node
+type simulationNodeResponse struct {
+	NodeID      int                        `json:"nodeId"`
+	FacilityID  int                        `json:"facilityId,omitempty"`
+	Cycles      int                        `json:"cycles"`
+	Utilization float64                    `json:"utilization"`
+	BusyTime    float64                    `json:"busyTime"`
+	IdleTime    float64                    `json:"idleTime"`
+	StarvedTime float64                    `json:"starvedTime"`
+	BlockedTime float64                    `json:"blockedTime"`
+	Produced    []itemAmountResponse       `json:"produced"`
+	Consumed    []itemAmountResponse       `json:"consumed"`
+	Samples     []simulationSampleResponse `json:"samples,omitempty"`
+}
+
+type simulationLinkResponse struct {
+	SourceNodeID      int     `json:"sourceNodeId"`
+	TargetNodeID      int     `json:"targetNodeId"`
+	TransportID       int     `json:"transportId"`
+	Moved             float64 `json:"moved"`
+	CapacityPerMinute float64 `json:"capacityPerMinute"`
+	Saturated         bool    `json:"saturated"`
+}
+
+type simulationResultResponse struct {
+	PowerDemand     float64                  `json:"powerDemand"`
+	PowerGeneration float64                  `json:"powerGeneration"`
+	Nodes           []simulationNodeResponse `json:"nodes"`
+	Links           []simulationLinkResponse `json:"links"`
+}
+
+type simulationRunResponse struct {
+	ID         int                        `json:"id"`
+	PipelineID int                        `json:"pipelineId"`
+	Status     string                     `json:"status"`
+	Error      string                     `json:"error,omitempty"`
+	Settings   simulationSettingsResponse `json:"settings"`
+	CreatedAt  time.Time                  `json:"createdAt"`
+	FinishedAt *time.Time                 `json:"finishedAt,omitempty"`
+	Result     *simulationResultResponse  `json:"result,omitempty"`
+}
+
+type nodeTickResponse struct {
+	NodeID       int                  `json:"nodeId"`
+	Status       string               `json:"status"`
+	Working      int                  `json:"working"`
+	Cycles       int                  `json:"cycles"`
+	InputBuffer  []itemAmountResponse `json:"inputBuffer"`
+	OutputBuffer []itemAmountResponse `json:"outputBuffer"`
+	Produced     []itemAmountResponse `json:"produced"`
+	Consumed     []itemAmountResponse `json:"consumed"`
+}
+
+type linkTickResponse struct {
+	SourceNodeID int     `json:"sourceNodeId"`
+	TargetNodeID int     `json:"targetNodeId"`
+	Delivered    float64 `json:"delivered"`
+}
+
+// tickResponse is the state of the pipeline at a point in simulated time, in seconds
+type tickResponse struct {
+	Time  float64            `json:"time"`
+	Nodes []nodeTickResponse `json:"nodes"`
+	Links []linkTickResponse `json:"links"`
+}
+
+func toItemAmountResponses(amounts map[int]float64) []itemAmountResponse {
+	responses := make([]itemAmountResponse, 0, len(amounts))
+	for itemID, quantity := range amounts {
+		responses = append(responses, itemAmountResponse{ItemID: itemID, Quantity: quantity})
+	}
+	sort.Slice(responses, func(i, j int) bool {
+		return responses[i].ItemID < responses[j].ItemID
+	})
+	return responses
+}
+
+func toSimulationResultResponse(result *models.SimulationResult) *simulationResultResponse {
+	nodes := make([]simulationNodeResponse, len(result.Nodes()))
+	for i, node := range result.Nodes() {
+		samples := make([]simulationSampleResponse, len(node.Samples()))
+		for j, sample := range node.Samples() {
+			samples[j] = simulationSampleResponse{
+				Time:         sample.Time().Seconds(),
+				InputBuffer:  toItemAmountResponses(sample.InputBuffer()),
+				OutputBuffer: toItemAmountResponses(sample.OutputBuffer()),
+				Produced:     toItemAmountResponses(sample.Produced()),
+				Consumed:     toItemAmountResponses(sample.Consumed()),
+			}
+		}
+		nodes[i] = simulationNodeResponse{
+			NodeID:      node.NodeID(),
+			FacilityID:  node.FacilityID(),
+			Cycles:      node.Cycles(),
+			Utilization: node.Utilization(),
+			BusyTime:    node.BusyTime().Seconds(),
+			IdleTime:    node.IdleTime().Seconds(),
+			StarvedTime: node.StarvedTime().Seconds(),
+			BlockedTime: node.BlockedTime().Seconds(),
+			Produced:    toItemAmountResponses(node.Produced()),
+			Consumed:    toItemAmountResponses(node.Consumed()),
+			Samples:     samples,
+		}
+	}
+
+	links := make([]simulationLinkResponse, len(result.Links()))
+	for i, link := range result.Links() {
+		links[i] = simulationLinkResponse{
+			SourceNodeID:      link.SourceNodeID(),
+			TargetNodeID:      link.TargetNodeID(),
+			TransportID:       link.TransportID(),
+			Moved:             link.Moved(),
+			CapacityPerMinute: link.CapacityPerMinute(),
+			Saturated:         link.Saturated(),
+		}
+	}
+
+	return &simulationResultResponse{
+		PowerDemand:     result.PowerDemand(),
+		PowerGeneration: result.PowerGeneration(),
+		Nodes:           nodes,
+		Links:           links,
+	}
+}
+
+func toSimulationRunResponse(run *models.SimulationRun) simulationRunResponse {
+	settings := run.Settings()
+	response := simulationRunResponse{
+		ID:         run.ID(),
+		PipelineID: run.PipelineID(),
+		Status:     string(run.Status()),
+		Error:      run.ErrorMessage(),
+		Settings: simulationSettingsResponse{
+			Duration:       settings.Duration().Seconds(),
+			TimeStep:       settings.TimeStep().Seconds(),
+			Seed:           settings.Seed(),
+			BufferCapacity: settings.BufferCapacity(),
+		},
+		CreatedAt: run.CreatedAt(),
+	}
+	if !run.FinishedAt().IsZero() {
+		finishedAt := run.FinishedAt()
+		response.FinishedAt = &finishedAt
+	}
+	if run.Result() != nil {
+		response.Result = toSimulationResultResponse(run.Result())
+	}
+	return response
+}
+
+func toTickResponse(tick *simulation.Tick) tickResponse {
+	nodes := make([]nodeTickResponse, len(tick.Nodes))
+	for i, node := range tick.Nodes {
+		nodes[i] = nodeTickResponse{
+			NodeID:       node.NodeID,
+			Status:       string(node.Status),
+			Working:      node.Working,
+			Cycles:       node.Cycles,
+			InputBuffer:  toItemAmountResponses(node.InputBuffer),
+			OutputBuffer: toItemAmountResponses(node.OutputBuffer),
+			Produced:     toItemAmountResponses(node.Produced),
+			Consumed:     toItemAmountResponses(node.Consumed),
+		}
+	}
+	links := make([]linkTickResponse, len(tick.Links))
+	for i, link := range tick.Links {
+		links[i] = linkTickResponse{
+			SourceNodeID: link.SourceNodeID,
+			TargetNodeID: link.TargetNodeID,
+			Delivered:    link.Delivered,
+		}
+	}
+	return tickResponse{
+		Time:  tick.Time.Seconds(),
+		Nodes: nodes,
+		Links: links,
+	}
+}
+
+// seconds converts seconds of simulated time into a duration
+func seconds(value float64) time.Duration {
+	return time.Duration(value * float64(time.Second))
+}
+
+// Start handles POST /api/pipelines/:id/simulations
+func (h *SimulationHandler) Start(c echo.Context) error {
+	pipelineID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid pipeline ID")
+	}
+
+	var req startSimulationRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	var playback *runner.Playback
+	if req.Live {
+		speed := req.Speed
+		if speed == 0 {
+			speed = 1
+		}
+		playback = runner.NewPlayback(speed, req.Paused)
+	}
+
+	settings := models.NewSimulationSettings(seconds(req.Duration), seconds(req.TimeStep), req.Seed, req.BufferCapacity)
+	run, err := h.runner.Start(c.Request().Context(), pipelineID, settings, playback)
+	switch {
+	case errors.Is(err, runner.ErrPipelineNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Pipeline not found")
+	case errors.Is(err, runner.ErrInvalidSettings):
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+	case err != nil:
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusAccepted, toSimulationRunResponse(run))
+}
+
+// List handles GET /api/pipelines/:id/simulations. The runs are listed without the samples
+// of their nodes, which Get returns.
+func (h *SimulationHandler) List(c echo.Context) error {
+	pipelineID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid pipeline ID")
+	}
+
+	runs, err := h.runRepo.ListByPipeline(c.Request().Context(), pipelineID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	responses := make([]simulationRunResponse, len(runs))
+	for i, run := range runs {
+		responses[i] = toSimulationRunResponse(run)
+	}
+
+	return c.JSON(http.StatusOK, responses)
+}
+
+// Get handles GET /api/simulations/:id
+func (h *SimulationHandler) Get(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid simulation ID")
+	}
+
+	run, err := h.runRepo.Get(c.Request().Context(), id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if run == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Simulation not found")
+	}
+
+	return c.JSON(http.StatusOK, toSimulationRunResponse(run))
+}
+
+// Delete handles DELETE /api/simulations/:id. Runs still executing are cancelled and
+// returned with their final status; finished runs are removed.
+func (h *SimulationHandler) Delete(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid simulation ID")
+	}
+
+	cancelled, err := h.runner.Cancel(c.Request().Context(), id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if cancelled {
+		run, err := h.runRepo.Get(c.Request().Context(), id)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+		if run == nil {
+			return echo.NewHTTPError(http.StatusNotFound, "Simulation not found")
+		}
+		return c.JSON(http.StatusOK, toSimulationRunResponse(run))
+	}
+
+	if err := h.runRepo.Delete(c.Request().Context(), id); err != nil {
+		return deleteError(err, "Simulation not found")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Stream handles GET /api/simulations/:id/stream. It sends the events of an executing run
+// as server-sent events: "tick" with the state of the pipeline every time step, "playback"
+// when the pace of a live run changes, and finally "status" with the finished run. For
+// runs that are not executing, only the status is sent.
+func (h *SimulationHandler) Stream(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid simulation ID")
+	}
+
+	events, unsubscribe, executing := h.runner.Subscribe(id)
+	if executing {
+		defer unsubscribe()
+	} else {
+		run, err := h.runRepo.Get(c.Request().Context(), id)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+		if run == nil {
+			return echo.NewHTTPError(http.StatusNotFound, "Simulation not found")
+		}
+	}
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set(echo.HeaderCacheControl, "no-cache")
+	res.Header().Set(echo.HeaderConnection, "keep-alive")
+	res.WriteHeader(http.StatusOK)
+	res.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for executing {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				executing = false
+				continue
+			}
+			if event.Playback != nil {
+				err = writeEvent(res, "playback", playbackResponse{Paused: event.Playback.Paused, Speed: event.Playback.Speed})
+			} else {
+				err = writeEvent(res, "tick", toTickResponse(event.Tick))
+			}
+			if err != nil {
+				return nil
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(res, ": heartbeat\n\n"); err != nil {
+				return nil
+			}
+			res.Flush()
+		case <-c.Request().Context().Done():
+			return nil
+		}
+	}
+
+	// The outcome is stored before the events end
+	run, err := h.runRepo.Get(c.Request().Context(), id)
+	if err != nil || run == nil {
+		return nil
+	}
+	_ = writeEvent(res, "status", toSimulationRunResponse(run))
+	return nil
+}
+
+// writeEvent sends a server-sent event with the data encoded as JSON
+func writeEvent(res *echo.Response, name string, data any) error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(res, "event: %s\ndata: %s\n\n", name, encoded); err != nil {
+		return err
+	}
+	res.Flush()
+	return nil
+}
+
+// SetPlayback handles PUT /api/simulations/:id/playback
+func (h *SimulationHandler) SetPlayback(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid simulation ID")
+	}
+
+	var req playbackRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	state, err := h.runner.SetPlayback(id, req.Paused, req.Speed)
+	switch {
+	case errors.Is(err, runner.ErrInvalidSettings):
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, runner.ErrNotLive):
+		return echo.NewHTTPError(http.StatusConflict, "Simulation is not live")
+	case errors.Is(err, runner.ErrNotExecuting):
+		run, err := h.runRepo.Get(c.Request().Context(), id)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+		if run == nil {
+			return echo.NewHTTPError(http.StatusNotFound, "Simulation not found")
+		}
+		return echo.NewHTTPError(http.StatusConflict, "Simulation is not running")
+	case err != nil:
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, playbackResponse{Paused: state.Paused, Speed: state.Speed})
+}
+
+// Export handles GET /api/simulations/:id/export. It returns the samples of a completed run
+// as a time series in the format given by the format query parameter, CSV by default,
+// thinned out to the interval query parameter in seconds if given.
+func (h *SimulationHandler) Export(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid simulation ID")
+	}
+
+	format := export.FormatCSV
+	if name := c.QueryParam("format"); name != "" {
+		format, err = export.ParseFormat(name)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+	}
+	var interval float64
+	if value := c.QueryParam("interval"); value != "" {
+		interval, err = strconv.ParseFloat(value, 64)
+		if err != nil || interval < 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid interval")
+		}
+	}
+
+	run, err := h.runRepo.Get(c.Request().Context(), id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if run == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Simulation not found")
+	}
+	if run.Result() == nil {
+		return echo.NewHTTPError(http.StatusConflict, "Simulation has not completed")
+	}
+
+	// The pipeline only names the nodes and items, so runs of deleted pipelines are exported unnamed
+	pipeline, err := h.pipelineRepo.Get(c.Request().Context(), run.PipelineID())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	rows := export.Rows(run.Result(), pipeline, seconds(interval))
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, format.ContentType())
+	res.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=simulation-%d.%s", id, format))
+	res.WriteHeader(http.StatusOK)
+	return export.Write(res, format, rows)
+}
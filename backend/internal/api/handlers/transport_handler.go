@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/fasim/backend/internal/models"
+	"github.com/fasim/backend/internal/repositories"
+	"github.com/labstack/echo/v4"
+)
+
+type TransportHandler struct {
+	repo repositories.TransportRepository
+}
+
+func NewTransportHandler(repo repositories.TransportRepository) *TransportHandler {
+	return &TransportHandler{repo: repo}
+}
+
+// createTransportRequest defaults Kind to solid when omitted
+type createTransportRequest struct {
+	Name           string  `json:"name"`
+	Description    string  `json:"description"`
+	Kind           string  `json:"kind"`
+	ItemsPerSecond float64 `json:"itemsPerSecond"`
+}
+
+type updateTransportRequest struct {
+	Name           string  `json:"name"`
+	Description    string  `json:"description"`
+	Kind           string  `json:"kind"`
+	ItemsPerSecond float64 `json:"itemsPerSecond"`
+}
+
+type transportResponse struct {
+	ID             int     `json:"id"`
+	Name           string  `json:"name"`
+	Description    string  `json:"description"`
+	Kind           string  `json:"kind"`
+	ItemsPerSecond float64 `json:"itemsPerSecond"`
+}
+
+func toTransportResponse(transport *models.Transport) transportResponse {
+	return transportResponse{
+		ID:             transport.ID(),
+		Name:           transport.Name(),
+		Description:    transport.Description(),
+		Kind:           string(transport.Kind()),
+		ItemsPerSecond: transport.ItemsPerSecond(),
+	}
+}
+
+// List handles GET /api/transports
+func (h *TransportHandler) List(c echo.Context) error {
+	transports, err := h.repo.List(c.Request().Context())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	responses := make([]transportResponse, len(transports))
+	for i, transport := range transports {
+		responses[i] = toTransportResponse(transport)
+	}
+
+	return c.JSON(http.StatusOK, responses)
+}
+
+// Get handles GET /api/transports/:id
+func (h *TransportHandler) Get(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid transport ID")
+	}
+
+	transport, err := h.repo.Get(c.Request().Context(), id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if transport == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Transport not found")
+	}
+
+	return c.JSON(http.StatusOK, toTransportResponse(transport))
+}
+
+// Create handles POST /api/transports
+func (h *TransportHandler) Create(c echo.Context) error {
+	var req createTransportRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	kind, err := toItemKind(req.Kind)
+	if err != nil {
+		return err
+	}
+	if req.ItemsPerSecond <= 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Items per second must be positive")
+	}
+
+	transport := models.NewTransport(req.Name, req.Description, kind, req.ItemsPerSecond)
+	if err := h.repo.Create(c.Request().Context(), transport); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusCreated, toTransportResponse(transport))
+}
+
+// Update handles PUT /api/transports/:id
+func (h *TransportHandler) Update(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid transport ID")
+	}
+
+	var req updateTransportRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	kind, err := toItemKind(req.Kind)
+	if err != nil {
+		return err
+	}
+	if req.ItemsPerSecond <= 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Items per second must be positive")
+	}
+
+	existingTransport, err := h.repo.Get(c.Request().Context(), id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if existingTransport == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Transport not found")
+	}
+
+	updatedTransport := models.NewTransportFromParams(id, req.Name, req.Description, kind, req.ItemsPerSecond)
+	if err := h.repo.Update(c.Request().Context(), updatedTransport); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, toTransportResponse(updatedTransport))
+}
+
+// Delete handles DELETE /api/transports/:id
+func (h *TransportHandler) Delete(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid transport ID")
+	}
+
+	if err := h.repo.Delete(c.Request().Context(), id); err != nil {
+		return deleteError(err, "Transport not found")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"github.com/fasim/backend/internal/api/handlers"
+	"github.com/labstack/echo/v4"
+)
+
+// RegisterAnalysisRoutes registers all pipeline analysis routes
+func RegisterAnalysisRoutes(e *echo.Echo, handler *handlers.AnalysisHandler) {
+	pipelines := e.Group("/api/pipelines")
+	pipelines.GET("/:id/throughput", handler.Throughput)
+	pipelines.GET("/:id/power", handler.Power)
+	pipelines.GET("/:id/bottlenecks", handler.Bottlenecks)
+	pipelines.POST("/:id/whatif", handler.WhatIf)
+}
@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"github.com/fasim/backend/internal/api/handlers"
+	"github.com/labstack/echo/v4"
+)
+
+// RegisterMachineTypeRoutes registers all machine type-related routes
+func RegisterMachineTypeRoutes(e *echo.Echo, handler *handlers.MachineTypeHandler) {
+	machineTypes := e.Group("/api/machine-types")
+	machineTypes.GET("", handler.List)
+	machineTypes.GET("/:id", handler.Get)
+	machineTypes.POST("", handler.Create)
+	machineTypes.PUT("/:id", handler.Update)
+	machineTypes.DELETE("/:id", handler.Delete)
+}
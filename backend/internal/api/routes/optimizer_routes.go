@@ -0,0 +1,11 @@
+package routes
+
+import (
+	"github.com/fasim/backend/internal/api/handlers"
+	"github.com/labstack/echo/v4"
+)
+
+// RegisterOptimizerRoutes registers all production line optimization routes
+func RegisterOptimizerRoutes(e *echo.Echo, handler *handlers.OptimizerHandler) {
+	e.POST("/api/optimize", handler.Optimize)
+}
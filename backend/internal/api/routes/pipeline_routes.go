@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"github.com/fasim/backend/internal/api/handlers"
+	"github.com/labstack/echo/v4"
+)
+
+// RegisterPipelineRoutes registers all pipeline-related routes
+func RegisterPipelineRoutes(e *echo.Echo, handler *handlers.PipelineHandler) {
+	pipelines := e.Group("/api/pipelines")
+	pipelines.GET("", handler.List)
+	pipelines.GET("/:id", handler.Get)
+	pipelines.POST("", handler.Create)
+	pipelines.POST("/generate", handler.Generate)
+	pipelines.PUT("/:id", handler.Update)
+	pipelines.DELETE("/:id", handler.Delete)
+	pipelines.POST("/:id/validate", handler.Validate)
+	pipelines.PUT("/:id/nodes/:nodeId/alternative", handler.PinAlternative)
+}
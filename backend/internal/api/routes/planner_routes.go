@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"github.com/fasim/backend/internal/api/handlers"
+	"github.com/labstack/echo/v4"
+)
+
+// RegisterPlannerRoutes registers all production planning routes
+func RegisterPlannerRoutes(e *echo.Echo, handler *handlers.PlannerHandler) {
+	items := e.Group("/api/items")
+	items.GET("/:id/plan", handler.Plan)
+	items.GET("/:id/alternatives", handler.Alternatives)
+	items.GET("/:id/bom", handler.BillOfMaterials)
+}
@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"github.com/fasim/backend/internal/api/handlers"
+	"github.com/labstack/echo/v4"
+)
+
+// RegisterRecipeRoutes registers all recipe-related routes
+func RegisterRecipeRoutes(e *echo.Echo, handler *handlers.RecipeHandler) {
+	recipes := e.Group("/api/recipes")
+	recipes.GET("", handler.List)
+	recipes.GET("/:id", handler.Get)
+	recipes.POST("", handler.Create)
+	recipes.PUT("/:id", handler.Update)
+	recipes.DELETE("/:id", handler.Delete)
+}
@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"github.com/fasim/backend/internal/api/handlers"
+	"github.com/labstack/echo/v4"
+)
+
+// RegisterSimulationRoutes registers all simulation run routes
+func RegisterSimulationRoutes(e *echo.Echo, handler *handlers.SimulationHandler) {
+	pipelines := e.Group("/api/pipelines")
+	pipelines.POST("/:id/simulations", handler.Start)
+	pipelines.GET("/:id/simulations", handler.List)
+
+	simulations := e.Group("/api/simulations")
+	simulations.GET("/:id", handler.Get)
+	simulations.DELETE("/:id", handler.Delete)
+	simulations.GET("/:id/stream", handler.Stream)
+	simulations.GET("/:id/export", handler.Export)
+	simulations.PUT("/:id/playback", handler.SetPlayback)
+}
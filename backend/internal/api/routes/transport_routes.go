@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"github.com/fasim/backend/internal/api/handlers"
+	"github.com/labstack/echo/v4"
+)
+
+// RegisterTransportRoutes registers all transport-related routes
+func RegisterTransportRoutes(e *echo.Echo, handler *handlers.TransportHandler) {
+	transports := e.Group("/api/transports")
+	transports.GET("", handler.List)
+	transports.GET("/:id", handler.Get)
+	transports.POST("", handler.Create)
+	transports.PUT("/:id", handler.Update)
+	transports.DELETE("/:id", handler.Delete)
+}
@@ -0,0 +1,202 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/fasim/backend/internal/models"
+)
+
+// Format is a file format simulation time series can be exported as
+type Format string
+
+const (
+	FormatCSV   Format = "csv"
+	FormatJSONL Format = "jsonl"
+)
+
+// ParseFormat returns the format with the given name
+func ParseFormat(name string) (Format, error) {
+	switch Format(name) {
+	case FormatCSV, FormatJSONL:
+		return Format(name), nil
+	}
+	return "", fmt.Errorf("unsupported export format %q, expected %q or %q", name, FormatCSV, FormatJSONL)
+}
+
+// ContentType returns the media type of files in the format
+func (f Format) ContentType() string {
+	if f == FormatJSONL {
+		return "application/x-ndjson"
+	}
+	return "text/csv"
+}
+
+// Row holds the counters of an item at a node at a sample time. Buffers are the units held
+// at the sample time, while Produced and Consumed are the totals since the start of the run.
+type Row struct {
+	Time         time.Duration
+	NodeID       int
+	Node         string
+	ItemID       int
+	Item         string
+	InputBuffer  float64
+	OutputBuffer float64
+	Produced     float64
+	Consumed     float64
+}
+
+// jsonRow is a row as written to JSON Lines, with the time in seconds
+type jsonRow struct {
+	Time         float64 `json:"time"`
+	NodeID       int     `json:"nodeId"`
+	Node         string  `json:"node"`
+	ItemID       int     `json:"itemId"`
+	Item         string  `json:"item"`
+	InputBuffer  float64 `json:"inputBuffer"`
+	OutputBuffer float64 `json:"outputBuffer"`
+	Produced     float64 `json:"produced"`
+	Consumed     float64 `json:"consumed"`
+}
+
+// csvHeader names the columns of CSV files, with the time in seconds
+var csvHeader = []string{"time", "node_id", "node", "item_id", "item", "input_buffer", "output_buffer", "produced", "consumed"}
+
+// Rows flattens the samples of a result into one row per sample, node and item, ordered by
+// time, node ID and item ID. Every sample of a node lists the same items, namely all items
+// the node has held, produced or consumed during the run. Samples closer than the interval
+// to the previous kept sample are dropped; zero keeps every sample.
+//
+// The pipeline, if given, names the nodes and items. Nodes and items it does not contain,
+// for instance after the pipeline changed, are left unnamed.
+func Rows(result *models.SimulationResult, pipeline *models.Pipeline, interval time.Duration) []Row {
+	nodeNames, itemNames := names(pipeline)
+
+	rows := make([]Row, 0)
+	for _, node := range result.Nodes() {
+		itemIDs := make([]int, 0)
+		seen := make(map[int]bool)
+		for _, sample := range node.Samples() {
+			for _, amounts := range []map[int]float64{sample.InputBuffer(), sample.OutputBuffer(), sample.Produced(), sample.Consumed()} {
+				for itemID := range amounts {
+					if !seen[itemID] {
+						seen[itemID] = true
+						itemIDs = append(itemIDs, itemID)
+					}
+				}
+			}
+		}
+		sort.Ints(itemIDs)
+
+		var next time.Duration
+		for i, sample := range node.Samples() {
+			if i > 0 && sample.Time() < next {
+				continue
+			}
+			next = sample.Time() + interval
+			for _, itemID := range itemIDs {
+				rows = append(rows, Row{
+					Time:         sample.Time(),
+					NodeID:       node.NodeID(),
+					Node:         nodeNames[node.NodeID()],
+					ItemID:       itemID,
+					Item:         itemNames[itemID],
+					InputBuffer:  sample.InputBuffer()[itemID],
+					OutputBuffer: sample.OutputBuffer()[itemID],
+					Produced:     sample.Produced()[itemID],
+					Consumed:     sample.Consumed()[itemID],
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].Time < rows[j].Time
+	})
+	return rows
+}
+
+// names returns the names of the nodes and items of the pipeline, keyed by ID
+func names(pipeline *models.Pipeline) (map[int]string, map[int]string) {
+	nodeNames := make(map[int]string)
+	itemNames := make(map[int]string)
+	if pipeline == nil {
+		return nodeNames, itemNames
+	}
+
+	for id, node := range pipeline.Nodes() {
+		nodeNames[id] = node.Name()
+		for _, input := range node.InputRequirements() {
+			itemNames[input.Item().ID()] = input.Item().Name()
+		}
+		for _, output := range node.OutputDefinitions() {
+			itemNames[output.Item().ID()] = output.Item().Name()
+		}
+	}
+	return nodeNames, itemNames
+}
+
+// Write writes the rows in the given format
+func Write(w io.Writer, format Format, rows []Row) error {
+	if format == FormatJSONL {
+		return WriteJSONL(w, rows)
+	}
+	return WriteCSV(w, rows)
+}
+
+// WriteCSV writes the rows as CSV with a header line
+func WriteCSV(w io.Writer, rows []Row) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		record := []string{
+			formatFloat(row.Time.Seconds()),
+			strconv.Itoa(row.NodeID),
+			row.Node,
+			strconv.Itoa(row.ItemID),
+			row.Item,
+			formatFloat(row.InputBuffer),
+			formatFloat(row.OutputBuffer),
+			formatFloat(row.Produced),
+			formatFloat(row.Consumed),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteJSONL writes the rows as JSON Lines, one object per row
+func WriteJSONL(w io.Writer, rows []Row) error {
+	encoder := json.NewEncoder(w)
+	for _, row := range rows {
+		err := encoder.Encode(jsonRow{
+			Time:         row.Time.Seconds(),
+			NodeID:       row.NodeID,
+			Node:         row.Node,
+			ItemID:       row.ItemID,
+			Item:         row.Item,
+			InputBuffer:  row.InputBuffer,
+			OutputBuffer: row.OutputBuffer,
+			Produced:     row.Produced,
+			Consumed:     row.Consumed,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
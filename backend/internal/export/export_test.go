@@ -0,0 +1,105 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/fasim/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	ore   = models.NewItemFromParams(1, "Ore", "", models.ItemKindSolid)
+	plate = models.NewItemFromParams(2, "Plate", "", models.ItemKindSolid)
+)
+
+// newTestPipeline connects a miner with node ID 1 to a furnace with node ID 2
+func newTestPipeline() *models.Pipeline {
+	miner := models.NewFacility("Miner", "", 1000)
+	miner.AddOutputDefinition(models.NewOutputDefinition(ore, 1))
+	furnace := models.NewFacility("Furnace", "", 2000)
+	furnace.AddInputRequirement(models.NewInputRequirement(ore, 1))
+	furnace.AddOutputDefinition(models.NewOutputDefinition(plate, 1))
+
+	pipeline := models.NewPipeline("Smelting", "")
+	minerNode := models.NewPipelineNode(miner, 1, 1)
+	pipeline.AddNode(minerNode)
+	furnaceNode := models.NewPipelineNode(furnace, 1, 1)
+	pipeline.AddNode(furnaceNode)
+	minerNode.AddNextNodeID(furnaceNode.ID())
+	return pipeline
+}
+
+// newTestResult samples the miner and furnace every 10 seconds over 20 seconds
+func newTestResult() *models.SimulationResult {
+	empty := map[int]float64{}
+	miner := models.NewSimulationNodeStats(1, 1, 20, map[int]float64{1: 20}, empty, 20*time.Second, 0, 0, 0,
+		[]*models.SimulationSample{
+			models.NewSimulationSample(0, empty, empty, empty, empty),
+			models.NewSimulationSample(10*time.Second, empty, empty, map[int]float64{1: 10}, empty),
+			models.NewSimulationSample(20*time.Second, empty, empty, map[int]float64{1: 20}, empty),
+		})
+	furnace := models.NewSimulationNodeStats(2, 2, 9, map[int]float64{2: 9}, map[int]float64{1: 10}, 19*time.Second, time.Second, time.Second, 0,
+		[]*models.SimulationSample{
+			models.NewSimulationSample(0, empty, empty, empty, empty),
+			models.NewSimulationSample(10*time.Second, map[int]float64{1: 4.5}, empty, map[int]float64{2: 4}, map[int]float64{1: 5}),
+			models.NewSimulationSample(20*time.Second, map[int]float64{1: 10}, empty, map[int]float64{2: 9}, map[int]float64{1: 10}),
+		})
+	return models.NewSimulationResult(0, 0, []*models.SimulationNodeStats{miner, furnace}, nil)
+}
+
+func TestRows(t *testing.T) {
+	rows := Rows(newTestResult(), newTestPipeline(), 0)
+
+	// The miner lists ore and the furnace ore and plates at each of the three samples
+	require.Len(t, rows, 9)
+	assert.Equal(t, Row{Time: 0, NodeID: 1, Node: "Miner", ItemID: 1, Item: "Ore"}, rows[0])
+	assert.Equal(t, Row{Time: 0, NodeID: 2, Node: "Furnace", ItemID: 2, Item: "Plate"}, rows[2])
+	assert.Equal(t, Row{
+		Time:        10 * time.Second,
+		NodeID:      2,
+		Node:        "Furnace",
+		ItemID:      1,
+		Item:        "Ore",
+		InputBuffer: 4.5,
+		Consumed:    5,
+	}, rows[4])
+
+	thinned := Rows(newTestResult(), nil, 15*time.Second)
+	require.Len(t, thinned, 6)
+	assert.Equal(t, time.Duration(0), thinned[0].Time)
+	assert.Equal(t, 20*time.Second, thinned[3].Time)
+	assert.Empty(t, thinned[0].Node)
+	assert.Empty(t, thinned[0].Item)
+}
+
+func TestWrite(t *testing.T) {
+	rows := Rows(newTestResult(), newTestPipeline(), 20*time.Second)
+
+	var csv bytes.Buffer
+	require.NoError(t, Write(&csv, FormatCSV, rows))
+	assert.Equal(t, `time,node_id,node,item_id,item,input_buffer,output_buffer,produced,consumed
+0,1,Miner,1,Ore,0,0,0,0
+0,2,Furnace,1,Ore,0,0,0,0
+0,2,Furnace,2,Plate,0,0,0,0
+20,1,Miner,1,Ore,0,0,20,0
+20,2,Furnace,1,Ore,10,0,0,10
+20,2,Furnace,2,Plate,0,0,9,0
+`, csv.String())
+
+	var jsonl bytes.Buffer
+	require.NoError(t, Write(&jsonl, FormatJSONL, rows[3:4]))
+	assert.Equal(t, `{"time":20,"nodeId":1,"node":"Miner","itemId":1,"item":"Ore","inputBuffer":0,"outputBuffer":0,"produced":20,"consumed":0}
+`, jsonl.String())
+}
+
+func TestParseFormat(t *testing.T) {
+	format, err := ParseFormat("jsonl")
+	require.NoError(t, err)
+	assert.Equal(t, FormatJSONL, format)
+
+	_, err = ParseFormat("xlsx")
+	assert.Error(t, err)
+}
@@ -0,0 +1,102 @@
+// Package loops finds the cycles of directed graphs and solves the linear systems that
+// balance what flows around them
+package loops
+
+import (
+	"errors"
+	"math"
+	"slices"
+)
+
+// epsilon is the smallest pivot treated as non-zero when solving a system
+const epsilon = 1e-9
+
+// ErrSingular is returned when a linear system has no unique solution
+var ErrSingular = errors.New("linear system is singular")
+
+// Components returns the strongly connected components of the graph reachable from the
+// roots, where next returns the nodes a node points to. Every component comes before the
+// components it points to. A component of several nodes, or of a node pointing to
+// itself, is a cycle.
+func Components[T comparable](roots []T, next func(T) []T) [][]T {
+	// Tarjan's algorithm completes a component only after all components it reaches
+	index := make(map[T]int)
+	lowLink := make(map[T]int)
+	onStack := make(map[T]bool)
+	stack := make([]T, 0)
+	completed := make([][]T, 0)
+
+	var visit func(node T)
+	visit = func(node T) {
+		index[node] = len(index)
+		lowLink[node] = index[node]
+		stack = append(stack, node)
+		onStack[node] = true
+
+		for _, target := range next(node) {
+			if _, visited := index[target]; !visited {
+				visit(target)
+				lowLink[node] = min(lowLink[node], lowLink[target])
+			} else if onStack[target] {
+				lowLink[node] = min(lowLink[node], index[target])
+			}
+		}
+
+		if lowLink[node] != index[node] {
+			return
+		}
+		component := make([]T, 0, 1)
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+			if top == node {
+				break
+			}
+		}
+		completed = append(completed, component)
+	}
+	for _, root := range roots {
+		if _, visited := index[root]; !visited {
+			visit(root)
+		}
+	}
+
+	slices.Reverse(completed)
+	return completed
+}
+
+// Solve solves a square linear system by Gaussian elimination with partial pivoting. Each
+// row holds the coefficients of the unknowns followed by the right-hand side, and the rows
+// are modified in place.
+func Solve(system [][]float64) ([]float64, error) {
+	n := len(system)
+	for column := 0; column < n; column++ {
+		pivot := column
+		for row := column + 1; row < n; row++ {
+			if math.Abs(system[row][column]) > math.Abs(system[pivot][column]) {
+				pivot = row
+			}
+		}
+		if math.Abs(system[pivot][column]) <= epsilon {
+			return nil, ErrSingular
+		}
+		system[column], system[pivot] = system[pivot], system[column]
+		for row := 0; row < n; row++ {
+			if row == column {
+				continue
+			}
+			factor := system[row][column] / system[column][column]
+			for k := column; k <= n; k++ {
+				system[row][k] -= factor * system[column][k]
+			}
+		}
+	}
+
+	solution := make([]float64, n)
+	for i := range solution {
+		solution[i] = system[i][n] / system[i][i]
+	}
+	return solution, nil
+}
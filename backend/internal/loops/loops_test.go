@@ -0,0 +1,39 @@
+package loops
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComponents(t *testing.T) {
+	// 1 feeds the cycle 2 -> 3 -> 2, which feeds 4; 5 only points to itself
+	edges := map[int][]int{1: {2}, 2: {3}, 3: {2, 4}, 5: {5}}
+	next := func(node int) []int { return edges[node] }
+
+	components := Components([]int{1, 5}, next)
+	for _, component := range components {
+		slices.Sort(component)
+	}
+	assert.Equal(t, [][]int{{5}, {1}, {2, 3}, {4}}, components)
+
+	assert.Equal(t, [][]int{{4}}, Components([]int{4}, next))
+}
+
+func TestSolve(t *testing.T) {
+	// The first column is zero on the first row, so the rows have to be swapped
+	solution, err := Solve([][]float64{
+		{0, 2, 4},
+		{1, 1, 3},
+	})
+	require.NoError(t, err)
+	assert.InDeltaSlice(t, []float64{1, 2}, solution, 1e-9)
+
+	_, err = Solve([][]float64{
+		{1, 2, 3},
+		{2, 4, 6},
+	})
+	assert.ErrorIs(t, err, ErrSingular)
+}
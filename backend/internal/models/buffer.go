@@ -0,0 +1,27 @@
+package models
+
+// BufferCapacity limits how many units of each item a pipeline node holds while waiting
+// to use or hand them on
+type BufferCapacity struct {
+	// input limits each input item received from upstream nodes; zero falls back to the
+	// default of the simulation
+	input int
+	// output limits each finished output waiting for downstream nodes to accept it; zero
+	// holds none, so that machines wait until every output has been delivered
+	output int
+}
+
+func NewBufferCapacity(input int, output int) BufferCapacity {
+	return BufferCapacity{
+		input:  input,
+		output: output,
+	}
+}
+
+func (b BufferCapacity) Input() int {
+	return b.input
+}
+
+func (b BufferCapacity) Output() int {
+	return b.output
+}
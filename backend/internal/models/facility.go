@@ -1,12 +1,18 @@
 package models
 
-// InputRequirement defines the quantity of a specific item required for processing
+import (
+	"math"
+	"slices"
+)
+
+// InputRequirement defines the quantity of a specific item required for processing.
+// Quantities of fluids may be fractional.
 type InputRequirement struct {
 	item     *Item
-	quantity int
+	quantity float64
 }
 
-func NewInputRequirement(item *Item, quantity int) *InputRequirement {
+func NewInputRequirement(item *Item, quantity float64) *InputRequirement {
 	return &InputRequirement{
 		item:     item,
 		quantity: quantity,
@@ -17,20 +23,30 @@ func (i *InputRequirement) Item() *Item {
 	return i.item
 }
 
-func (i *InputRequirement) Quantity() int {
+func (i *InputRequirement) Quantity() float64 {
 	return i.quantity
 }
 
-// OutputDefinition specifies the quantity of a specific item produced by processing
+// OutputDefinition specifies the quantity of a specific item produced by processing.
+// Quantities of fluids may be fractional.
 type OutputDefinition struct {
 	item     *Item
-	quantity int
+	quantity float64
+	// probability is the chance that a cycle yields the quantity; otherwise it yields nothing
+	probability float64
+}
+
+// NewOutputDefinition creates an output yielded by every cycle
+func NewOutputDefinition(item *Item, quantity float64) *OutputDefinition {
+	return NewChanceOutputDefinition(item, quantity, 1)
 }
 
-func NewOutputDefinition(item *Item, quantity int) *OutputDefinition {
+// NewChanceOutputDefinition creates an output yielded by a cycle with the given probability
+func NewChanceOutputDefinition(item *Item, quantity float64, probability float64) *OutputDefinition {
 	return &OutputDefinition{
-		item:     item,
-		quantity: quantity,
+		item:        item,
+		quantity:    quantity,
+		probability: probability,
 	}
 }
 
@@ -38,19 +54,36 @@ func (o *OutputDefinition) Item() *Item {
 	return o.item
 }
 
-func (o *OutputDefinition) Quantity() int {
+func (o *OutputDefinition) Quantity() float64 {
 	return o.quantity
 }
 
+func (o *OutputDefinition) Probability() float64 {
+	return o.probability
+}
+
+// ExpectedQuantity returns the average quantity yielded per cycle
+func (o *OutputDefinition) ExpectedQuantity() float64 {
+	return o.quantity * o.probability
+}
+
 // Facility represents a manufacturing unit that transforms input materials into output products
-// through a time-based production process
+// through a time-based production process.
+//
+// A facility either defines its inputs, outputs and processing time itself, or binds a machine
+// type to a recipe, in which case they are taken from the recipe and the processing time is
+// the crafting time of the recipe divided by the crafting speed of the machine.
 type Facility struct {
 	id                int
 	name              string
 	description       string
 	inputRequirements []*InputRequirement
 	outputDefinitions []*OutputDefinition
-	processingTime    int64
+	// processingTime is the duration of one production cycle in milliseconds
+	processingTime int64
+	machineType    *MachineType
+	recipe         *Recipe
+	power          PowerProfile
 }
 
 // NewFacility creates a new facility with empty input/output requirements
@@ -64,9 +97,21 @@ func NewFacility(name string, description string, processingTime int64) *Facilit
 	}
 }
 
+// NewRecipeFacility creates a facility running the recipe on a machine of the given type
+func NewRecipeFacility(name string, description string, machineType *MachineType, recipe *Recipe) *Facility {
+	return &Facility{
+		name:              name,
+		description:       description,
+		inputRequirements: make([]*InputRequirement, 0),
+		outputDefinitions: make([]*OutputDefinition, 0),
+		machineType:       machineType,
+		recipe:            recipe,
+	}
+}
+
 // NewFacilityFromParams creates a facility with all parameters specified.
 // Use this function only when creating objects from persisted data, and use NewFacility() for other purposes.
-func NewFacilityFromParams(id int, name string, description string, inputReqs []*InputRequirement, outputDefs []*OutputDefinition, processingTime int64) *Facility {
+func NewFacilityFromParams(id int, name string, description string, inputReqs []*InputRequirement, outputDefs []*OutputDefinition, processingTime int64, machineType *MachineType, recipe *Recipe, power PowerProfile) *Facility {
 	return &Facility{
 		id:                id,
 		name:              name,
@@ -74,6 +119,9 @@ func NewFacilityFromParams(id int, name string, description string, inputReqs []
 		inputRequirements: inputReqs,
 		outputDefinitions: outputDefs,
 		processingTime:    processingTime,
+		machineType:       machineType,
+		recipe:            recipe,
+		power:             power,
 	}
 }
 
@@ -90,15 +138,84 @@ func (f *Facility) Description() string {
 }
 
 func (f *Facility) InputRequirements() []*InputRequirement {
+	if f.recipe != nil {
+		return f.recipe.inputRequirements
+	}
 	return f.inputRequirements
 }
 
 func (f *Facility) OutputDefinitions() []*OutputDefinition {
+	if f.recipe != nil {
+		return f.recipe.outputDefinitions
+	}
 	return f.outputDefinitions
 }
 
+// NetQuantities returns, per item ID, the expected quantity a cycle of the facility outputs
+// minus the quantity it consumes, which is negative for items consumed on balance
+func (f *Facility) NetQuantities() map[int]float64 {
+	quantities := make(map[int]float64)
+	for _, output := range f.OutputDefinitions() {
+		quantities[output.Item().ID()] += output.ExpectedQuantity()
+	}
+	for _, input := range f.InputRequirements() {
+		quantities[input.Item().ID()] -= input.Quantity()
+	}
+	return quantities
+}
+
+// ProcessingTime returns the effective duration of one production cycle in milliseconds,
+// rounded to the nearest millisecond for facilities running a recipe
 func (f *Facility) ProcessingTime() int64 {
-	return f.processingTime
+	if f.recipe == nil {
+		return f.processingTime
+	}
+	if f.machineType == nil || f.machineType.craftingSpeed <= 0 {
+		return f.recipe.craftingTime
+	}
+	return int64(math.Round(float64(f.recipe.craftingTime) / f.machineType.craftingSpeed))
+}
+
+// WithProcessingTime returns a copy of the facility taking the given time per cycle in
+// milliseconds. A copy of a facility running a recipe defines the inputs and outputs of the
+// recipe itself, since its processing time no longer follows from the recipe.
+func (f *Facility) WithProcessingTime(processingTime int64) *Facility {
+	return &Facility{
+		id:                f.id,
+		name:              f.name,
+		description:       f.description,
+		inputRequirements: slices.Clone(f.InputRequirements()),
+		outputDefinitions: slices.Clone(f.OutputDefinitions()),
+		processingTime:    processingTime,
+		power:             f.power,
+	}
+}
+
+// Machines returns the number of machines of the facility needed to run the given cycles
+// per minute
+func (f *Facility) Machines(cyclesPerMinute float64) float64 {
+	return cyclesPerMinute * float64(f.ProcessingTime()) / (60 * 1000)
+}
+
+// MachineType returns the machine the facility runs its recipe on, or nil
+func (f *Facility) MachineType() *MachineType {
+	return f.machineType
+}
+
+// Recipe returns the recipe the facility runs, or nil if the facility defines its own
+// inputs, outputs and processing time
+func (f *Facility) Recipe() *Recipe {
+	return f.recipe
+}
+
+// Power returns the power draw and generation of one machine of the facility
+func (f *Facility) Power() PowerProfile {
+	return f.power
+}
+
+// SetPower replaces the power draw and generation of the facility
+func (f *Facility) SetPower(power PowerProfile) {
+	f.power = power
 }
 
 func (f *Facility) AddInputRequirement(req *InputRequirement) {
@@ -0,0 +1,119 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFacilityRecipeBinding(t *testing.T) {
+	ore := NewItemFromParams(1, "Ore", "", ItemKindSolid)
+	plate := NewItemFromParams(2, "Plate", "", ItemKindSolid)
+
+	smelting := NewRecipeFromParams(1, "Smelting", "",
+		[]*InputRequirement{NewInputRequirement(ore, 1)},
+		[]*OutputDefinition{NewOutputDefinition(plate, 1)},
+		3000,
+	)
+
+	testCases := []struct {
+		name                   string
+		facility               *Facility
+		expectedProcessingTime int64
+		expectedInputItemIDs   []int
+		expectedOutputItemIDs  []int
+	}{
+		{
+			name: "facility defining its own recipe",
+			facility: func() *Facility {
+				facility := NewFacility("Furnace", "", 2000)
+				facility.AddInputRequirement(NewInputRequirement(ore, 1))
+				facility.AddOutputDefinition(NewOutputDefinition(plate, 1))
+				return facility
+			}(),
+			expectedProcessingTime: 2000,
+			expectedInputItemIDs:   []int{ore.ID()},
+			expectedOutputItemIDs:  []int{plate.ID()},
+		},
+		{
+			name:                   "machine with crafting speed 1",
+			facility:               NewRecipeFacility("Furnace", "", NewMachineTypeFromParams(1, "Stone Furnace", "", 1), smelting),
+			expectedProcessingTime: 3000,
+			expectedInputItemIDs:   []int{ore.ID()},
+			expectedOutputItemIDs:  []int{plate.ID()},
+		},
+		{
+			name:                   "faster machine shortens processing time",
+			facility:               NewRecipeFacility("Furnace", "", NewMachineTypeFromParams(2, "Steel Furnace", "", 2), smelting),
+			expectedProcessingTime: 1500,
+			expectedInputItemIDs:   []int{ore.ID()},
+			expectedOutputItemIDs:  []int{plate.ID()},
+		},
+		{
+			name:                   "processing time is rounded to milliseconds",
+			facility:               NewRecipeFacility("Furnace", "", NewMachineTypeFromParams(3, "Slow Furnace", "", 0.7), smelting),
+			expectedProcessingTime: 4286,
+			expectedInputItemIDs:   []int{ore.ID()},
+			expectedOutputItemIDs:  []int{plate.ID()},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedProcessingTime, tc.facility.ProcessingTime())
+
+			inputItemIDs := make([]int, 0)
+			for _, input := range tc.facility.InputRequirements() {
+				inputItemIDs = append(inputItemIDs, input.Item().ID())
+			}
+			assert.Equal(t, tc.expectedInputItemIDs, inputItemIDs)
+
+			outputItemIDs := make([]int, 0)
+			for _, output := range tc.facility.OutputDefinitions() {
+				outputItemIDs = append(outputItemIDs, output.Item().ID())
+			}
+			assert.Equal(t, tc.expectedOutputItemIDs, outputItemIDs)
+		})
+	}
+}
+
+func TestFacilityNetQuantities(t *testing.T) {
+	ore := NewItemFromParams(1, "Ore", "", ItemKindSolid)
+	plate := NewItemFromParams(2, "Plate", "", ItemKindSolid)
+	slag := NewItemFromParams(3, "Slag", "", ItemKindSolid)
+
+	// The slag is a catalyst returned with a bonus from half of the cycles
+	facility := NewFacility("Refinery", "", 1500)
+	facility.AddInputRequirement(NewInputRequirement(ore, 2))
+	facility.AddInputRequirement(NewInputRequirement(slag, 1))
+	facility.AddOutputDefinition(NewOutputDefinition(plate, 1))
+	facility.AddOutputDefinition(NewOutputDefinition(slag, 1))
+	facility.AddOutputDefinition(NewChanceOutputDefinition(slag, 1, 0.5))
+
+	assert.Equal(t, map[int]float64{ore.ID(): -2, plate.ID(): 1, slag.ID(): 0.5}, facility.NetQuantities())
+	assert.Equal(t, 1.0, facility.Machines(40))
+}
+
+func TestFacilityWithProcessingTime(t *testing.T) {
+	ore := NewItemFromParams(1, "Ore", "", ItemKindSolid)
+	plate := NewItemFromParams(2, "Plate", "", ItemKindSolid)
+	smelting := NewRecipeFromParams(1, "Smelting", "",
+		[]*InputRequirement{NewInputRequirement(ore, 1)},
+		[]*OutputDefinition{NewOutputDefinition(plate, 1)},
+		3000,
+	)
+	facility := NewFacilityFromParams(4, "Furnace", "Smelts ore", nil, nil, 0,
+		NewMachineTypeFromParams(1, "Steel Furnace", "", 2), smelting, NewPowerProfile(1, 0, 0))
+
+	faster := facility.WithProcessingTime(500)
+	assert.Equal(t, int64(500), faster.ProcessingTime())
+	assert.Equal(t, facility.ID(), faster.ID())
+	assert.Equal(t, facility.Name(), faster.Name())
+	assert.Equal(t, facility.Power(), faster.Power())
+	assert.Equal(t, facility.InputRequirements(), faster.InputRequirements())
+	assert.Equal(t, facility.OutputDefinitions(), faster.OutputDefinitions())
+	assert.Nil(t, faster.Recipe())
+
+	// The original keeps running its recipe
+	assert.Equal(t, int64(1500), facility.ProcessingTime())
+}
@@ -1,27 +1,40 @@
 package models
 
+// ItemKind distinguishes items moved in discrete units from fluids moved in continuous amounts
+type ItemKind string
+
+const (
+	// ItemKindSolid marks items transported on belts
+	ItemKindSolid ItemKind = "solid"
+	// ItemKindFluid marks items transported through pipes, whose quantities may be fractional
+	ItemKindFluid ItemKind = "fluid"
+)
+
 // Item represents a production item
 type Item struct {
 	id          int
 	name        string
 	description string
+	kind        ItemKind
 }
 
 // NewItem creates a new Item
-func NewItem(name, description string) *Item {
+func NewItem(name, description string, kind ItemKind) *Item {
 	return &Item{
 		name:        name,
 		description: description,
+		kind:        kind,
 	}
 }
 
 // NewItemFromParams creates an item with all parameters specified.
 // Use this function only when creating objects from persisted data, and use NewItem() for other purposes.
-func NewItemFromParams(id int, name string, description string) *Item {
+func NewItemFromParams(id int, name string, description string, kind ItemKind) *Item {
 	return &Item{
 		id:          id,
 		name:        name,
 		description: description,
+		kind:        kind,
 	}
 }
 
@@ -39,3 +52,13 @@ func (i *Item) Name() string {
 func (i *Item) Description() string {
 	return i.description
 }
+
+// Kind returns whether the item is a solid or a fluid
+func (i *Item) Kind() ItemKind {
+	return i.kind
+}
+
+// IsFluid reports whether the item is transported through pipes
+func (i *Item) IsFluid() bool {
+	return i.kind == ItemKindFluid
+}
@@ -0,0 +1,47 @@
+package models
+
+// MachineType represents a kind of machine that can run any compatible recipe,
+// such as an assembler tier
+type MachineType struct {
+	id          int
+	name        string
+	description string
+	// craftingSpeed divides the crafting time of recipes run by the machine
+	craftingSpeed float64
+}
+
+// NewMachineType creates a new machine type
+func NewMachineType(name string, description string, craftingSpeed float64) *MachineType {
+	return &MachineType{
+		name:          name,
+		description:   description,
+		craftingSpeed: craftingSpeed,
+	}
+}
+
+// NewMachineTypeFromParams creates a machine type with all parameters specified.
+// Use this function only when creating objects from persisted data, and use NewMachineType() for other purposes.
+func NewMachineTypeFromParams(id int, name string, description string, craftingSpeed float64) *MachineType {
+	return &MachineType{
+		id:            id,
+		name:          name,
+		description:   description,
+		craftingSpeed: craftingSpeed,
+	}
+}
+
+func (m *MachineType) ID() int {
+	return m.id
+}
+
+func (m *MachineType) Name() string {
+	return m.name
+}
+
+func (m *MachineType) Description() string {
+	return m.description
+}
+
+func (m *MachineType) CraftingSpeed() float64 {
+	return m.craftingSpeed
+}
@@ -1,28 +1,177 @@
 package models
 
+import (
+	"fmt"
+	"sort"
+)
+
+// PipelineConnection routes items from a node to one of its downstream nodes
+type PipelineConnection struct {
+	targetNodeID int
+	// item restricts the connection to a single item; nil carries every output the
+	// downstream node consumes
+	item *Item
+	// ratio is the fraction of the item's output reserved for this connection; zero
+	// leaves the split to the demand of the downstream nodes
+	ratio float64
+	// priority orders connections competing for the output beyond the reserved ratios,
+	// higher values being served first
+	priority int
+	// maxThroughput caps the flow in items per minute; zero means unlimited
+	maxThroughput float64
+	// transport is the belt or pipe tier of the link, capping the flow of the items it
+	// moves; nil leaves the link without a physical limit
+	transport *Transport
+}
+
+func NewPipelineConnection(targetNodeID int, item *Item, ratio float64, priority int, maxThroughput float64, transport *Transport) *PipelineConnection {
+	return &PipelineConnection{
+		targetNodeID:  targetNodeID,
+		item:          item,
+		ratio:         ratio,
+		priority:      priority,
+		maxThroughput: maxThroughput,
+		transport:     transport,
+	}
+}
+
+func (c *PipelineConnection) TargetNodeID() int {
+	return c.targetNodeID
+}
+
+func (c *PipelineConnection) Item() *Item {
+	return c.item
+}
+
+func (c *PipelineConnection) Ratio() float64 {
+	return c.ratio
+}
+
+func (c *PipelineConnection) Priority() int {
+	return c.priority
+}
+
+func (c *PipelineConnection) MaxThroughput() float64 {
+	return c.maxThroughput
+}
+
+func (c *PipelineConnection) Transport() *Transport {
+	return c.transport
+}
+
+// Carries reports whether the connection may transport the item
+func (c *PipelineConnection) Carries(itemID int) bool {
+	return c.item == nil || c.item.id == itemID
+}
+
+// movesAny reports whether the transport of the connection can move any item the
+// connection carries from the source node to the target node
+func (c *PipelineConnection) movesAny(source *PipelineNode, target *PipelineNode) bool {
+	for _, output := range source.OutputDefinitions() {
+		item := output.item
+		if !c.Carries(item.id) || (target.defined() && !target.requires(item.id)) {
+			continue
+		}
+		if c.transport.Moves(item.kind) {
+			return true
+		}
+	}
+	return false
+}
+
+// NodeKind distinguishes the nodes processing items from the boundaries where items
+// enter and leave a pipeline
+type NodeKind string
+
+const (
+	// NodeKindFacility marks nodes running the machines of a facility
+	NodeKindFacility NodeKind = "facility"
+	// NodeKindSource marks nodes supplying an item to the pipeline, such as a mining patch
+	NodeKindSource NodeKind = "source"
+	// NodeKindSink marks nodes taking an item out of the pipeline, such as a storage or
+	// the demand of a consumer
+	NodeKindSink NodeKind = "sink"
+)
+
 // PipelineNode represents a facility within a production line, defining its connections
-// to downstream facilities to establish material flow
+// to downstream facilities to establish material flow. Source and sink nodes stand for
+// the supply and demand at the boundaries of the line instead of a facility.
 type PipelineNode struct {
-	id          int
-	facility    *Facility
-	nextNodeIDs []int
+	id       int
+	kind     NodeKind
+	facility *Facility
+	// item is supplied by a source node or taken by a sink node
+	item *Item
+	// perMinute is the rate of a source or sink node in items per minute; zero means unlimited
+	perMinute float64
+	// instanceCount is the number of identical machines the node stands for
+	instanceCount int
+	// clockSpeed multiplies the speed of every machine; 1 runs at the facility's processing time
+	clockSpeed  float64
+	buffers     BufferCapacity
+	connections []*PipelineConnection
 }
 
 // NewPipelineNode creates a node with no downstream connections
-func NewPipelineNode(facility *Facility) *PipelineNode {
+func NewPipelineNode(facility *Facility, instanceCount int, clockSpeed float64) *PipelineNode {
 	return &PipelineNode{
-		facility:    facility,
-		nextNodeIDs: make([]int, 0),
+		kind:          NodeKindFacility,
+		facility:      facility,
+		instanceCount: instanceCount,
+		clockSpeed:    clockSpeed,
+		connections:   make([]*PipelineConnection, 0),
 	}
 }
 
 // NewPipelineNodeFromParams creates a node with all parameters specified.
 // Use this function only when creating objects from persisted data, and use NewPipelineNode() for other purposes.
-func NewPipelineNodeFromParams(id int, facility *Facility, nextNodeIDs []int) *PipelineNode {
+func NewPipelineNodeFromParams(id int, facility *Facility, instanceCount int, clockSpeed float64, buffers BufferCapacity, connections []*PipelineConnection) *PipelineNode {
 	return &PipelineNode{
-		id:          id,
-		facility:    facility,
-		nextNodeIDs: nextNodeIDs,
+		id:            id,
+		kind:          NodeKindFacility,
+		facility:      facility,
+		instanceCount: instanceCount,
+		clockSpeed:    clockSpeed,
+		buffers:       buffers,
+		connections:   connections,
+	}
+}
+
+// NewSourceNode creates a node supplying the item at the given rate in items per minute,
+// or without limit if the rate is zero
+func NewSourceNode(item *Item, perMinute float64) *PipelineNode {
+	return newBoundaryNode(NodeKindSource, item, perMinute)
+}
+
+// NewSinkNode creates a node taking the item at the given rate in items per minute, or
+// without limit if the rate is zero
+func NewSinkNode(item *Item, perMinute float64) *PipelineNode {
+	return newBoundaryNode(NodeKindSink, item, perMinute)
+}
+
+func newBoundaryNode(kind NodeKind, item *Item, perMinute float64) *PipelineNode {
+	return &PipelineNode{
+		kind:          kind,
+		item:          item,
+		perMinute:     perMinute,
+		instanceCount: 1,
+		clockSpeed:    1,
+		connections:   make([]*PipelineConnection, 0),
+	}
+}
+
+// NewBoundaryNodeFromParams creates a source or sink node with all parameters specified.
+// Use this function only when creating objects from persisted data, and use NewSourceNode() or NewSinkNode() for other purposes.
+func NewBoundaryNodeFromParams(id int, kind NodeKind, item *Item, perMinute float64, buffers BufferCapacity, connections []*PipelineConnection) *PipelineNode {
+	return &PipelineNode{
+		id:            id,
+		kind:          kind,
+		item:          item,
+		perMinute:     perMinute,
+		instanceCount: 1,
+		clockSpeed:    1,
+		buffers:       buffers,
+		connections:   connections,
 	}
 }
 
@@ -30,16 +179,185 @@ func (n *PipelineNode) ID() int {
 	return n.id
 }
 
+func (n *PipelineNode) Kind() NodeKind {
+	return n.kind
+}
+
+// Facility returns the facility of the node, or nil for source and sink nodes
 func (n *PipelineNode) Facility() *Facility {
 	return n.facility
 }
 
+// SetFacility replaces the facility of a facility node, such as with an alternative
+// producing the same items, keeping its machines and connections
+func (n *PipelineNode) SetFacility(facility *Facility) {
+	n.facility = facility
+}
+
+// Item returns the item of a source or sink node, or nil for facility nodes
+func (n *PipelineNode) Item() *Item {
+	return n.item
+}
+
+// PerMinute returns the rate of a source or sink node; zero means unlimited
+func (n *PipelineNode) PerMinute() float64 {
+	return n.perMinute
+}
+
+// IsBoundary reports whether the node is a source or a sink
+func (n *PipelineNode) IsBoundary() bool {
+	return n.kind == NodeKindSource || n.kind == NodeKindSink
+}
+
+// InputRequirements returns what a cycle of the node consumes. A cycle of a sink node
+// takes a single item.
+func (n *PipelineNode) InputRequirements() []*InputRequirement {
+	switch {
+	case n.kind == NodeKindSink && n.item != nil:
+		return []*InputRequirement{NewInputRequirement(n.item, 1)}
+	case n.facility != nil:
+		return n.facility.InputRequirements()
+	}
+	return nil
+}
+
+// OutputDefinitions returns what a cycle of the node yields. A cycle of a source node
+// supplies a single item.
+func (n *PipelineNode) OutputDefinitions() []*OutputDefinition {
+	switch {
+	case n.kind == NodeKindSource && n.item != nil:
+		return []*OutputDefinition{NewOutputDefinition(n.item, 1)}
+	case n.facility != nil:
+		return n.facility.OutputDefinitions()
+	}
+	return nil
+}
+
+// Power returns the power profile of a machine of the node; source and sink nodes
+// exchange no power
+func (n *PipelineNode) Power() PowerProfile {
+	if n.facility == nil {
+		return PowerProfile{}
+	}
+	return n.facility.Power()
+}
+
+func (n *PipelineNode) InstanceCount() int {
+	return n.instanceCount
+}
+
+func (n *PipelineNode) ClockSpeed() float64 {
+	return n.clockSpeed
+}
+
+func (n *PipelineNode) Buffers() BufferCapacity {
+	return n.buffers
+}
+
+func (n *PipelineNode) SetBuffers(buffers BufferCapacity) {
+	n.buffers = buffers
+}
+
+// CycleTime returns how long one machine of the node takes per cycle, in milliseconds.
+// It is zero for source and sink nodes without a rate limit and for facility nodes without
+// a facility.
+func (n *PipelineNode) CycleTime() float64 {
+	if n.IsBoundary() {
+		if n.perMinute <= 0 {
+			return 0
+		}
+		return 60 * 1000 / n.perMinute
+	}
+	if n.facility == nil {
+		return 0
+	}
+	return float64(n.facility.ProcessingTime()) / n.clockSpeed
+}
+
+// defined reports whether the node has the facility or item it needs
+func (n *PipelineNode) defined() bool {
+	if n.IsBoundary() {
+		return n.item != nil
+	}
+	return n.facility != nil
+}
+
+// Name returns the name of the facility of the node, or the kind and item of boundaries
+func (n *PipelineNode) Name() string {
+	if n.IsBoundary() {
+		if n.item == nil {
+			return string(n.kind)
+		}
+		return fmt.Sprintf("%s %s", n.kind, n.item.name)
+	}
+	if n.facility == nil {
+		return ""
+	}
+	return n.facility.name
+}
+
+// label names the node in diagnostics
+func (n *PipelineNode) label() string {
+	if n.IsBoundary() {
+		return fmt.Sprintf("%s %q", n.kind, n.item.name)
+	}
+	return fmt.Sprintf("%q", n.facility.name)
+}
+
+// outputs reports whether the node yields the item
+func (n *PipelineNode) outputs(itemID int) bool {
+	for _, output := range n.OutputDefinitions() {
+		if output.item.id == itemID {
+			return true
+		}
+	}
+	return false
+}
+
+// requires reports whether the node consumes the item
+func (n *PipelineNode) requires(itemID int) bool {
+	for _, input := range n.InputRequirements() {
+		if input.item.id == itemID {
+			return true
+		}
+	}
+	return false
+}
+
+// feeds reports whether the node yields any item the other node consumes
+func (n *PipelineNode) feeds(other *PipelineNode) bool {
+	for _, input := range other.InputRequirements() {
+		if n.outputs(input.item.id) {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *PipelineNode) Connections() []*PipelineConnection {
+	return n.connections
+}
+
+// NextNodeIDs returns the distinct IDs of the downstream nodes in connection order
 func (n *PipelineNode) NextNodeIDs() []int {
-	return n.nextNodeIDs
+	ids := make([]int, 0, len(n.connections))
+	seen := make(map[int]bool, len(n.connections))
+	for _, conn := range n.connections {
+		if !seen[conn.targetNodeID] {
+			seen[conn.targetNodeID] = true
+			ids = append(ids, conn.targetNodeID)
+		}
+	}
+	return ids
 }
 
+// AddNextNodeID connects the node to a downstream node without routing constraints
 func (n *PipelineNode) AddNextNodeID(nodeID int) {
-	n.nextNodeIDs = append(n.nextNodeIDs, nodeID)
+	n.AddConnection(NewPipelineConnection(nodeID, nil, 0, 0, 0, nil))
+}
+
+func (n *PipelineNode) AddConnection(conn *PipelineConnection) {
+	n.connections = append(n.connections, conn)
 }
 
 // Pipeline represents a manufacturing line that connects multiple facilities
@@ -52,10 +370,11 @@ type Pipeline struct {
 }
 
 // NewPipeline creates an empty pipeline with no nodes
-func NewPipeline(name string) *Pipeline {
+func NewPipeline(name string, description string) *Pipeline {
 	return &Pipeline{
-		name:  name,
-		nodes: make(map[int]*PipelineNode),
+		name:        name,
+		description: description,
+		nodes:       make(map[int]*PipelineNode),
 	}
 }
 
@@ -86,6 +405,169 @@ func (p *Pipeline) Nodes() map[int]*PipelineNode {
 	return p.nodes
 }
 
+// AddNode adds a node to the pipeline. A node without an ID is given a temporary ID
+// following the largest one in use, so that NextNodeIDs can reference it before the
+// pipeline is persisted.
 func (p *Pipeline) AddNode(node *PipelineNode) {
+	if node.id == 0 {
+		for id := range p.nodes {
+			if id > node.id {
+				node.id = id
+			}
+		}
+		node.id++
+	}
 	p.nodes[node.id] = node
 }
+
+// Validate checks the pipeline graph and returns its diagnostics ordered by node ID
+func (p *Pipeline) Validate() []*Diagnostic {
+	diagnostics := make([]*Diagnostic, 0)
+	if len(p.nodes) == 0 {
+		return append(diagnostics, NewDiagnostic(SeverityWarning, 0, 0, "pipeline has no nodes"))
+	}
+
+	ids := make([]int, 0, len(p.nodes))
+	for id := range p.nodes {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	// Map from node ID to the nodes feeding it, ignoring self-loops and dangling references
+	suppliers := make(map[int][]*PipelineNode)
+	for _, id := range ids {
+		for _, nextID := range p.nodes[id].NextNodeIDs() {
+			if _, ok := p.nodes[nextID]; ok && nextID != id {
+				suppliers[nextID] = append(suppliers[nextID], p.nodes[id])
+			}
+		}
+	}
+
+	for _, id := range ids {
+		node := p.nodes[id]
+		switch {
+		case node.IsBoundary() && node.item == nil:
+			diagnostics = append(diagnostics, NewDiagnostic(SeverityError, id, 0,
+				fmt.Sprintf("%s node %d has no item", node.kind, id)))
+			continue
+		case node.IsBoundary():
+			if node.perMinute < 0 {
+				diagnostics = append(diagnostics, NewDiagnostic(SeverityError, id, node.item.id,
+					fmt.Sprintf("%s node %d has a negative rate", node.kind, id)))
+			}
+			if node.kind == NodeKindSource && len(suppliers[id]) > 0 {
+				diagnostics = append(diagnostics, NewDiagnostic(SeverityError, id, 0,
+					fmt.Sprintf("source node %d cannot receive items", id)))
+			}
+			if node.kind == NodeKindSink && len(node.connections) > 0 {
+				diagnostics = append(diagnostics, NewDiagnostic(SeverityError, id, 0,
+					fmt.Sprintf("sink node %d cannot deliver items", id)))
+			}
+		case node.facility == nil:
+			diagnostics = append(diagnostics, NewDiagnostic(SeverityError, id, 0,
+				fmt.Sprintf("node %d has no facility", id)))
+			continue
+		case node.facility.ProcessingTime() <= 0:
+			diagnostics = append(diagnostics, NewDiagnostic(SeverityError, id, 0,
+				fmt.Sprintf("facility %q of node %d has a non-positive processing time", node.facility.name, id)))
+		}
+		if node.instanceCount < 1 {
+			diagnostics = append(diagnostics, NewDiagnostic(SeverityError, id, 0,
+				fmt.Sprintf("node %d must have at least one instance", id)))
+		}
+		if node.clockSpeed <= 0 {
+			diagnostics = append(diagnostics, NewDiagnostic(SeverityError, id, 0,
+				fmt.Sprintf("node %d has a non-positive clock speed", id)))
+		}
+		if node.buffers.input < 0 || node.buffers.output < 0 {
+			diagnostics = append(diagnostics, NewDiagnostic(SeverityError, id, 0,
+				fmt.Sprintf("node %d has a negative buffer capacity", id)))
+		}
+
+		ratios := make(map[int]float64)
+		ratioItems := make([]*Item, 0)
+		for _, conn := range node.connections {
+			nextID := conn.targetNodeID
+			next, ok := p.nodes[nextID]
+			switch {
+			case nextID == id:
+				diagnostics = append(diagnostics, NewDiagnostic(SeverityError, id, 0,
+					fmt.Sprintf("node %d is connected to itself", id)))
+			case !ok:
+				diagnostics = append(diagnostics, NewDiagnostic(SeverityError, id, 0,
+					fmt.Sprintf("node %d is connected to node %d, which does not exist", id, nextID)))
+			case conn.item != nil && (!node.outputs(conn.item.id) || (next.defined() && !next.requires(conn.item.id))):
+				diagnostics = append(diagnostics, NewDiagnostic(SeverityError, id, conn.item.id,
+					fmt.Sprintf("connection from node %d to node %d routes %q, which is not passed between them",
+						id, nextID, conn.item.name)))
+			case conn.item == nil && next.defined() && !node.feeds(next):
+				diagnostics = append(diagnostics, NewDiagnostic(SeverityError, id, 0,
+					fmt.Sprintf("%s of node %d produces nothing that %s of node %d consumes",
+						node.label(), id, next.label(), nextID)))
+			}
+
+			if conn.transport != nil && ok && nextID != id && !conn.movesAny(node, next) {
+				diagnostics = append(diagnostics, NewDiagnostic(SeverityError, id, 0,
+					fmt.Sprintf("connection from node %d to node %d uses %q, which cannot move the items it carries",
+						id, nextID, conn.transport.name)))
+			}
+			if conn.ratio < 0 || conn.maxThroughput < 0 {
+				diagnostics = append(diagnostics, NewDiagnostic(SeverityError, id, 0,
+					fmt.Sprintf("connection from node %d to node %d has a negative ratio or throughput", id, nextID)))
+			}
+			if conn.ratio > 0 {
+				if conn.item == nil {
+					diagnostics = append(diagnostics, NewDiagnostic(SeverityError, id, 0,
+						fmt.Sprintf("connection from node %d to node %d has a ratio but no item", id, nextID)))
+				} else {
+					if _, ok := ratios[conn.item.id]; !ok {
+						ratioItems = append(ratioItems, conn.item)
+					}
+					ratios[conn.item.id] += conn.ratio
+				}
+			}
+		}
+		for _, item := range ratioItems {
+			if ratios[item.id] > 1+1e-9 {
+				diagnostics = append(diagnostics, NewDiagnostic(SeverityError, id, item.id,
+					fmt.Sprintf("ratios of %q leaving node %d add up to more than 1", item.name, id)))
+			}
+		}
+
+		for _, input := range node.InputRequirements() {
+			supplied := false
+			for _, supplier := range suppliers[id] {
+				if !supplier.outputs(input.item.id) {
+					continue
+				}
+				for _, conn := range supplier.connections {
+					if conn.targetNodeID == id && conn.Carries(input.item.id) {
+						supplied = true
+					}
+				}
+			}
+			if !supplied {
+				diagnostics = append(diagnostics, NewDiagnostic(SeverityWarning, id, input.item.id,
+					fmt.Sprintf("input %q of node %d is not supplied by any upstream node", input.item.name, id)))
+			}
+		}
+
+		if len(p.nodes) > 1 && len(node.connections) == 0 && len(suppliers[id]) == 0 {
+			diagnostics = append(diagnostics, NewDiagnostic(SeverityWarning, id, 0,
+				fmt.Sprintf("node %d is not connected to any other node", id)))
+		}
+	}
+
+	return diagnostics
+}
+
+// EnsureValid returns a *ValidationError when Validate reports any diagnostic of error severity
+func (p *Pipeline) EnsureValid() error {
+	diagnostics := p.Validate()
+	for _, diagnostic := range diagnostics {
+		if diagnostic.severity == SeverityError {
+			return &ValidationError{diagnostics: diagnostics}
+		}
+	}
+	return nil
+}
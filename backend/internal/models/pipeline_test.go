@@ -0,0 +1,272 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type expectedDiagnostic struct {
+	severity Severity
+	nodeID   int
+	itemID   int
+}
+
+func TestPipelineValidate(t *testing.T) {
+	ore := NewItemFromParams(1, "Ore", "", ItemKindSolid)
+	plate := NewItemFromParams(2, "Plate", "", ItemKindSolid)
+
+	newMiner := func() *Facility {
+		miner := NewFacility("Miner", "", 1000)
+		miner.AddOutputDefinition(NewOutputDefinition(ore, 1))
+		return miner
+	}
+	newFurnace := func() *Facility {
+		furnace := NewFacility("Furnace", "", 2000)
+		furnace.AddInputRequirement(NewInputRequirement(ore, 1))
+		furnace.AddOutputDefinition(NewOutputDefinition(plate, 1))
+		return furnace
+	}
+
+	testCases := []struct {
+		name     string
+		pipeline func() *Pipeline
+		expected []expectedDiagnostic
+	}{
+		{
+			name: "valid chain",
+			pipeline: func() *Pipeline {
+				pipeline := NewPipeline("Test", "")
+				miner := NewPipelineNode(newMiner(), 1, 1)
+				miner.AddNextNodeID(2)
+				pipeline.AddNode(miner)
+				pipeline.AddNode(NewPipelineNode(newFurnace(), 1, 1))
+				return pipeline
+			},
+			expected: []expectedDiagnostic{},
+		},
+		{
+			name: "empty pipeline",
+			pipeline: func() *Pipeline {
+				return NewPipeline("Test", "")
+			},
+			expected: []expectedDiagnostic{{severity: SeverityWarning}},
+		},
+		{
+			name: "dangling next node",
+			pipeline: func() *Pipeline {
+				pipeline := NewPipeline("Test", "")
+				miner := NewPipelineNode(newMiner(), 1, 1)
+				miner.AddNextNodeID(5)
+				pipeline.AddNode(miner)
+				return pipeline
+			},
+			expected: []expectedDiagnostic{{severity: SeverityError, nodeID: 1}},
+		},
+		{
+			name: "self-loop",
+			pipeline: func() *Pipeline {
+				pipeline := NewPipeline("Test", "")
+				miner := NewPipelineNode(newMiner(), 1, 1)
+				miner.AddNextNodeID(1)
+				pipeline.AddNode(miner)
+				return pipeline
+			},
+			expected: []expectedDiagnostic{{severity: SeverityError, nodeID: 1}},
+		},
+		{
+			name: "connection carrying no item",
+			pipeline: func() *Pipeline {
+				pipeline := NewPipeline("Test", "")
+				furnace := NewPipelineNode(newFurnace(), 1, 1)
+				furnace.AddNextNodeID(2)
+				pipeline.AddNode(furnace)
+				pipeline.AddNode(NewPipelineNode(newFurnace(), 1, 1))
+				return pipeline
+			},
+			expected: []expectedDiagnostic{
+				{severity: SeverityError, nodeID: 1},
+				{severity: SeverityWarning, nodeID: 1, itemID: ore.ID()},
+				{severity: SeverityWarning, nodeID: 2, itemID: ore.ID()},
+			},
+		},
+		{
+			name: "connection routing an item the source does not output",
+			pipeline: func() *Pipeline {
+				pipeline := NewPipeline("Test", "")
+				miner := NewPipelineNode(newMiner(), 1, 1)
+				miner.AddConnection(NewPipelineConnection(2, plate, 0, 0, 0, nil))
+				pipeline.AddNode(miner)
+				pipeline.AddNode(NewPipelineNode(newFurnace(), 1, 1))
+				return pipeline
+			},
+			expected: []expectedDiagnostic{
+				{severity: SeverityError, nodeID: 1, itemID: plate.ID()},
+				{severity: SeverityWarning, nodeID: 2, itemID: ore.ID()},
+			},
+		},
+		{
+			name: "ratio without item",
+			pipeline: func() *Pipeline {
+				pipeline := NewPipeline("Test", "")
+				miner := NewPipelineNode(newMiner(), 1, 1)
+				miner.AddConnection(NewPipelineConnection(2, nil, 0.5, 0, 0, nil))
+				pipeline.AddNode(miner)
+				pipeline.AddNode(NewPipelineNode(newFurnace(), 1, 1))
+				return pipeline
+			},
+			expected: []expectedDiagnostic{{severity: SeverityError, nodeID: 1}},
+		},
+		{
+			name: "ratios adding up to more than the output",
+			pipeline: func() *Pipeline {
+				pipeline := NewPipeline("Test", "")
+				miner := NewPipelineNode(newMiner(), 1, 1)
+				miner.AddConnection(NewPipelineConnection(2, ore, 0.7, 0, 0, nil))
+				miner.AddConnection(NewPipelineConnection(3, ore, 0.6, 0, 0, nil))
+				pipeline.AddNode(miner)
+				pipeline.AddNode(NewPipelineNode(newFurnace(), 1, 1))
+				pipeline.AddNode(NewPipelineNode(newFurnace(), 1, 1))
+				return pipeline
+			},
+			expected: []expectedDiagnostic{{severity: SeverityError, nodeID: 1, itemID: ore.ID()}},
+		},
+		{
+			name: "transport unable to move the carried items",
+			pipeline: func() *Pipeline {
+				pipeline := NewPipeline("Test", "")
+				miner := NewPipelineNode(newMiner(), 1, 1)
+				miner.AddConnection(NewPipelineConnection(2, nil, 0, 0, 0, NewTransport("Pipe", "", ItemKindFluid, 20)))
+				pipeline.AddNode(miner)
+				pipeline.AddNode(NewPipelineNode(newFurnace(), 1, 1))
+				return pipeline
+			},
+			expected: []expectedDiagnostic{{severity: SeverityError, nodeID: 1}},
+		},
+		{
+			name: "disconnected nodes",
+			pipeline: func() *Pipeline {
+				pipeline := NewPipeline("Test", "")
+				pipeline.AddNode(NewPipelineNode(newMiner(), 1, 1))
+				pipeline.AddNode(NewPipelineNode(newMiner(), 1, 1))
+				return pipeline
+			},
+			expected: []expectedDiagnostic{
+				{severity: SeverityWarning, nodeID: 1},
+				{severity: SeverityWarning, nodeID: 2},
+			},
+		},
+		{
+			name: "non-positive processing time",
+			pipeline: func() *Pipeline {
+				pipeline := NewPipeline("Test", "")
+				pipeline.AddNode(NewPipelineNode(NewFacility("Broken", "", 0), 1, 1))
+				return pipeline
+			},
+			expected: []expectedDiagnostic{{severity: SeverityError, nodeID: 1}},
+		},
+		{
+			name: "no instances and non-positive clock speed",
+			pipeline: func() *Pipeline {
+				pipeline := NewPipeline("Test", "")
+				pipeline.AddNode(NewPipelineNode(newMiner(), 0, 0))
+				return pipeline
+			},
+			expected: []expectedDiagnostic{
+				{severity: SeverityError, nodeID: 1},
+				{severity: SeverityError, nodeID: 1},
+			},
+		},
+		{
+			name: "negative buffer capacity",
+			pipeline: func() *Pipeline {
+				pipeline := NewPipeline("Test", "")
+				node := NewPipelineNode(newMiner(), 1, 1)
+				node.SetBuffers(NewBufferCapacity(0, -1))
+				pipeline.AddNode(node)
+				return pipeline
+			},
+			expected: []expectedDiagnostic{{severity: SeverityError, nodeID: 1}},
+		},
+		{
+			name: "source and sink bounding a facility",
+			pipeline: func() *Pipeline {
+				pipeline := NewPipeline("Test", "")
+				source := NewSourceNode(ore, 30)
+				source.AddNextNodeID(2)
+				pipeline.AddNode(source)
+				furnace := NewPipelineNode(newFurnace(), 1, 1)
+				furnace.AddNextNodeID(3)
+				pipeline.AddNode(furnace)
+				pipeline.AddNode(NewSinkNode(plate, 0))
+				return pipeline
+			},
+			expected: []expectedDiagnostic{},
+		},
+		{
+			name: "source receiving and sink delivering items",
+			pipeline: func() *Pipeline {
+				pipeline := NewPipeline("Test", "")
+				sink := NewSinkNode(ore, 0)
+				sink.AddNextNodeID(2)
+				pipeline.AddNode(sink)
+				source := NewSourceNode(ore, 0)
+				source.AddNextNodeID(1)
+				pipeline.AddNode(source)
+				return pipeline
+			},
+			expected: []expectedDiagnostic{
+				{severity: SeverityError, nodeID: 1},
+				{severity: SeverityError, nodeID: 1},
+				{severity: SeverityError, nodeID: 2},
+			},
+		},
+		{
+			name: "boundary nodes without item or with a negative rate",
+			pipeline: func() *Pipeline {
+				pipeline := NewPipeline("Test", "")
+				source := NewSourceNode(nil, 0)
+				pipeline.AddNode(source)
+				pipeline.AddNode(NewSinkNode(plate, -1))
+				return pipeline
+			},
+			expected: []expectedDiagnostic{
+				{severity: SeverityError, nodeID: 1},
+				{severity: SeverityError, nodeID: 2, itemID: 2},
+				{severity: SeverityWarning, nodeID: 2, itemID: 2},
+				{severity: SeverityWarning, nodeID: 2},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			diagnostics := tc.pipeline().Validate()
+
+			actual := make([]expectedDiagnostic, len(diagnostics))
+			for i, diagnostic := range diagnostics {
+				assert.NotEmpty(t, diagnostic.Message())
+				actual[i] = expectedDiagnostic{
+					severity: diagnostic.Severity(),
+					nodeID:   diagnostic.NodeID(),
+					itemID:   diagnostic.ItemID(),
+				}
+			}
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestPipelineEnsureValid(t *testing.T) {
+	pipeline := NewPipeline("Test", "")
+	node := NewPipelineNode(NewFacility("Miner", "", 1000), 1, 1)
+	node.AddNextNodeID(5)
+	pipeline.AddNode(node)
+
+	err := pipeline.EnsureValid()
+
+	var validationErr *ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+	assert.Len(t, validationErr.Diagnostics(), 1)
+	assert.Contains(t, err.Error(), "does not exist")
+}
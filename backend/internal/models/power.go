@@ -0,0 +1,37 @@
+package models
+
+// PowerProfile describes the electricity one machine of a facility exchanges with the
+// power grid, in megawatts
+type PowerProfile struct {
+	// activeDraw is consumed while the machine is in a production cycle
+	activeDraw float64
+	// idleDraw is consumed while the machine waits for inputs or for outputs to be taken
+	idleDraw float64
+	// generation is fed into the grid while the machine is in a production cycle
+	generation float64
+}
+
+func NewPowerProfile(activeDraw float64, idleDraw float64, generation float64) PowerProfile {
+	return PowerProfile{
+		activeDraw: activeDraw,
+		idleDraw:   idleDraw,
+		generation: generation,
+	}
+}
+
+func (p PowerProfile) ActiveDraw() float64 {
+	return p.activeDraw
+}
+
+func (p PowerProfile) IdleDraw() float64 {
+	return p.idleDraw
+}
+
+func (p PowerProfile) Generation() float64 {
+	return p.generation
+}
+
+// IsGenerator reports whether the machine feeds power into the grid
+func (p PowerProfile) IsGenerator() bool {
+	return p.generation > 0
+}
@@ -0,0 +1,69 @@
+package models
+
+// Recipe describes how input items are turned into output items, independent of the
+// machine that runs it
+type Recipe struct {
+	id                int
+	name              string
+	description       string
+	inputRequirements []*InputRequirement
+	outputDefinitions []*OutputDefinition
+	// craftingTime is the duration of one cycle in milliseconds on a machine with crafting speed 1
+	craftingTime int64
+}
+
+// NewRecipe creates a new recipe with empty input/output requirements
+func NewRecipe(name string, description string, craftingTime int64) *Recipe {
+	return &Recipe{
+		name:              name,
+		description:       description,
+		craftingTime:      craftingTime,
+		inputRequirements: make([]*InputRequirement, 0),
+		outputDefinitions: make([]*OutputDefinition, 0),
+	}
+}
+
+// NewRecipeFromParams creates a recipe with all parameters specified.
+// Use this function only when creating objects from persisted data, and use NewRecipe() for other purposes.
+func NewRecipeFromParams(id int, name string, description string, inputReqs []*InputRequirement, outputDefs []*OutputDefinition, craftingTime int64) *Recipe {
+	return &Recipe{
+		id:                id,
+		name:              name,
+		description:       description,
+		inputRequirements: inputReqs,
+		outputDefinitions: outputDefs,
+		craftingTime:      craftingTime,
+	}
+}
+
+func (r *Recipe) ID() int {
+	return r.id
+}
+
+func (r *Recipe) Name() string {
+	return r.name
+}
+
+func (r *Recipe) Description() string {
+	return r.description
+}
+
+func (r *Recipe) InputRequirements() []*InputRequirement {
+	return r.inputRequirements
+}
+
+func (r *Recipe) OutputDefinitions() []*OutputDefinition {
+	return r.outputDefinitions
+}
+
+func (r *Recipe) CraftingTime() int64 {
+	return r.craftingTime
+}
+
+func (r *Recipe) AddInputRequirement(req *InputRequirement) {
+	r.inputRequirements = append(r.inputRequirements, req)
+}
+
+func (r *Recipe) AddOutputDefinition(def *OutputDefinition) {
+	r.outputDefinitions = append(r.outputDefinitions, def)
+}
@@ -0,0 +1,353 @@
+package models
+
+import "time"
+
+// SimulationStatus is the stage of the lifecycle a simulation run is in
+type SimulationStatus string
+
+const (
+	SimulationStatusPending   SimulationStatus = "pending"
+	SimulationStatusRunning   SimulationStatus = "running"
+	SimulationStatusCompleted SimulationStatus = "completed"
+	SimulationStatusFailed    SimulationStatus = "failed"
+	SimulationStatusCancelled SimulationStatus = "cancelled"
+)
+
+// IsFinished reports whether a run in the status has ended, successfully or not
+func (s SimulationStatus) IsFinished() bool {
+	return s == SimulationStatusCompleted || s == SimulationStatusFailed || s == SimulationStatusCancelled
+}
+
+// SimulationSettings holds the parameters a simulation run was started with
+type SimulationSettings struct {
+	duration time.Duration
+	// timeStep is the interval between samples of the node buffers; zero takes no samples
+	timeStep time.Duration
+	seed     uint64
+	// bufferCapacity is the input buffer of nodes without one of their own; zero means unlimited
+	bufferCapacity int
+}
+
+func NewSimulationSettings(duration time.Duration, timeStep time.Duration, seed uint64, bufferCapacity int) SimulationSettings {
+	return SimulationSettings{
+		duration:       duration,
+		timeStep:       timeStep,
+		seed:           seed,
+		bufferCapacity: bufferCapacity,
+	}
+}
+
+func (s SimulationSettings) Duration() time.Duration {
+	return s.duration
+}
+
+func (s SimulationSettings) TimeStep() time.Duration {
+	return s.timeStep
+}
+
+func (s SimulationSettings) Seed() uint64 {
+	return s.seed
+}
+
+func (s SimulationSettings) BufferCapacity() int {
+	return s.bufferCapacity
+}
+
+// SimulationSample is the fill of the buffers of a node at a point in simulated time,
+// along with the units it has produced and consumed so far
+type SimulationSample struct {
+	time time.Duration
+	// inputBuffer, outputBuffer, produced and consumed are keyed by item ID
+	inputBuffer  map[int]float64
+	outputBuffer map[int]float64
+	produced     map[int]float64
+	consumed     map[int]float64
+}
+
+func NewSimulationSample(time time.Duration, inputBuffer map[int]float64, outputBuffer map[int]float64, produced map[int]float64, consumed map[int]float64) *SimulationSample {
+	return &SimulationSample{
+		time:         time,
+		inputBuffer:  inputBuffer,
+		outputBuffer: outputBuffer,
+		produced:     produced,
+		consumed:     consumed,
+	}
+}
+
+func (s *SimulationSample) Time() time.Duration {
+	return s.time
+}
+
+func (s *SimulationSample) InputBuffer() map[int]float64 {
+	return s.inputBuffer
+}
+
+func (s *SimulationSample) OutputBuffer() map[int]float64 {
+	return s.outputBuffer
+}
+
+func (s *SimulationSample) Produced() map[int]float64 {
+	return s.produced
+}
+
+func (s *SimulationSample) Consumed() map[int]float64 {
+	return s.consumed
+}
+
+// SimulationNodeStats holds the statistics a simulation run collected for a pipeline node
+type SimulationNodeStats struct {
+	nodeID     int
+	facilityID int
+	cycles     int
+	// produced and consumed are keyed by item ID
+	produced    map[int]float64
+	consumed    map[int]float64
+	busyTime    time.Duration
+	idleTime    time.Duration
+	starvedTime time.Duration
+	blockedTime time.Duration
+	samples     []*SimulationSample
+}
+
+func NewSimulationNodeStats(nodeID int, facilityID int, cycles int, produced map[int]float64, consumed map[int]float64, busyTime time.Duration, idleTime time.Duration, starvedTime time.Duration, blockedTime time.Duration, samples []*SimulationSample) *SimulationNodeStats {
+	return &SimulationNodeStats{
+		nodeID:      nodeID,
+		facilityID:  facilityID,
+		cycles:      cycles,
+		produced:    produced,
+		consumed:    consumed,
+		busyTime:    busyTime,
+		idleTime:    idleTime,
+		starvedTime: starvedTime,
+		blockedTime: blockedTime,
+		samples:     samples,
+	}
+}
+
+func (s *SimulationNodeStats) NodeID() int {
+	return s.nodeID
+}
+
+func (s *SimulationNodeStats) FacilityID() int {
+	return s.facilityID
+}
+
+func (s *SimulationNodeStats) Cycles() int {
+	return s.cycles
+}
+
+func (s *SimulationNodeStats) Produced() map[int]float64 {
+	return s.produced
+}
+
+func (s *SimulationNodeStats) Consumed() map[int]float64 {
+	return s.consumed
+}
+
+func (s *SimulationNodeStats) BusyTime() time.Duration {
+	return s.busyTime
+}
+
+func (s *SimulationNodeStats) IdleTime() time.Duration {
+	return s.idleTime
+}
+
+func (s *SimulationNodeStats) StarvedTime() time.Duration {
+	return s.starvedTime
+}
+
+func (s *SimulationNodeStats) BlockedTime() time.Duration {
+	return s.blockedTime
+}
+
+func (s *SimulationNodeStats) Samples() []*SimulationSample {
+	return s.samples
+}
+
+// Utilization returns the fraction of the run the node spent processing
+func (s *SimulationNodeStats) Utilization() float64 {
+	total := s.busyTime + s.idleTime
+	if total == 0 {
+		return 0
+	}
+	return float64(s.busyTime) / float64(total)
+}
+
+// SimulationLinkStats holds the statistics a simulation run collected for a connection
+// carried by a transport
+type SimulationLinkStats struct {
+	sourceNodeID      int
+	targetNodeID      int
+	transportID       int
+	moved             float64
+	capacityPerMinute float64
+	saturated         bool
+}
+
+func NewSimulationLinkStats(sourceNodeID int, targetNodeID int, transportID int, moved float64, capacityPerMinute float64, saturated bool) *SimulationLinkStats {
+	return &SimulationLinkStats{
+		sourceNodeID:      sourceNodeID,
+		targetNodeID:      targetNodeID,
+		transportID:       transportID,
+		moved:             moved,
+		capacityPerMinute: capacityPerMinute,
+		saturated:         saturated,
+	}
+}
+
+func (s *SimulationLinkStats) SourceNodeID() int {
+	return s.sourceNodeID
+}
+
+func (s *SimulationLinkStats) TargetNodeID() int {
+	return s.targetNodeID
+}
+
+func (s *SimulationLinkStats) TransportID() int {
+	return s.transportID
+}
+
+func (s *SimulationLinkStats) Moved() float64 {
+	return s.moved
+}
+
+func (s *SimulationLinkStats) CapacityPerMinute() float64 {
+	return s.capacityPerMinute
+}
+
+func (s *SimulationLinkStats) Saturated() bool {
+	return s.saturated
+}
+
+// SimulationResult holds the outcome of a completed simulation run
+type SimulationResult struct {
+	// powerDemand and powerGeneration are averages over the run, in megawatts
+	powerDemand     float64
+	powerGeneration float64
+	// nodes is ordered by node ID and links by source and target node ID
+	nodes []*SimulationNodeStats
+	links []*SimulationLinkStats
+}
+
+func NewSimulationResult(powerDemand float64, powerGeneration float64, nodes []*SimulationNodeStats, links []*SimulationLinkStats) *SimulationResult {
+	return &SimulationResult{
+		powerDemand:     powerDemand,
+		powerGeneration: powerGeneration,
+		nodes:           nodes,
+		links:           links,
+	}
+}
+
+func (r *SimulationResult) PowerDemand() float64 {
+	return r.powerDemand
+}
+
+func (r *SimulationResult) PowerGeneration() float64 {
+	return r.powerGeneration
+}
+
+func (r *SimulationResult) Nodes() []*SimulationNodeStats {
+	return r.nodes
+}
+
+func (r *SimulationResult) Links() []*SimulationLinkStats {
+	return r.links
+}
+
+// SimulationRun is a simulation of a pipeline executed in the background, together with
+// its outcome once it has finished
+type SimulationRun struct {
+	id         int
+	pipelineID int
+	settings   SimulationSettings
+	status     SimulationStatus
+	// errorMessage explains why a failed run stopped
+	errorMessage string
+	createdAt    time.Time
+	// finishedAt is zero until the run has finished
+	finishedAt time.Time
+	// result is only set for completed runs
+	result *SimulationResult
+}
+
+// NewSimulationRun creates a pending run of the pipeline with the given ID
+func NewSimulationRun(pipelineID int, settings SimulationSettings) *SimulationRun {
+	return &SimulationRun{
+		pipelineID: pipelineID,
+		settings:   settings,
+		status:     SimulationStatusPending,
+	}
+}
+
+// NewSimulationRunFromParams creates a simulation run with all parameters specified.
+// Use this function only when creating objects from persisted data, and use NewSimulationRun() for other purposes.
+func NewSimulationRunFromParams(id int, pipelineID int, settings SimulationSettings, status SimulationStatus, errorMessage string, createdAt time.Time, finishedAt time.Time, result *SimulationResult) *SimulationRun {
+	return &SimulationRun{
+		id:           id,
+		pipelineID:   pipelineID,
+		settings:     settings,
+		status:       status,
+		errorMessage: errorMessage,
+		createdAt:    createdAt,
+		finishedAt:   finishedAt,
+		result:       result,
+	}
+}
+
+func (r *SimulationRun) ID() int {
+	return r.id
+}
+
+func (r *SimulationRun) PipelineID() int {
+	return r.pipelineID
+}
+
+func (r *SimulationRun) Settings() SimulationSettings {
+	return r.settings
+}
+
+func (r *SimulationRun) Status() SimulationStatus {
+	return r.status
+}
+
+func (r *SimulationRun) ErrorMessage() string {
+	return r.errorMessage
+}
+
+func (r *SimulationRun) CreatedAt() time.Time {
+	return r.createdAt
+}
+
+func (r *SimulationRun) FinishedAt() time.Time {
+	return r.finishedAt
+}
+
+func (r *SimulationRun) Result() *SimulationResult {
+	return r.result
+}
+
+// Start marks the run as running
+func (r *SimulationRun) Start() {
+	r.status = SimulationStatusRunning
+}
+
+// Complete marks the run as completed with the given result
+func (r *SimulationRun) Complete(result *SimulationResult, at time.Time) {
+	r.status = SimulationStatusCompleted
+	r.result = result
+	r.finishedAt = at
+}
+
+// Fail marks the run as failed for the given reason, discarding any result
+func (r *SimulationRun) Fail(message string, at time.Time) {
+	r.status = SimulationStatusFailed
+	r.errorMessage = message
+	r.result = nil
+	r.finishedAt = at
+}
+
+// Cancel marks the run as cancelled
+func (r *SimulationRun) Cancel(at time.Time) {
+	r.status = SimulationStatusCancelled
+	r.finishedAt = at
+}
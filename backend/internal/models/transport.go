@@ -0,0 +1,65 @@
+package models
+
+// Transport represents a tier of belts or pipes moving items between pipeline nodes,
+// such as a yellow belt or a conveyor Mk3
+type Transport struct {
+	id          int
+	name        string
+	description string
+	// kind is the kind of items the transport moves, belts moving solids and pipes fluids
+	kind ItemKind
+	// itemsPerSecond is the capacity of a single link, in units of fluid for pipes
+	itemsPerSecond float64
+}
+
+// NewTransport creates a new transport tier
+func NewTransport(name string, description string, kind ItemKind, itemsPerSecond float64) *Transport {
+	return &Transport{
+		name:           name,
+		description:    description,
+		kind:           kind,
+		itemsPerSecond: itemsPerSecond,
+	}
+}
+
+// NewTransportFromParams creates a transport tier with all parameters specified.
+// Use this function only when creating objects from persisted data, and use NewTransport() for other purposes.
+func NewTransportFromParams(id int, name string, description string, kind ItemKind, itemsPerSecond float64) *Transport {
+	return &Transport{
+		id:             id,
+		name:           name,
+		description:    description,
+		kind:           kind,
+		itemsPerSecond: itemsPerSecond,
+	}
+}
+
+func (t *Transport) ID() int {
+	return t.id
+}
+
+func (t *Transport) Name() string {
+	return t.name
+}
+
+func (t *Transport) Description() string {
+	return t.description
+}
+
+func (t *Transport) Kind() ItemKind {
+	return t.kind
+}
+
+func (t *Transport) ItemsPerSecond() float64 {
+	return t.itemsPerSecond
+}
+
+// ItemsPerMinute returns the capacity in the per-minute rates used by the analyses
+func (t *Transport) ItemsPerMinute() float64 {
+	return t.itemsPerSecond * 60
+}
+
+// Moves reports whether the transport can carry items of the given kind
+func (t *Transport) Moves(kind ItemKind) bool {
+	return (kind == ItemKindFluid) == (t.kind == ItemKindFluid)
+}
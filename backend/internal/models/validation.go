@@ -0,0 +1,69 @@
+package models
+
+import (
+	"strings"
+)
+
+// Severity classifies how serious a diagnostic is
+type Severity string
+
+const (
+	// SeverityError marks problems that make a pipeline unusable
+	SeverityError Severity = "error"
+	// SeverityWarning marks suspicious configurations that are still allowed
+	SeverityWarning Severity = "warning"
+)
+
+// Diagnostic describes a problem found while validating a pipeline.
+// The node and item IDs are zero when the problem does not concern a specific node or item.
+type Diagnostic struct {
+	severity Severity
+	nodeID   int
+	itemID   int
+	message  string
+}
+
+func NewDiagnostic(severity Severity, nodeID int, itemID int, message string) *Diagnostic {
+	return &Diagnostic{
+		severity: severity,
+		nodeID:   nodeID,
+		itemID:   itemID,
+		message:  message,
+	}
+}
+
+func (d *Diagnostic) Severity() Severity {
+	return d.severity
+}
+
+func (d *Diagnostic) NodeID() int {
+	return d.nodeID
+}
+
+func (d *Diagnostic) ItemID() int {
+	return d.itemID
+}
+
+func (d *Diagnostic) Message() string {
+	return d.message
+}
+
+// ValidationError is returned when a pipeline has diagnostics of error severity
+type ValidationError struct {
+	diagnostics []*Diagnostic
+}
+
+// Diagnostics returns all diagnostics of the pipeline, including warnings
+func (e *ValidationError) Diagnostics() []*Diagnostic {
+	return e.diagnostics
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.diagnostics))
+	for _, diagnostic := range e.diagnostics {
+		if diagnostic.severity == SeverityError {
+			messages = append(messages, diagnostic.message)
+		}
+	}
+	return "invalid pipeline: " + strings.Join(messages, "; ")
+}
@@ -0,0 +1,213 @@
+// Package optimizer chooses how to produce target items at given rates by solving a linear
+// program over all facilities, so that alternative facilities producing the same item are
+// weighed against each other instead of always taking the first one
+package optimizer
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/fasim/backend/internal/models"
+	"github.com/fasim/backend/internal/planner"
+)
+
+// negligible is the size below which rates and machine counts are floating point error
+const negligible = 1e-6
+
+var (
+	// ErrNoTargets is returned when nothing is asked to be produced
+	ErrNoTargets = errors.New("at least one target is required")
+	// ErrInfeasible is returned when the facilities cannot produce the targets, for instance
+	// because every way of making an item needs more of it than it yields
+	ErrInfeasible = errors.New("targets cannot be produced with the available facilities")
+)
+
+// Objective is the quantity an optimization minimizes
+type Objective string
+
+const (
+	// ObjectiveMachines minimizes the number of machines running at full speed
+	ObjectiveMachines Objective = "machines"
+	// ObjectivePower minimizes the power the machines draw while working
+	ObjectivePower Objective = "power"
+	// ObjectiveRawResources minimizes the raw resources consumed per minute
+	ObjectiveRawResources Objective = "rawResources"
+)
+
+// ParseObjective returns the objective with the given name
+func ParseObjective(name string) (Objective, error) {
+	switch Objective(name) {
+	case ObjectiveMachines, ObjectivePower, ObjectiveRawResources:
+		return Objective(name), nil
+	}
+	return "", fmt.Errorf("unsupported objective %q, expected %q, %q or %q", name, ObjectiveMachines, ObjectivePower, ObjectiveRawResources)
+}
+
+// Solution is the cheapest set of facilities found to produce the targets
+type Solution struct {
+	Objective Objective
+	// Value is the minimized quantity: machines, megawatts or raw items per minute
+	Value float64
+	// Targets are the requested items and rates, ordered by item ID
+	Targets []*planner.ItemDemand
+	// Facilities lists the facilities in use ordered by facility ID
+	Facilities []*planner.FacilityDemand
+	// RawResources are items no facility produces, ordered by item ID
+	RawResources []*planner.ItemDemand
+	// Byproducts are items produced beyond the targets and what the facilities consume,
+	// ordered by item ID
+	Byproducts []*planner.ItemDemand
+	// Machines is the exact number of machines, summed over all facilities
+	Machines float64
+	// Power is the power in megawatts the machines draw while working
+	Power float64
+}
+
+// Optimize finds the rates at which to run the facilities so that the targets, in items
+// per minute keyed by item ID, are produced while the objective is minimized. Every
+// facility with a positive processing time is a candidate, items no facility produces
+// are raw resources available at any rate, and ties of the objective are broken by the
+// number of machines, or by the raw resources when minimizing machines.
+func Optimize(facilities []*models.Facility, targets map[int]float64, objective Objective) (*Solution, error) {
+	if len(targets) == 0 {
+		return nil, ErrNoTargets
+	}
+	if _, err := ParseObjective(string(objective)); err != nil {
+		return nil, err
+	}
+
+	candidates := make([]*models.Facility, 0, len(facilities))
+	for _, facility := range facilities {
+		if facility.ProcessingTime() > 0 {
+			candidates = append(candidates, facility)
+		}
+	}
+	book := planner.NewRecipeBook(candidates)
+
+	items := make(map[int]*models.Item)
+	for _, facility := range candidates {
+		for _, output := range facility.OutputDefinitions() {
+			items[output.Item().ID()] = output.Item()
+		}
+		for _, input := range facility.InputRequirements() {
+			items[input.Item().ID()] = input.Item()
+		}
+	}
+	for itemID, perMinute := range targets {
+		if perMinute <= 0 {
+			return nil, fmt.Errorf("production rate of item %d must be positive", itemID)
+		}
+		if len(book.Producers(itemID)) == 0 {
+			return nil, fmt.Errorf("item %d: %w", itemID, planner.ErrNoProducer)
+		}
+	}
+
+	// The variables are the cycles per minute of the candidates. Every produced item must
+	// be made at least as fast as it is consumed, plus its target rate.
+	net := make([]map[int]float64, len(candidates))
+	for i, facility := range candidates {
+		net[i] = facility.NetQuantities()
+	}
+	program := &linearProgram{variables: len(candidates)}
+	for _, itemID := range sortedKeys(items) {
+		if len(book.Producers(itemID)) == 0 {
+			continue
+		}
+		row := make([]float64, len(candidates))
+		for i := range candidates {
+			row[i] = net[i][itemID]
+		}
+		program.addRow(row, targets[itemID])
+	}
+
+	tieBreaker := ObjectiveMachines
+	if objective == ObjectiveMachines {
+		tieBreaker = ObjectiveRawResources
+	}
+	primary := costs(candidates, net, book, objective)
+	cycles, err := program.solve(primary, costs(candidates, net, book, tieBreaker))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInfeasible, err)
+	}
+
+	return newSolution(candidates, net, book, items, targets, objective, primary, cycles), nil
+}
+
+// costs returns the objective per cycle per minute of each candidate
+func costs(candidates []*models.Facility, net []map[int]float64, book *planner.RecipeBook, objective Objective) []float64 {
+	result := make([]float64, len(candidates))
+	for i, facility := range candidates {
+		machines := facility.Machines(1)
+		switch objective {
+		case ObjectiveMachines:
+			result[i] = machines
+		case ObjectivePower:
+			result[i] = machines * facility.Power().ActiveDraw()
+		case ObjectiveRawResources:
+			for itemID, quantity := range net[i] {
+				if quantity < 0 && len(book.Producers(itemID)) == 0 {
+					result[i] -= quantity
+				}
+			}
+		}
+	}
+	return result
+}
+
+func newSolution(candidates []*models.Facility, net []map[int]float64, book *planner.RecipeBook, items map[int]*models.Item, targets map[int]float64, objective Objective, primary []float64, cycles []float64) *Solution {
+	solution := &Solution{
+		Objective:    objective,
+		Targets:      make([]*planner.ItemDemand, 0, len(targets)),
+		Facilities:   make([]*planner.FacilityDemand, 0),
+		RawResources: make([]*planner.ItemDemand, 0),
+		Byproducts:   make([]*planner.ItemDemand, 0),
+	}
+	for _, itemID := range sortedKeys(targets) {
+		solution.Targets = append(solution.Targets, &planner.ItemDemand{Item: items[itemID], PerMinute: targets[itemID]})
+	}
+
+	balance := make(map[int]float64)
+	for i, facility := range candidates {
+		if cycles[i] <= tolerance {
+			continue
+		}
+		solution.Value += cycles[i] * primary[i]
+		count := facility.Machines(cycles[i])
+		solution.Facilities = append(solution.Facilities, &planner.FacilityDemand{
+			Facility:      facility,
+			Count:         count,
+			RequiredCount: int(math.Ceil(count - negligible)),
+		})
+		solution.Machines += count
+		solution.Power += count * facility.Power().ActiveDraw()
+		for itemID, quantity := range net[i] {
+			balance[itemID] += cycles[i] * quantity
+		}
+	}
+	sort.Slice(solution.Facilities, func(i, j int) bool {
+		return solution.Facilities[i].Facility.ID() < solution.Facilities[j].Facility.ID()
+	})
+
+	for _, itemID := range sortedKeys(balance) {
+		rate := balance[itemID] - targets[itemID]
+		if len(book.Producers(itemID)) == 0 {
+			if -rate > negligible {
+				solution.RawResources = append(solution.RawResources, &planner.ItemDemand{Item: items[itemID], PerMinute: -rate})
+			}
+		} else if rate > negligible {
+			solution.Byproducts = append(solution.Byproducts, &planner.ItemDemand{Item: items[itemID], PerMinute: rate})
+		}
+	}
+	return solution
+}
+
+func sortedKeys[V any](m map[int]V) []int {
+	keys := make([]int, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+	return keys
+}
@@ -0,0 +1,192 @@
+package optimizer
+
+import (
+	"testing"
+
+	"github.com/fasim/backend/internal/models"
+	"github.com/fasim/backend/internal/planner"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	ore   = models.NewItemFromParams(1, "Ore", "", models.ItemKindSolid)
+	plate = models.NewItemFromParams(2, "Plate", "", models.ItemKindSolid)
+	gear  = models.NewItemFromParams(3, "Gear", "", models.ItemKindSolid)
+	slag  = models.NewItemFromParams(4, "Slag", "", models.ItemKindSolid)
+)
+
+var (
+	// furnace turns every ore into a plate, at 30 plates per minute and machine
+	furnace = models.NewFacilityFromParams(1, "Furnace", "",
+		[]*models.InputRequirement{models.NewInputRequirement(ore, 1)},
+		[]*models.OutputDefinition{models.NewOutputDefinition(plate, 1)},
+		2000, nil, nil, models.NewPowerProfile(1, 0, 0))
+	// smelter is four times faster than the furnace but wastes a third of its ore as slag
+	smelter = models.NewFacilityFromParams(2, "Smelter", "",
+		[]*models.InputRequirement{models.NewInputRequirement(ore, 3)},
+		[]*models.OutputDefinition{models.NewOutputDefinition(plate, 2), models.NewOutputDefinition(slag, 1)},
+		1000, nil, nil, models.NewPowerProfile(6, 0, 0))
+	// assembler makes 120 gears per minute and machine
+	assembler = models.NewFacilityFromParams(3, "Assembler", "",
+		[]*models.InputRequirement{models.NewInputRequirement(plate, 2)},
+		[]*models.OutputDefinition{models.NewOutputDefinition(gear, 1)},
+		500, nil, nil, models.NewPowerProfile(2, 0, 0))
+)
+
+// facilityCounts returns the exact machine counts keyed by facility ID
+func facilityCounts(demands []*planner.FacilityDemand) map[int]float64 {
+	counts := make(map[int]float64)
+	for _, demand := range demands {
+		counts[demand.Facility.ID()] = demand.Count
+	}
+	return counts
+}
+
+// itemRates returns the rates keyed by item ID
+func itemRates(demands []*planner.ItemDemand) map[int]float64 {
+	rates := make(map[int]float64)
+	for _, demand := range demands {
+		rates[demand.Item.ID()] = demand.PerMinute
+	}
+	return rates
+}
+
+func TestOptimize(t *testing.T) {
+	testCases := []struct {
+		name       string
+		objective  Objective
+		counts     map[int]float64
+		raw        map[int]float64
+		byproducts map[int]float64
+		value      float64
+	}{
+		{
+			// 120 plates take 1 smelter instead of 4 furnaces
+			name:       "Fewest machines",
+			objective:  ObjectiveMachines,
+			counts:     map[int]float64{2: 1, 3: 0.5},
+			raw:        map[int]float64{1: 180},
+			byproducts: map[int]float64{4: 60},
+			value:      1.5,
+		},
+		{
+			// The furnaces draw 4 megawatts against the 6 of the smelter
+			name:       "Least power",
+			objective:  ObjectivePower,
+			counts:     map[int]float64{1: 4, 3: 0.5},
+			raw:        map[int]float64{1: 120},
+			byproducts: map[int]float64{},
+			value:      5,
+		},
+		{
+			name:       "Least raw resources",
+			objective:  ObjectiveRawResources,
+			counts:     map[int]float64{1: 4, 3: 0.5},
+			raw:        map[int]float64{1: 120},
+			byproducts: map[int]float64{},
+			value:      120,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			solution, err := Optimize([]*models.Facility{furnace, smelter, assembler}, map[int]float64{gear.ID(): 60}, tc.objective)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.objective, solution.Objective)
+			assert.InDelta(t, tc.value, solution.Value, 1e-9)
+			require.Len(t, solution.Targets, 1)
+			assert.Equal(t, gear, solution.Targets[0].Item)
+
+			counts := facilityCounts(solution.Facilities)
+			require.Len(t, counts, len(tc.counts))
+			for id, count := range tc.counts {
+				assert.InDelta(t, count, counts[id], 1e-9, "facility %d", id)
+			}
+			machines := 0.0
+			for _, count := range tc.counts {
+				machines += count
+			}
+			assert.InDelta(t, machines, solution.Machines, 1e-9)
+
+			raw := itemRates(solution.RawResources)
+			require.Len(t, raw, len(tc.raw))
+			for id, rate := range tc.raw {
+				assert.InDelta(t, rate, raw[id], 1e-9, "raw item %d", id)
+			}
+			byproducts := itemRates(solution.Byproducts)
+			require.Len(t, byproducts, len(tc.byproducts))
+			for id, rate := range tc.byproducts {
+				assert.InDelta(t, rate, byproducts[id], 1e-9, "byproduct %d", id)
+			}
+		})
+	}
+}
+
+func TestOptimizeSeveralTargets(t *testing.T) {
+	solution, err := Optimize([]*models.Facility{furnace, assembler}, map[int]float64{gear.ID(): 30, plate.ID(): 30}, ObjectiveMachines)
+	require.NoError(t, err)
+
+	// The plates sold and those made into gears come from the same furnaces
+	counts := facilityCounts(solution.Facilities)
+	assert.InDelta(t, 3, counts[furnace.ID()], 1e-9)
+	assert.InDelta(t, 0.25, counts[assembler.ID()], 1e-9)
+	assert.Equal(t, 3, solution.Facilities[0].RequiredCount)
+	assert.Equal(t, 1, solution.Facilities[1].RequiredCount)
+	assert.InDelta(t, 3.5, solution.Power, 1e-9)
+	assert.Equal(t, []int{plate.ID(), gear.ID()}, []int{solution.Targets[0].Item.ID(), solution.Targets[1].Item.ID()})
+	assert.Empty(t, solution.Byproducts)
+}
+
+func TestOptimizeRecyclingLoop(t *testing.T) {
+	// The washer needs a unit of slag per gear and returns it with another from the ore
+	washer := models.NewFacilityFromParams(4, "Washer", "",
+		[]*models.InputRequirement{models.NewInputRequirement(ore, 1), models.NewInputRequirement(slag, 1)},
+		[]*models.OutputDefinition{models.NewOutputDefinition(gear, 1), models.NewOutputDefinition(slag, 2)},
+		1000, nil, nil, models.NewPowerProfile(1, 0, 0))
+	// The crusher recycles two slag back into an ore, faster than the miner digs one up
+	crusher := models.NewFacilityFromParams(5, "Crusher", "",
+		[]*models.InputRequirement{models.NewInputRequirement(slag, 2)},
+		[]*models.OutputDefinition{models.NewOutputDefinition(ore, 1)},
+		500, nil, nil, models.NewPowerProfile(1, 0, 0))
+	miner := models.NewFacilityFromParams(6, "Miner", "",
+		nil,
+		[]*models.OutputDefinition{models.NewOutputDefinition(ore, 1)},
+		1000, nil, nil, models.NewPowerProfile(1, 0, 0))
+
+	solution, err := Optimize([]*models.Facility{washer, crusher, miner}, map[int]float64{gear.ID(): 60}, ObjectiveMachines)
+	require.NoError(t, err)
+
+	// Every two gears leave two slag, recycled into one of the two ores they need
+	counts := facilityCounts(solution.Facilities)
+	assert.InDelta(t, 1, counts[washer.ID()], 1e-9)
+	assert.InDelta(t, 0.25, counts[crusher.ID()], 1e-9)
+	assert.InDelta(t, 0.5, counts[miner.ID()], 1e-9)
+	assert.Empty(t, solution.RawResources)
+	assert.Empty(t, solution.Byproducts)
+}
+
+func TestOptimizeErrors(t *testing.T) {
+	facilities := []*models.Facility{furnace, assembler}
+
+	_, err := Optimize(facilities, nil, ObjectiveMachines)
+	assert.ErrorIs(t, err, ErrNoTargets)
+
+	_, err = Optimize(facilities, map[int]float64{ore.ID(): 60}, ObjectiveMachines)
+	assert.ErrorIs(t, err, planner.ErrNoProducer)
+
+	_, err = Optimize(facilities, map[int]float64{gear.ID(): 0}, ObjectiveMachines)
+	assert.Error(t, err)
+
+	_, err = Optimize(facilities, map[int]float64{gear.ID(): 60}, Objective("speed"))
+	assert.Error(t, err)
+
+	// Each plate takes two gears and each gear two plates, so neither can ever be made
+	reverse := models.NewFacilityFromParams(4, "Reverse", "",
+		[]*models.InputRequirement{models.NewInputRequirement(gear, 2)},
+		[]*models.OutputDefinition{models.NewOutputDefinition(plate, 1)},
+		1000, nil, nil, models.NewPowerProfile(0, 0, 0))
+	_, err = Optimize([]*models.Facility{reverse, assembler}, map[int]float64{gear.ID(): 60}, ObjectiveMachines)
+	assert.ErrorIs(t, err, ErrInfeasible)
+}
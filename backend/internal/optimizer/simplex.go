@@ -0,0 +1,229 @@
+package optimizer
+
+import (
+	"errors"
+	"math"
+)
+
+// tolerance absorbs floating point error in the simplex pivots
+const tolerance = 1e-9
+
+var (
+	errInfeasible = errors.New("linear program is infeasible")
+	errUnbounded  = errors.New("linear program is unbounded")
+)
+
+// linearProgram constrains the variables x by rows·x >= bounds and x >= 0
+type linearProgram struct {
+	variables int
+	rows      [][]float64
+	bounds    []float64
+}
+
+// addRow appends the constraint coefficients·x >= bound
+func (p *linearProgram) addRow(coefficients []float64, bound float64) {
+	p.rows = append(p.rows, coefficients)
+	p.bounds = append(p.bounds, bound)
+}
+
+// tableau is a dense simplex tableau. Every row holds the coefficients of all columns
+// followed by its right-hand side, and objective holds the reduced costs followed by the
+// negated objective value.
+type tableau struct {
+	rows      [][]float64
+	basis     []int
+	objective []float64
+	// allowed marks the columns that may enter the basis
+	allowed []bool
+}
+
+// solve finds a vertex of the program minimizing costs·x with the two-phase simplex method.
+// Further costs break ties lexicographically: each is minimized among the solutions optimal
+// for the costs before it. Bland's rule picks the pivots, so that the degenerate vertices
+// common to balance constraints cannot make it cycle.
+func (p *linearProgram) solve(costs ...[]float64) ([]float64, error) {
+	variables := p.variables
+	constraints := len(p.rows)
+
+	// Every constraint gets a surplus column. Rows with a positive bound also get an
+	// artificial column to start from, the others are negated so their surplus is basic.
+	artificials := 0
+	for _, bound := range p.bounds {
+		if bound > 0 {
+			artificials++
+		}
+	}
+	columns := variables + constraints + artificials
+
+	t := &tableau{
+		rows:    make([][]float64, constraints),
+		basis:   make([]int, constraints),
+		allowed: make([]bool, columns),
+	}
+	for j := range t.allowed {
+		t.allowed[j] = true
+	}
+	phaseOne := make([]float64, columns)
+	artificial := variables + constraints
+	for i, coefficients := range p.rows {
+		row := make([]float64, columns+1)
+		sign := -1.0
+		if p.bounds[i] > 0 {
+			sign = 1
+		}
+		for j, coefficient := range coefficients {
+			row[j] = sign * coefficient
+		}
+		row[variables+i] = -sign
+		row[columns] = sign * p.bounds[i]
+		if p.bounds[i] > 0 {
+			row[artificial] = 1
+			phaseOne[artificial] = 1
+			t.basis[i] = artificial
+			artificial++
+		} else {
+			t.basis[i] = variables + i
+		}
+		t.rows[i] = row
+	}
+
+	if artificials > 0 {
+		t.price(phaseOne)
+		if err := t.optimize(); err != nil {
+			return nil, err
+		}
+		if -t.objective[columns] > tolerance*math.Max(1, maxBound(p.bounds)) {
+			return nil, errInfeasible
+		}
+		for j := variables + constraints; j < columns; j++ {
+			t.allowed[j] = false
+		}
+		t.dropArtificials(variables + constraints)
+	}
+
+	for _, cost := range costs {
+		columnCosts := make([]float64, columns)
+		copy(columnCosts, cost)
+		t.price(columnCosts)
+		if err := t.optimize(); err != nil {
+			return nil, err
+		}
+		// Raising a column with a positive reduced cost would lose the optimum
+		for j := 0; j < columns; j++ {
+			if t.objective[j] > tolerance {
+				t.allowed[j] = false
+			}
+		}
+	}
+
+	solution := make([]float64, variables)
+	for i, column := range t.basis {
+		if column < variables {
+			solution[column] = math.Max(0, t.rows[i][columns])
+		}
+	}
+	return solution, nil
+}
+
+// price sets the objective row to the reduced costs of the given column costs
+func (t *tableau) price(costs []float64) {
+	t.objective = make([]float64, len(costs)+1)
+	copy(t.objective, costs)
+	for i, row := range t.rows {
+		if cost := costs[t.basis[i]]; cost != 0 {
+			for j, value := range row {
+				t.objective[j] -= cost * value
+			}
+		}
+	}
+}
+
+// optimize pivots until no allowed column has a negative reduced cost
+func (t *tableau) optimize() error {
+	rhs := len(t.objective) - 1
+	for {
+		entering := -1
+		for j := 0; j < rhs; j++ {
+			if t.allowed[j] && t.objective[j] < -tolerance {
+				entering = j
+				break
+			}
+		}
+		if entering < 0 {
+			return nil
+		}
+
+		leaving := -1
+		ratio := math.Inf(1)
+		for i, row := range t.rows {
+			if row[entering] <= tolerance {
+				continue
+			}
+			r := row[rhs] / row[entering]
+			if leaving < 0 || r < ratio-tolerance || (r <= ratio+tolerance && t.basis[i] < t.basis[leaving]) {
+				leaving, ratio = i, r
+			}
+		}
+		if leaving < 0 {
+			return errUnbounded
+		}
+		t.pivot(leaving, entering)
+	}
+}
+
+// pivot makes the column basic in the row
+func (t *tableau) pivot(row int, column int) {
+	pivotRow := t.rows[row]
+	scale := pivotRow[column]
+	for j := range pivotRow {
+		pivotRow[j] /= scale
+	}
+	eliminate := func(target []float64) {
+		factor := target[column]
+		if factor == 0 {
+			return
+		}
+		for j, value := range pivotRow {
+			target[j] -= factor * value
+		}
+	}
+	for i, r := range t.rows {
+		if i != row {
+			eliminate(r)
+		}
+	}
+	eliminate(t.objective)
+	t.basis[row] = column
+}
+
+// dropArtificials replaces the artificial columns still basic at zero after the first
+// phase, removing the rows that turn out to be redundant
+func (t *tableau) dropArtificials(firstArtificial int) {
+	for i := 0; i < len(t.rows); i++ {
+		if t.basis[i] < firstArtificial {
+			continue
+		}
+		replacement := -1
+		for j := 0; j < firstArtificial; j++ {
+			if math.Abs(t.rows[i][j]) > tolerance {
+				replacement = j
+				break
+			}
+		}
+		if replacement >= 0 {
+			t.pivot(i, replacement)
+			continue
+		}
+		t.rows = append(t.rows[:i], t.rows[i+1:]...)
+		t.basis = append(t.basis[:i], t.basis[i+1:]...)
+		i--
+	}
+}
+
+func maxBound(bounds []float64) float64 {
+	largest := 0.0
+	for _, bound := range bounds {
+		largest = math.Max(largest, math.Abs(bound))
+	}
+	return largest
+}
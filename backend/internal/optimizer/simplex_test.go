@@ -0,0 +1,94 @@
+package optimizer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSolve(t *testing.T) {
+	testCases := []struct {
+		name     string
+		program  *linearProgram
+		costs    [][]float64
+		expected []float64
+		err      error
+	}{
+		{
+			name: "Cheapest mix covering two demands",
+			// 2x + y >= 4 and x + 3y >= 6 meet at (1.2, 1.6), where x + y is smallest
+			program: &linearProgram{
+				variables: 2,
+				rows:      [][]float64{{2, 1}, {1, 3}},
+				bounds:    []float64{4, 6},
+			},
+			costs:    [][]float64{{1, 1}},
+			expected: []float64{1.2, 1.6},
+		},
+		{
+			name: "Degenerate balance rows",
+			// y must cover what x consumes, and x covers the demand of 3
+			program: &linearProgram{
+				variables: 2,
+				rows:      [][]float64{{1, 0}, {-1, 1}, {-1, 1}},
+				bounds:    []float64{3, 0, 0},
+			},
+			costs:    [][]float64{{1, 2}},
+			expected: []float64{3, 3},
+		},
+		{
+			name: "Upper bound given as negated row",
+			program: &linearProgram{
+				variables: 2,
+				rows:      [][]float64{{-1, 0}, {0, 1}},
+				bounds:    []float64{-5, 2},
+			},
+			costs:    [][]float64{{-1, 0}},
+			expected: []float64{5, 2},
+		},
+		{
+			name: "Ties broken by the next costs",
+			// x and y serve the demand equally well, but y is cheaper by the second costs
+			program: &linearProgram{
+				variables: 2,
+				rows:      [][]float64{{1, 1}},
+				bounds:    []float64{4},
+			},
+			costs:    [][]float64{{1, 1}, {2, 1}},
+			expected: []float64{0, 4},
+		},
+		{
+			name: "Infeasible",
+			program: &linearProgram{
+				variables: 1,
+				rows:      [][]float64{{1}, {-1}},
+				bounds:    []float64{2, -1},
+			},
+			costs: [][]float64{{1}},
+			err:   errInfeasible,
+		},
+		{
+			name: "Unbounded",
+			program: &linearProgram{
+				variables: 1,
+				rows:      [][]float64{{1}},
+				bounds:    []float64{1},
+			},
+			costs: [][]float64{{-1}},
+			err:   errUnbounded,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			solution, err := tc.program.solve(tc.costs...)
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.InDeltaSlice(t, tc.expected, solution, 1e-9)
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package planner
+
+import (
+	"fmt"
+
+	"github.com/fasim/backend/internal/models"
+)
+
+// secondsPerMinute converts machine counts at a rate of one item per minute into the
+// machine time spent per item
+const secondsPerMinute = 60
+
+// Alternative is the cost of making one unit of an item with one of its producers, with
+// the inputs made as plans make them
+type Alternative struct {
+	Facility *models.Facility
+	// MachineSeconds is the time machines work per unit, summed over the recipe tree
+	MachineSeconds float64
+	// Energy is the energy in megajoules machines draw while working per unit
+	Energy float64
+	// RawTotal is the quantity of all raw resources consumed per unit
+	RawTotal float64
+	// RawResources are the raw resources consumed per unit, ordered by item ID
+	RawResources []*ItemDemand
+	// Byproducts are the unused outputs per unit, ordered by item ID
+	Byproducts []*ItemDemand
+}
+
+// Compare computes the cost per unit of the item for every facility producing it, ordered
+// by facility ID. The inputs of each alternative are made by their pinned producers or
+// else the producers with the lowest facility ID, as in Plan.
+func (b *RecipeBook) Compare(itemID int) ([]*Alternative, error) {
+	producers := b.producers[itemID]
+	if len(producers) == 0 {
+		return nil, fmt.Errorf("item %d: %w", itemID, ErrNoProducer)
+	}
+
+	alternatives := make([]*Alternative, 0, len(producers))
+	for _, facility := range producers {
+		book := b.withPin(itemID, facility)
+		// A plan at one item per minute needs the machines for a single unit each minute
+		plan, err := book.Plan(itemID, 1)
+		if err != nil {
+			return nil, fmt.Errorf("facility %q: %w", facility.Name(), err)
+		}
+
+		alternative := &Alternative{
+			Facility:     facility,
+			RawResources: plan.RawResources,
+			Byproducts:   plan.Byproducts,
+		}
+		for _, demand := range plan.Facilities {
+			alternative.MachineSeconds += demand.Count * secondsPerMinute
+			alternative.Energy += demand.Count * secondsPerMinute * demand.Facility.Power().ActiveDraw()
+		}
+		for _, demand := range plan.RawResources {
+			alternative.RawTotal += demand.PerMinute
+		}
+		alternatives = append(alternatives, alternative)
+	}
+	return alternatives, nil
+}
+
+// IsAlternative reports whether the candidate produces any item the facility produces, so
+// that it can stand in for the facility
+func (b *RecipeBook) IsAlternative(facility *models.Facility, candidate *models.Facility) bool {
+	for itemID, quantity := range facility.NetQuantities() {
+		if quantity <= epsilon {
+			continue
+		}
+		for _, producer := range b.producers[itemID] {
+			if producer.ID() == candidate.ID() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// withPin returns a copy of the recipe book with the item pinned to the facility
+func (b *RecipeBook) withPin(itemID int, facility *models.Facility) *RecipeBook {
+	pins := make(map[int]*models.Facility, len(b.pins)+1)
+	for id, pinned := range b.pins {
+		pins[id] = pinned
+	}
+	pins[itemID] = facility
+	return &RecipeBook{producers: b.producers, pins: pins}
+}
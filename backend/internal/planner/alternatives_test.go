@@ -0,0 +1,81 @@
+package planner
+
+import (
+	"testing"
+
+	"github.com/fasim/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompare(t *testing.T) {
+	// The foundry draws 3 megawatts while working, the other facilities draw nothing
+	foundry := models.NewFacilityFromParams(7, "Foundry", "",
+		[]*models.InputRequirement{models.NewInputRequirement(ore, 3)},
+		[]*models.OutputDefinition{models.NewOutputDefinition(plate, 4)},
+		6000, nil, nil, models.NewPowerProfile(3, 0, 0))
+	book := NewRecipeBook([]*models.Facility{furnace, assembler, smelter, sifter, foundry})
+
+	alternatives, err := book.Compare(plate.ID())
+	require.NoError(t, err)
+
+	expected := []struct {
+		facilityID     int
+		machineSeconds float64
+		energy         float64
+		rawTotal       float64
+		byproducts     map[int]float64
+	}{
+		{furnace.ID(), 2, 0, 1, map[int]float64{}},
+		{smelter.ID(), 1, 0, 1, map[int]float64{slag.ID(): 0.5}},
+		{sifter.ID(), 4, 0, 4, map[int]float64{slag.ID(): 3}},
+		{foundry.ID(), 1.5, 4.5, 0.75, map[int]float64{}},
+	}
+	require.Len(t, alternatives, len(expected))
+	for i, alternative := range alternatives {
+		assert.Equal(t, expected[i].facilityID, alternative.Facility.ID())
+		assert.InDelta(t, expected[i].machineSeconds, alternative.MachineSeconds, 1e-9, "machine seconds of %s", alternative.Facility.Name())
+		assert.InDelta(t, expected[i].energy, alternative.Energy, 1e-9, "energy of %s", alternative.Facility.Name())
+		assert.InDelta(t, expected[i].rawTotal, alternative.RawTotal, 1e-9, "raw resources of %s", alternative.Facility.Name())
+		require.Len(t, alternative.RawResources, 1)
+		assert.Equal(t, ore.ID(), alternative.RawResources[0].Item.ID())
+
+		byproducts := make(map[int]float64)
+		for _, byproduct := range alternative.Byproducts {
+			byproducts[byproduct.Item.ID()] = byproduct.PerMinute
+		}
+		assert.InDeltaMapValues(t, expected[i].byproducts, byproducts, 1e-9)
+	}
+
+	// The gear alternatives make their plates with the pinned smelter
+	require.NoError(t, book.Pin(plate.ID(), smelter.ID()))
+	alternatives, err = book.Compare(gear.ID())
+	require.NoError(t, err)
+	require.Len(t, alternatives, 1)
+	assert.InDelta(t, 0.5+2, alternatives[0].MachineSeconds, 1e-9)
+	assert.InDelta(t, 2, alternatives[0].RawTotal, 1e-9)
+
+	_, err = book.Compare(ore.ID())
+	assert.ErrorIs(t, err, ErrNoProducer)
+}
+
+func TestPin(t *testing.T) {
+	book := NewRecipeBook([]*models.Facility{miner, furnace, assembler, smelter})
+
+	assert.ErrorIs(t, book.Pin(plate.ID(), assembler.ID()), ErrNotProducer)
+	assert.ErrorIs(t, book.Pin(plate.ID(), 99), ErrNotProducer)
+	require.NoError(t, book.Pin(plate.ID(), smelter.ID()))
+
+	plan, err := book.Plan(gear.ID(), 60)
+	require.NoError(t, err)
+	facilityIDs := make([]int, len(plan.Facilities))
+	for i, demand := range plan.Facilities {
+		facilityIDs[i] = demand.Facility.ID()
+	}
+	assert.Equal(t, []int{miner.ID(), assembler.ID(), smelter.ID()}, facilityIDs)
+
+	assert.True(t, book.IsAlternative(furnace, smelter))
+	assert.True(t, book.IsAlternative(smelter, furnace))
+	assert.False(t, book.IsAlternative(furnace, assembler))
+	assert.False(t, book.IsAlternative(furnace, miner))
+}
@@ -0,0 +1,169 @@
+package planner
+
+import (
+	"slices"
+
+	"github.com/fasim/backend/internal/models"
+)
+
+// MaterialNode is an item in the tree of a bill of materials
+type MaterialNode struct {
+	Item *models.Item
+	// Facility makes the item, or is nil for raw resources and cut loops
+	Facility *models.Facility
+	// PerUnit is the quantity needed per unit of the root item
+	PerUnit float64
+	// PerMinute is the quantity needed per minute at the rate of the bill
+	PerMinute float64
+	// Loop marks an item needed by its own production. It is not expanded again, since the
+	// loop supplies it.
+	Loop bool
+	// Inputs are the items the facility consumes, ordered by item ID
+	Inputs []*MaterialNode
+}
+
+// MaterialTotal is the quantity of an item summed over a bill of materials
+type MaterialTotal struct {
+	Item      *models.Item
+	PerUnit   float64
+	PerMinute float64
+}
+
+// BillOfMaterials expands an item into the intermediate items and raw resources it is
+// made of
+type BillOfMaterials struct {
+	Root      *MaterialNode
+	PerMinute float64
+	// RawResources are the raw resources consumed in total, ordered by item ID. Recipe
+	// loops are balanced as in Plan, so they count what the loops do not recycle.
+	RawResources []*MaterialTotal
+}
+
+// BillOfMaterials expands the item into the tree of items needed to make it at the given
+// rate in items per minute, choosing producers as Plan does. Inputs returned by the
+// facilities consuming them are not needed and left out. Within a recipe cycle, every
+// facility consumes what it needs at the rate the balanced loop runs it, and its inputs
+// are listed under the first item of the loop it makes.
+func (b *RecipeBook) BillOfMaterials(itemID int, perMinute float64) (*BillOfMaterials, error) {
+	plan, err := b.Plan(itemID, perMinute)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &expander{book: b, rate: perMinute, loops: make(map[int][]int)}
+	for _, loop := range plan.Loops {
+		component := make([]int, len(loop))
+		for i, item := range loop {
+			component[i] = item.ID()
+		}
+		for _, id := range component {
+			e.loops[id] = component
+		}
+	}
+	root, err := e.expand(plan.Item, 1, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	bom := &BillOfMaterials{
+		Root:         root,
+		PerMinute:    perMinute,
+		RawResources: make([]*MaterialTotal, len(plan.RawResources)),
+	}
+	for i, demand := range plan.RawResources {
+		bom.RawResources[i] = &MaterialTotal{
+			Item:      demand.Item,
+			PerUnit:   demand.PerMinute / perMinute,
+			PerMinute: demand.PerMinute,
+		}
+	}
+	return bom, nil
+}
+
+// expander builds the tree of a bill of materials
+type expander struct {
+	book *RecipeBook
+	rate float64
+	// loops maps the items of every recipe cycle of the plan to the items of the cycle
+	loops map[int][]int
+}
+
+// loopRun is a recipe cycle balanced to make an item of the loop
+type loopRun struct {
+	items []int
+	// cycles holds the cycles per unit of the root each facility of the loop runs
+	cycles map[*models.Facility]float64
+	// listed marks the facilities whose inputs are already in the tree
+	listed map[*models.Facility]bool
+}
+
+// expand builds the node of an item needed at the given quantity per unit of the root,
+// below the items on the path from the root, within the run of the loop the item belongs
+// to, if any
+func (e *expander) expand(item *models.Item, perUnit float64, path []int, run *loopRun) (*MaterialNode, error) {
+	node := &MaterialNode{
+		Item:      item,
+		PerUnit:   perUnit,
+		PerMinute: perUnit * e.rate,
+		Inputs:    make([]*MaterialNode, 0),
+	}
+	if slices.Contains(path, item.ID()) {
+		node.Loop = true
+		return node, nil
+	}
+	facility := e.book.producer(item.ID())
+	if facility == nil {
+		return node, nil
+	}
+	node.Facility = facility
+
+	// Entering a loop balances it for the quantity of the item needed
+	if component, ok := e.loops[item.ID()]; ok && run == nil {
+		facilities := e.book.loopFacilities(component)
+		cycles, err := e.book.solveLoop(component, facilities, map[int]float64{item.ID(): perUnit})
+		if err != nil {
+			return nil, err
+		}
+		run = &loopRun{
+			items:  component,
+			cycles: make(map[*models.Facility]float64, len(facilities)),
+			listed: make(map[*models.Facility]bool, len(facilities)),
+		}
+		for i, facility := range facilities {
+			run.cycles[facility] = cycles[i]
+		}
+	}
+
+	cyclesPerUnit := perUnit / facility.NetQuantities()[item.ID()]
+	if run != nil {
+		if run.listed[facility] {
+			return node, nil
+		}
+		run.listed[facility] = true
+		cyclesPerUnit = run.cycles[facility]
+	}
+
+	path = append(slices.Clone(path), item.ID())
+	net := facility.NetQuantities()
+	seen := make(map[int]bool)
+	for _, input := range facility.InputRequirements() {
+		inputID := input.Item().ID()
+		if seen[inputID] || net[inputID] >= -epsilon || cyclesPerUnit <= epsilon {
+			continue
+		}
+		seen[inputID] = true
+		var inputRun *loopRun
+		if run != nil && slices.Contains(run.items, inputID) {
+			inputRun = run
+		}
+		child, err := e.expand(input.Item(), -cyclesPerUnit*net[inputID], path, inputRun)
+		if err != nil {
+			return nil, err
+		}
+		node.Inputs = append(node.Inputs, child)
+	}
+	slices.SortFunc(node.Inputs, func(x, y *MaterialNode) int {
+		return x.Item.ID() - y.Item.ID()
+	})
+	return node, nil
+}
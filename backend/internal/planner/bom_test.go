@@ -0,0 +1,133 @@
+package planner
+
+import (
+	"testing"
+
+	"github.com/fasim/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBillOfMaterials(t *testing.T) {
+	circuit := models.NewItemFromParams(5, "Circuit", "", models.ItemKindSolid)
+	fabricator := newTestFacility(5, "Fabricator", 1000, []testOutput{{plate, 1}, {gear, 2}}, []testOutput{{circuit, 2}})
+
+	bom, err := NewRecipeBook([]*models.Facility{furnace, assembler, fabricator}).BillOfMaterials(circuit.ID(), 30)
+	require.NoError(t, err)
+	assert.Equal(t, 30.0, bom.PerMinute)
+
+	root := bom.Root
+	assert.Equal(t, circuit, root.Item)
+	assert.Equal(t, fabricator, root.Facility)
+	assert.Equal(t, 1.0, root.PerUnit)
+	assert.Equal(t, 30.0, root.PerMinute)
+	require.Len(t, root.Inputs, 2)
+
+	// Plates are needed both directly and through the gears, each branch expanded on its own
+	direct := root.Inputs[0]
+	assert.Equal(t, plate, direct.Item)
+	assert.InDelta(t, 0.5, direct.PerUnit, 1e-9)
+	assert.InDelta(t, 15, direct.PerMinute, 1e-9)
+	require.Len(t, direct.Inputs, 1)
+	assert.Equal(t, ore, direct.Inputs[0].Item)
+	assert.Nil(t, direct.Inputs[0].Facility)
+	assert.Empty(t, direct.Inputs[0].Inputs)
+
+	gears := root.Inputs[1]
+	assert.Equal(t, gear, gears.Item)
+	assert.Equal(t, assembler, gears.Facility)
+	assert.InDelta(t, 1, gears.PerUnit, 1e-9)
+	require.Len(t, gears.Inputs, 1)
+	assert.InDelta(t, 2, gears.Inputs[0].PerUnit, 1e-9)
+	assert.InDelta(t, 60, gears.Inputs[0].PerMinute, 1e-9)
+
+	require.Len(t, bom.RawResources, 1)
+	assert.Equal(t, ore, bom.RawResources[0].Item)
+	assert.InDelta(t, 2.5, bom.RawResources[0].PerUnit, 1e-9)
+	assert.InDelta(t, 75, bom.RawResources[0].PerMinute, 1e-9)
+}
+
+func TestBillOfMaterialsCutsLoops(t *testing.T) {
+	plant := models.NewItemFromParams(5, "Plant", "", models.ItemKindSolid)
+	seed := models.NewItemFromParams(6, "Seed", "", models.ItemKindSolid)
+	cultivator := newTestFacility(7, "Cultivator", 1000, []testOutput{{seed, 1}, {ore, 1}}, []testOutput{{plant, 2}})
+	seeder := newTestFacility(8, "Seeder", 1000, []testOutput{{plant, 1}}, []testOutput{{seed, 1}})
+
+	bom, err := NewRecipeBook([]*models.Facility{cultivator, seeder}).BillOfMaterials(plant.ID(), 60)
+	require.NoError(t, err)
+
+	// Half of the plants are sown again, so a plant kept takes a whole cultivator cycle,
+	// with its seed and ore
+	require.Len(t, bom.Root.Inputs, 2)
+	assert.Equal(t, ore, bom.Root.Inputs[0].Item)
+	assert.InDelta(t, 1, bom.Root.Inputs[0].PerUnit, 1e-9)
+	seeds := bom.Root.Inputs[1]
+	assert.Equal(t, seed, seeds.Item)
+	assert.InDelta(t, 1, seeds.PerUnit, 1e-9)
+	assert.InDelta(t, 60, seeds.PerMinute, 1e-9)
+	require.Len(t, seeds.Inputs, 1)
+	assert.Equal(t, plant, seeds.Inputs[0].Item)
+	assert.InDelta(t, 1, seeds.Inputs[0].PerUnit, 1e-9)
+	assert.True(t, seeds.Inputs[0].Loop)
+	assert.Empty(t, seeds.Inputs[0].Inputs)
+
+	require.Len(t, bom.RawResources, 1)
+	assert.InDelta(t, 1, bom.RawResources[0].PerUnit, 1e-9)
+	assertLeavesMatchRawResources(t, bom)
+
+	// The catalyst of the enricher is returned, so only its ore is needed
+	bom, err = NewRecipeBook([]*models.Facility{enricher}).BillOfMaterials(gear.ID(), 60)
+	require.NoError(t, err)
+	require.Len(t, bom.Root.Inputs, 1)
+	assert.Equal(t, ore, bom.Root.Inputs[0].Item)
+	assert.InDelta(t, 2, bom.Root.Inputs[0].PerUnit, 1e-9)
+}
+
+func TestBillOfMaterialsLeavesMatchRawResources(t *testing.T) {
+	// Baskets are woven from plants grown in a loop sowing back half of the plants
+	basket := models.NewItemFromParams(5, "Basket", "", models.ItemKindSolid)
+	plant := models.NewItemFromParams(6, "Plant", "", models.ItemKindSolid)
+	seed := models.NewItemFromParams(7, "Seed", "", models.ItemKindSolid)
+	weaver := newTestFacility(7, "Weaver", 1000, []testOutput{{plant, 3}}, []testOutput{{basket, 1}})
+	cultivator := newTestFacility(8, "Cultivator", 1000, []testOutput{{seed, 1}, {ore, 1}}, []testOutput{{plant, 2}})
+	seeder := newTestFacility(9, "Seeder", 1000, []testOutput{{plant, 1}}, []testOutput{{seed, 1}})
+
+	bom, err := NewRecipeBook([]*models.Facility{weaver, cultivator, seeder}).BillOfMaterials(basket.ID(), 20)
+	require.NoError(t, err)
+	require.Len(t, bom.Root.Inputs, 1)
+	plants := bom.Root.Inputs[0]
+	assert.InDelta(t, 3, plants.PerUnit, 1e-9)
+	require.Len(t, plants.Inputs, 2)
+	assert.InDelta(t, 3, plants.Inputs[0].PerUnit, 1e-9)
+	assert.InDelta(t, 3, plants.Inputs[1].PerUnit, 1e-9)
+	require.Len(t, bom.RawResources, 1)
+	assert.InDelta(t, 3, bom.RawResources[0].PerUnit, 1e-9)
+	assertLeavesMatchRawResources(t, bom)
+
+	bom, err = NewRecipeBook([]*models.Facility{furnace, assembler}).BillOfMaterials(gear.ID(), 30)
+	require.NoError(t, err)
+	assertLeavesMatchRawResources(t, bom)
+}
+
+// assertLeavesMatchRawResources checks that the raw resources at the leaves of the tree
+// add up to the totals of the bill
+func assertLeavesMatchRawResources(t *testing.T, bom *BillOfMaterials) {
+	t.Helper()
+	leaves := make(map[int]float64)
+	var collect func(node *MaterialNode)
+	collect = func(node *MaterialNode) {
+		if node.Facility == nil && !node.Loop {
+			leaves[node.Item.ID()] += node.PerUnit
+		}
+		for _, input := range node.Inputs {
+			collect(input)
+		}
+	}
+	collect(bom.Root)
+
+	totals := make(map[int]float64)
+	for _, total := range bom.RawResources {
+		totals[total.Item.ID()] = total.PerUnit
+	}
+	assert.InDeltaMapValues(t, totals, leaves, 1e-9)
+}
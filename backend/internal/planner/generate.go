@@ -0,0 +1,52 @@
+package planner
+
+import (
+	"github.com/fasim/backend/internal/models"
+)
+
+// GeneratePipeline synthesizes a pipeline with a node running the required machines of every
+// facility of the plan, connected to the producers the plan chose, leaving raw resources unconnected
+func (b *RecipeBook) GeneratePipeline(name string, description string, plan *Plan) *models.Pipeline {
+	pipeline := models.NewPipeline(name, description)
+
+	nodes := make(map[int]*models.PipelineNode, len(plan.Facilities))
+	for _, demand := range plan.Facilities {
+		node := models.NewPipelineNode(demand.Facility, demand.RequiredCount, 1)
+		pipeline.AddNode(node)
+		nodes[demand.Facility.ID()] = node
+	}
+
+	for _, demand := range plan.Facilities {
+		consumer := nodes[demand.Facility.ID()]
+		connected := make(map[int]bool)
+		for _, input := range demand.Facility.InputRequirements() {
+			supplier := b.supplier(plan, nodes, input.Item().ID())
+			if supplier == nil || connected[supplier.ID()] {
+				continue
+			}
+			supplier.AddNextNodeID(consumer.ID())
+			connected[supplier.ID()] = true
+		}
+	}
+
+	return pipeline
+}
+
+// supplier returns the node making the item, which runs its producer unless the plan leaves
+// the producer idle and the item comes from another facility of the plan, or nil for raw
+// resources
+func (b *RecipeBook) supplier(plan *Plan, nodes map[int]*models.PipelineNode, itemID int) *models.PipelineNode {
+	producer := b.producer(itemID)
+	if producer == nil {
+		return nil
+	}
+	if node, ok := nodes[producer.ID()]; ok {
+		return node
+	}
+	for _, demand := range plan.Facilities {
+		if demand.Facility.NetQuantities()[itemID] > epsilon {
+			return nodes[demand.Facility.ID()]
+		}
+	}
+	return nil
+}
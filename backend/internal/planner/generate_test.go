@@ -0,0 +1,104 @@
+package planner
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/fasim/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeneratePipeline(t *testing.T) {
+	circuit := models.NewItemFromParams(5, "Circuit", "", models.ItemKindSolid)
+	fabricator := newTestFacility(5, "Fabricator", 1000, []testOutput{{plate, 1}, {gear, 1}}, []testOutput{{circuit, 1}})
+
+	testCases := []struct {
+		name       string
+		facilities []*models.Facility
+		itemID     int
+		// expectedEdges maps each facility ID to the facility IDs its node feeds
+		expectedEdges map[int][]int
+	}{
+		{
+			name:       "sequential chain",
+			facilities: []*models.Facility{miner, furnace, assembler},
+			itemID:     gear.ID(),
+			expectedEdges: map[int][]int{
+				miner.ID():     {furnace.ID()},
+				furnace.ID():   {assembler.ID()},
+				assembler.ID(): {},
+			},
+		},
+		{
+			name:       "shared intermediate feeds several consumers",
+			facilities: []*models.Facility{furnace, assembler, fabricator},
+			itemID:     circuit.ID(),
+			expectedEdges: map[int][]int{
+				furnace.ID():    {assembler.ID(), fabricator.ID()},
+				assembler.ID():  {fabricator.ID()},
+				fabricator.ID(): {},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			book := NewRecipeBook(tc.facilities)
+			plan, err := book.Plan(tc.itemID, 60)
+			require.NoError(t, err)
+
+			pipeline := book.GeneratePipeline("Generated", "Generated pipeline", plan)
+			assert.Equal(t, "Generated", pipeline.Name())
+			assert.Equal(t, "Generated pipeline", pipeline.Description())
+			require.Len(t, pipeline.Nodes(), len(tc.expectedEdges))
+
+			instances := make(map[int]int)
+			for _, demand := range plan.Facilities {
+				instances[demand.Facility.ID()] = demand.RequiredCount
+			}
+
+			for _, node := range pipeline.Nodes() {
+				assert.Equal(t, instances[node.Facility().ID()], node.InstanceCount(), "instances of %s", node.Facility().Name())
+				assert.Equal(t, 1.0, node.ClockSpeed())
+
+				targets := make([]int, 0, len(node.NextNodeIDs()))
+				for _, nextID := range node.NextNodeIDs() {
+					require.Contains(t, pipeline.Nodes(), nextID)
+					targets = append(targets, pipeline.Nodes()[nextID].Facility().ID())
+				}
+				sort.Ints(targets)
+				assert.Equal(t, tc.expectedEdges[node.Facility().ID()], targets, "targets of %s", node.Facility().Name())
+			}
+		})
+	}
+}
+
+func TestGeneratePipelineLeavesOutIdleLoopFacilities(t *testing.T) {
+	// The spinner makes the thread the loop needs as a byproduct of the cloth, so the pinned
+	// twister never runs
+	cloth := models.NewItemFromParams(7, "Cloth", "", models.ItemKindSolid)
+	fiber := models.NewItemFromParams(8, "Fiber", "", models.ItemKindSolid)
+	thread := models.NewItemFromParams(9, "Thread", "", models.ItemKindSolid)
+	spinner := newTestFacility(7, "Spinner", 1000, []testOutput{{fiber, 1}}, []testOutput{{cloth, 1}, {thread, 1}})
+	carder := newTestFacility(8, "Carder", 1000, []testOutput{{thread, 1}}, []testOutput{{fiber, 1}})
+	twister := newTestFacility(9, "Twister", 1000, []testOutput{{cloth, 1}}, []testOutput{{thread, 1}})
+
+	book := NewRecipeBook([]*models.Facility{spinner, carder, twister})
+	require.NoError(t, book.Pin(thread.ID(), twister.ID()))
+	plan, err := book.Plan(cloth.ID(), 60)
+	require.NoError(t, err)
+	require.Len(t, plan.Facilities, 2)
+	for _, demand := range plan.Facilities {
+		assert.NotEqual(t, twister.ID(), demand.Facility.ID())
+		assert.Equal(t, 1, demand.RequiredCount)
+	}
+
+	pipeline := book.GeneratePipeline("Generated", "", plan)
+	require.NoError(t, pipeline.EnsureValid())
+	require.Len(t, pipeline.Nodes(), 2)
+	for _, node := range pipeline.Nodes() {
+		require.Len(t, node.NextNodeIDs(), 1)
+		assert.NotEqual(t, node.Facility(), pipeline.Nodes()[node.NextNodeIDs()[0]].Facility())
+	}
+}
@@ -0,0 +1,321 @@
+package planner
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+
+	"github.com/fasim/backend/internal/loops"
+	"github.com/fasim/backend/internal/models"
+)
+
+// epsilon absorbs floating point error when rounding machine counts
+const epsilon = 1e-9
+
+var (
+	// ErrNoProducer is returned when the target item is not output by any facility
+	ErrNoProducer = errors.New("item is not produced by any facility")
+	// ErrCyclicRecipe is returned when a recipe cycle has no steady state, because the loop
+	// cannot make more of its items than it consumes
+	ErrCyclicRecipe = errors.New("recipe cycle cannot be balanced")
+	// ErrNotProducer is returned when pinning an item to a facility that does not produce it
+	ErrNotProducer = errors.New("facility does not produce the item")
+)
+
+// RecipeBook indexes facilities by the items they output so that production chains
+// can be traced from a product back to its raw resources
+type RecipeBook struct {
+	// producers lists the facilities outputting each item, ordered by facility ID
+	producers map[int][]*models.Facility
+	// pins holds the producer chosen for an item in place of the one with the lowest ID
+	pins map[int]*models.Facility
+}
+
+// NewRecipeBook creates a recipe book from the given facilities. A facility produces the
+// items it outputs more of than it consumes, so that catalysts it returns are not mistaken
+// for products.
+func NewRecipeBook(facilities []*models.Facility) *RecipeBook {
+	book := &RecipeBook{
+		producers: make(map[int][]*models.Facility),
+		pins:      make(map[int]*models.Facility),
+	}
+	for _, facility := range facilities {
+		seen := make(map[int]bool)
+		for _, output := range facility.OutputDefinitions() {
+			itemID := output.Item().ID()
+			if seen[itemID] || facility.NetQuantities()[itemID] <= epsilon {
+				continue
+			}
+			seen[itemID] = true
+			book.producers[itemID] = append(book.producers[itemID], facility)
+		}
+	}
+	for _, producers := range book.producers {
+		sort.Slice(producers, func(i, j int) bool {
+			return producers[i].ID() < producers[j].ID()
+		})
+	}
+	return book
+}
+
+// Producers returns the facilities that output the item
+func (b *RecipeBook) Producers(itemID int) []*models.Facility {
+	return b.producers[itemID]
+}
+
+// Pin makes plans produce the item with the facility of the given ID, which must be one of
+// its producers
+func (b *RecipeBook) Pin(itemID int, facilityID int) error {
+	for _, facility := range b.producers[itemID] {
+		if facility.ID() == facilityID {
+			b.pins[itemID] = facility
+			return nil
+		}
+	}
+	return fmt.Errorf("facility %d, item %d: %w", facilityID, itemID, ErrNotProducer)
+}
+
+// producer returns the facility used to make the item, or nil for raw resources. It is the
+// pinned producer if there is one, or else the producer with the lowest facility ID.
+func (b *RecipeBook) producer(itemID int) *models.Facility {
+	if facility, ok := b.pins[itemID]; ok {
+		return facility
+	}
+	producers := b.producers[itemID]
+	if len(producers) == 0 {
+		return nil
+	}
+	return producers[0]
+}
+
+// FacilityDemand is the number of machines of a facility a plan requires
+type FacilityDemand struct {
+	Facility *models.Facility
+	// Count is the exact number of machines running at full speed
+	Count float64
+	// RequiredCount is Count rounded up to whole machines
+	RequiredCount int
+}
+
+// ItemDemand is a rate of an item in items per minute
+type ItemDemand struct {
+	Item      *models.Item
+	PerMinute float64
+}
+
+// Plan lists the facilities and raw resources needed to produce an item at a given rate
+type Plan struct {
+	Item      *models.Item
+	PerMinute float64
+	// Facilities is ordered by facility ID. Facilities of a recipe cycle that the balanced
+	// loop does not run are left out.
+	Facilities []*FacilityDemand
+	// RawResources are items no facility produces, ordered by item ID
+	RawResources []*ItemDemand
+	// Byproducts are outputs of the planned facilities that the plan does not use, ordered by
+	// item ID. They must be sunk or recycled for the facilities to keep running.
+	Byproducts []*ItemDemand
+	// Loops lists the items of each recipe cycle the plan runs, such as a recycling loop,
+	// ordered by item ID
+	Loops [][]*models.Item
+}
+
+// Plan computes the facilities required to produce the item at the given rate in items
+// per minute. Each item is made by its pinned producer or else the producer with the
+// lowest facility ID, and items without a producer are treated as raw resources. Outputs
+// with a probability count with their expected quantity, and catalysts returned by a
+// facility only count with the part they are not returned. Items whose production
+// eventually requires themselves are solved together, balancing the loop as a linear
+// system.
+func (b *RecipeBook) Plan(itemID int, perMinute float64) (*Plan, error) {
+	target := b.producer(itemID)
+	if target == nil {
+		return nil, fmt.Errorf("item %d: %w", itemID, ErrNoProducer)
+	}
+	if perMinute <= 0 || math.IsNaN(perMinute) || math.IsInf(perMinute, 0) {
+		return nil, fmt.Errorf("production rate must be positive and finite")
+	}
+
+	items := make(map[int]*models.Item)
+	demands := map[int]float64{itemID: perMinute}
+	produced := make(map[int]float64)
+	counts := make(map[*models.Facility]float64)
+	raw := make(map[int]float64)
+	loops := make([][]int, 0)
+
+	run := func(facility *models.Facility, cyclesPerMinute float64) {
+		counts[facility] += facility.Machines(cyclesPerMinute)
+
+		for _, output := range facility.OutputDefinitions() {
+			items[output.Item().ID()] = output.Item()
+		}
+		for _, input := range facility.InputRequirements() {
+			items[input.Item().ID()] = input.Item()
+		}
+		for itemID, quantity := range facility.NetQuantities() {
+			if quantity > 0 {
+				produced[itemID] += cyclesPerMinute * quantity
+			} else {
+				demands[itemID] -= cyclesPerMinute * quantity
+			}
+		}
+	}
+
+	for _, component := range b.components(itemID) {
+		facilities := b.loopFacilities(component)
+		for _, facility := range facilities {
+			if facility.ProcessingTime() <= 0 {
+				return nil, fmt.Errorf("facility %q has a non-positive processing time", facility.Name())
+			}
+		}
+
+		if len(component) == 1 {
+			id := component[0]
+			if len(facilities) == 0 {
+				raw[id] += demands[id]
+				continue
+			}
+			run(facilities[0], demands[id]/facilities[0].NetQuantities()[id])
+			continue
+		}
+
+		cycles, err := b.solveLoop(component, facilities, demands)
+		if err != nil {
+			return nil, err
+		}
+		for i, facility := range facilities {
+			run(facility, cycles[i])
+		}
+		for _, id := range component {
+			if produced[id]-demands[id] < -epsilon {
+				return nil, fmt.Errorf("item %d: %w", id, ErrCyclicRecipe)
+			}
+		}
+		loops = append(loops, component)
+	}
+
+	plan := &Plan{
+		Item:         items[itemID],
+		PerMinute:    perMinute,
+		Facilities:   make([]*FacilityDemand, 0, len(counts)),
+		RawResources: make([]*ItemDemand, 0, len(raw)),
+		Byproducts:   make([]*ItemDemand, 0),
+	}
+	for facility, count := range counts {
+		if count <= epsilon {
+			continue
+		}
+		plan.Facilities = append(plan.Facilities, &FacilityDemand{
+			Facility:      facility,
+			Count:         count,
+			RequiredCount: int(math.Ceil(count - epsilon)),
+		})
+	}
+	sort.Slice(plan.Facilities, func(i, j int) bool {
+		return plan.Facilities[i].Facility.ID() < plan.Facilities[j].Facility.ID()
+	})
+
+	for id, perMinute := range raw {
+		plan.RawResources = append(plan.RawResources, &ItemDemand{Item: items[id], PerMinute: perMinute})
+	}
+	sort.Slice(plan.RawResources, func(i, j int) bool {
+		return plan.RawResources[i].Item.ID() < plan.RawResources[j].Item.ID()
+	})
+
+	for id, perMinute := range produced {
+		if surplus := perMinute - demands[id]; surplus > epsilon {
+			plan.Byproducts = append(plan.Byproducts, &ItemDemand{Item: items[id], PerMinute: surplus})
+		}
+	}
+	sort.Slice(plan.Byproducts, func(i, j int) bool {
+		return plan.Byproducts[i].Item.ID() < plan.Byproducts[j].Item.ID()
+	})
+
+	plan.Loops = make([][]*models.Item, len(loops))
+	for i, loop := range loops {
+		plan.Loops[i] = make([]*models.Item, len(loop))
+		for j, id := range loop {
+			plan.Loops[i][j] = items[id]
+		}
+	}
+
+	return plan, nil
+}
+
+// components returns the strongly connected components of the items involved in producing
+// the target, where an item is connected to the inputs needed to make it. The components
+// are ordered so that every one comes before those making its inputs, and their items are
+// ordered by item ID. A component of several items is a recipe cycle.
+func (b *RecipeBook) components(itemID int) [][]int {
+	components := loops.Components([]int{itemID}, func(id int) []int {
+		facility := b.producer(id)
+		if facility == nil {
+			return nil
+		}
+		inputs := make([]int, 0, len(facility.InputRequirements()))
+		for _, input := range facility.InputRequirements() {
+			if facility.NetQuantities()[input.Item().ID()] < -epsilon {
+				inputs = append(inputs, input.Item().ID())
+			}
+		}
+		return inputs
+	})
+	for _, component := range components {
+		sort.Ints(component)
+	}
+	return components
+}
+
+// loopFacilities returns the producers of the items of a component, each once, in the
+// order of the items they produce
+func (b *RecipeBook) loopFacilities(component []int) []*models.Facility {
+	facilities := make([]*models.Facility, 0, len(component))
+	for _, id := range component {
+		if facility := b.producer(id); facility != nil && !slices.Contains(facilities, facility) {
+			facilities = append(facilities, facility)
+		}
+	}
+	return facilities
+}
+
+// solveLoop returns the cycles per minute of the facilities producing the items of a
+// recipe cycle, so that the loop yields what the rest of the plan demands of each item.
+// Each facility balances the first item of the loop it produces, and the other items of
+// the loop it produces are left to be covered or turn into byproducts.
+func (b *RecipeBook) solveLoop(component []int, facilities []*models.Facility, demands map[int]float64) ([]float64, error) {
+	balanced := make([]int, 0, len(facilities))
+	for _, facility := range facilities {
+		for _, id := range component {
+			if b.producer(id) == facility {
+				balanced = append(balanced, id)
+				break
+			}
+		}
+	}
+
+	// Row i states that the loop nets demands[i] of its item, outside demand only, since
+	// the facilities of the loop have not run yet
+	n := len(facilities)
+	system := make([][]float64, n)
+	for i, id := range balanced {
+		system[i] = make([]float64, n+1)
+		for j, facility := range facilities {
+			system[i][j] = facility.NetQuantities()[id]
+		}
+		system[i][n] = demands[id]
+	}
+
+	cycles, err := loops.Solve(system)
+	if err != nil {
+		return nil, fmt.Errorf("items %v: %w", component, ErrCyclicRecipe)
+	}
+	for i := range cycles {
+		if cycles[i] < -epsilon {
+			return nil, fmt.Errorf("items %v: %w", component, ErrCyclicRecipe)
+		}
+		cycles[i] = math.Max(0, cycles[i])
+	}
+	return cycles, nil
+}
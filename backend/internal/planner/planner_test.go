@@ -0,0 +1,252 @@
+package planner
+
+import (
+	"math"
+	"testing"
+
+	"github.com/fasim/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	ore   = models.NewItemFromParams(1, "Ore", "", models.ItemKindSolid)
+	plate = models.NewItemFromParams(2, "Plate", "", models.ItemKindSolid)
+	gear  = models.NewItemFromParams(3, "Gear", "", models.ItemKindSolid)
+	slag  = models.NewItemFromParams(4, "Slag", "", models.ItemKindSolid)
+)
+
+type testOutput struct {
+	item     *models.Item
+	quantity float64
+}
+
+func newTestFacility(id int, name string, processingTime int64, inputs []testOutput, outputs []testOutput) *models.Facility {
+	inputReqs := make([]*models.InputRequirement, len(inputs))
+	for i, input := range inputs {
+		inputReqs[i] = models.NewInputRequirement(input.item, input.quantity)
+	}
+	outputDefs := make([]*models.OutputDefinition, len(outputs))
+	for i, output := range outputs {
+		outputDefs[i] = models.NewOutputDefinition(output.item, output.quantity)
+	}
+	return models.NewFacilityFromParams(id, name, "", inputReqs, outputDefs, processingTime, nil, nil, models.PowerProfile{})
+}
+
+var (
+	miner     = newTestFacility(1, "Miner", 1000, nil, []testOutput{{ore, 1}})
+	furnace   = newTestFacility(2, "Furnace", 2000, []testOutput{{ore, 1}}, []testOutput{{plate, 1}})
+	assembler = newTestFacility(3, "Assembler", 500, []testOutput{{plate, 2}}, []testOutput{{gear, 1}})
+	smelter   = newTestFacility(4, "Smelter", 2000, []testOutput{{ore, 2}}, []testOutput{{plate, 2}, {slag, 1}})
+)
+
+// sifter yields a plate from a quarter of its cycles and slag from the rest
+var sifter = models.NewFacilityFromParams(5, "Sifter", "",
+	[]*models.InputRequirement{models.NewInputRequirement(ore, 1)},
+	[]*models.OutputDefinition{
+		models.NewChanceOutputDefinition(plate, 1, 0.25),
+		models.NewChanceOutputDefinition(slag, 1, 0.75),
+	},
+	1000, nil, nil, models.PowerProfile{})
+
+// enricher needs slag as a catalyst that it returns at the end of every cycle
+var enricher = newTestFacility(6, "Enricher", 1000, []testOutput{{ore, 2}, {slag, 1}}, []testOutput{{gear, 1}, {slag, 1}})
+
+func TestPlan(t *testing.T) {
+	type expectedFacility struct {
+		count    float64
+		required int
+	}
+
+	testCases := []struct {
+		name               string
+		facilities         []*models.Facility
+		itemID             int
+		perMinute          float64
+		expectedFacilities map[int]expectedFacility
+		expectedRaw        map[int]float64
+		expectedByproducts map[int]float64
+	}{
+		{
+			name:       "raw resources without a producer",
+			facilities: []*models.Facility{furnace, assembler},
+			itemID:     gear.ID(),
+			perMinute:  30,
+			expectedFacilities: map[int]expectedFacility{
+				furnace.ID():   {count: 2, required: 2},
+				assembler.ID(): {count: 0.25, required: 1},
+			},
+			expectedRaw:        map[int]float64{ore.ID(): 60},
+			expectedByproducts: map[int]float64{},
+		},
+		{
+			name:       "full chain down to extraction",
+			facilities: []*models.Facility{miner, furnace, assembler},
+			itemID:     gear.ID(),
+			perMinute:  45,
+			expectedFacilities: map[int]expectedFacility{
+				miner.ID():     {count: 1.5, required: 2},
+				furnace.ID():   {count: 3, required: 3},
+				assembler.ID(): {count: 0.375, required: 1},
+			},
+			expectedRaw:        map[int]float64{},
+			expectedByproducts: map[int]float64{},
+		},
+		{
+			name:       "unused outputs are reported as byproducts",
+			facilities: []*models.Facility{smelter},
+			itemID:     plate.ID(),
+			perMinute:  60,
+			expectedFacilities: map[int]expectedFacility{
+				smelter.ID(): {count: 1, required: 1},
+			},
+			expectedRaw:        map[int]float64{ore.ID(): 60},
+			expectedByproducts: map[int]float64{slag.ID(): 30},
+		},
+		{
+			name:       "chance outputs count with their expected quantity",
+			facilities: []*models.Facility{sifter},
+			itemID:     plate.ID(),
+			perMinute:  15,
+			expectedFacilities: map[int]expectedFacility{
+				sifter.ID(): {count: 1, required: 1},
+			},
+			expectedRaw:        map[int]float64{ore.ID(): 60},
+			expectedByproducts: map[int]float64{slag.ID(): 45},
+		},
+		{
+			name:       "returned catalysts are neither consumed nor produced",
+			facilities: []*models.Facility{enricher},
+			itemID:     gear.ID(),
+			perMinute:  60,
+			expectedFacilities: map[int]expectedFacility{
+				enricher.ID(): {count: 1, required: 1},
+			},
+			expectedRaw:        map[int]float64{ore.ID(): 120},
+			expectedByproducts: map[int]float64{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			plan, err := NewRecipeBook(tc.facilities).Plan(tc.itemID, tc.perMinute)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.itemID, plan.Item.ID())
+			assert.Equal(t, tc.perMinute, plan.PerMinute)
+
+			require.Len(t, plan.Facilities, len(tc.expectedFacilities))
+			for _, demand := range plan.Facilities {
+				expected := tc.expectedFacilities[demand.Facility.ID()]
+				assert.InDelta(t, expected.count, demand.Count, 1e-9, "count of %s", demand.Facility.Name())
+				assert.Equal(t, expected.required, demand.RequiredCount, "required count of %s", demand.Facility.Name())
+			}
+
+			require.Len(t, plan.RawResources, len(tc.expectedRaw))
+			for _, demand := range plan.RawResources {
+				assert.InDelta(t, tc.expectedRaw[demand.Item.ID()], demand.PerMinute, 1e-9, "demand of %s", demand.Item.Name())
+			}
+
+			require.Len(t, plan.Byproducts, len(tc.expectedByproducts))
+			for _, byproduct := range plan.Byproducts {
+				assert.InDelta(t, tc.expectedByproducts[byproduct.Item.ID()], byproduct.PerMinute, 1e-9, "surplus of %s", byproduct.Item.Name())
+			}
+		})
+	}
+}
+
+func TestPlanBalancesRecipeLoops(t *testing.T) {
+	plant := models.NewItemFromParams(5, "Plant", "", models.ItemKindSolid)
+	seed := models.NewItemFromParams(6, "Seed", "", models.ItemKindSolid)
+	// The cultivator grows two plants from a seed, and the seeder turns a plant back into one
+	cultivator := newTestFacility(7, "Cultivator", 1000, []testOutput{{seed, 1}, {ore, 1}}, []testOutput{{plant, 2}, {slag, 1}})
+	seeder := newTestFacility(8, "Seeder", 2000, []testOutput{{plant, 1}}, []testOutput{{seed, 1}})
+	// The press makes gears from plants, so that the loop sits inside a longer chain
+	press := newTestFacility(9, "Press", 1000, []testOutput{{plant, 2}}, []testOutput{{gear, 1}})
+
+	plan, err := NewRecipeBook([]*models.Facility{cultivator, seeder, press}).Plan(gear.ID(), 30)
+	require.NoError(t, err)
+
+	// Every plant kept takes one cultivator cycle, whose other plant is sown again
+	counts := make(map[int]float64)
+	for _, demand := range plan.Facilities {
+		counts[demand.Facility.ID()] = demand.Count
+	}
+	assert.InDeltaMapValues(t, map[int]float64{cultivator.ID(): 1, seeder.ID(): 2, press.ID(): 0.5}, counts, 1e-9)
+
+	require.Len(t, plan.RawResources, 1)
+	assert.Equal(t, ore.ID(), plan.RawResources[0].Item.ID())
+	assert.InDelta(t, 60, plan.RawResources[0].PerMinute, 1e-9)
+	require.Len(t, plan.Byproducts, 1)
+	assert.Equal(t, slag.ID(), plan.Byproducts[0].Item.ID())
+	assert.InDelta(t, 60, plan.Byproducts[0].PerMinute, 1e-9)
+
+	require.Len(t, plan.Loops, 1)
+	assert.Equal(t, []*models.Item{plant, seed}, plan.Loops[0])
+}
+
+func TestPlanRejectsInvalidInput(t *testing.T) {
+	testCases := []struct {
+		name       string
+		facilities []*models.Facility
+		itemID     int
+		perMinute  float64
+		expected   error
+	}{
+		{
+			name:       "item without producer",
+			facilities: []*models.Facility{furnace},
+			itemID:     gear.ID(),
+			perMinute:  1,
+			expected:   ErrNoProducer,
+		},
+		{
+			name: "cyclic recipes",
+			facilities: []*models.Facility{
+				newTestFacility(1, "Forward", 1000, []testOutput{{ore, 1}}, []testOutput{{plate, 1}}),
+				newTestFacility(2, "Backward", 1000, []testOutput{{plate, 1}}, []testOutput{{ore, 1}}),
+			},
+			itemID:    plate.ID(),
+			perMinute: 1,
+			expected:  ErrCyclicRecipe,
+		},
+		{
+			name: "cyclic recipes losing items",
+			facilities: []*models.Facility{
+				newTestFacility(1, "Forward", 1000, []testOutput{{ore, 1}}, []testOutput{{plate, 1}}),
+				newTestFacility(2, "Backward", 1000, []testOutput{{plate, 2}}, []testOutput{{ore, 1}}),
+			},
+			itemID:    plate.ID(),
+			perMinute: 1,
+			expected:  ErrCyclicRecipe,
+		},
+		{
+			name:       "non-positive rate",
+			facilities: []*models.Facility{furnace},
+			itemID:     plate.ID(),
+			perMinute:  0,
+		},
+		{
+			name:       "infinite rate",
+			facilities: []*models.Facility{furnace},
+			itemID:     plate.ID(),
+			perMinute:  math.Inf(1),
+		},
+		{
+			name:       "rate that is not a number",
+			facilities: []*models.Facility{furnace},
+			itemID:     plate.ID(),
+			perMinute:  math.NaN(),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewRecipeBook(tc.facilities).Plan(tc.itemID, tc.perMinute)
+			require.Error(t, err)
+			if tc.expected != nil {
+				assert.ErrorIs(t, err, tc.expected)
+			}
+		})
+	}
+}
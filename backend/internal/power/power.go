@@ -0,0 +1,79 @@
+package power
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/fasim/backend/internal/models"
+	"github.com/fasim/backend/internal/throughput"
+)
+
+// NodePower describes the power a pipeline node exchanges with the grid, in megawatts
+type NodePower struct {
+	NodeID     int
+	FacilityID int
+	// Demand is the average draw at the steady-state utilization of the node; PeakDemand
+	// is the draw while every machine of the node is in a cycle
+	Demand     float64
+	PeakDemand float64
+	// Generation is the average power the node feeds into the grid
+	Generation float64
+}
+
+// Result holds the power balance of a pipeline, in megawatts
+type Result struct {
+	// Nodes is keyed by pipeline node ID
+	Nodes      map[int]*NodePower
+	Demand     float64
+	PeakDemand float64
+	Generation float64
+	// Satisfaction is the fraction of the demand covered by the generation, at most 1
+	Satisfaction float64
+}
+
+// Calculate derives the power demand and generation of every node from its steady-state
+// utilization. Machines draw their active power while in a cycle and their idle power
+// otherwise, and generators feed power into the grid while in a cycle.
+//
+// A pipeline without generators is assumed to draw from an external grid, so its demand is
+// always satisfied.
+func Calculate(pipeline *models.Pipeline, rates *throughput.Result) (*Result, error) {
+	result := &Result{Nodes: make(map[int]*NodePower, len(pipeline.Nodes()))}
+	hasGenerators := false
+	for id, node := range pipeline.Nodes() {
+		rate, ok := rates.Nodes[id]
+		if !ok {
+			return nil, fmt.Errorf("no rate calculated for node %d", id)
+		}
+
+		profile := node.Power()
+		instances := float64(node.InstanceCount())
+		nodePower := &NodePower{
+			NodeID:     id,
+			FacilityID: rate.FacilityID,
+			Demand:     instances * (profile.IdleDraw() + (profile.ActiveDraw()-profile.IdleDraw())*rate.Utilization),
+			PeakDemand: instances * profile.ActiveDraw(),
+			Generation: instances * profile.Generation() * rate.Utilization,
+		}
+		hasGenerators = hasGenerators || profile.IsGenerator()
+
+		result.Nodes[id] = nodePower
+		result.Demand += nodePower.Demand
+		result.PeakDemand += nodePower.PeakDemand
+		result.Generation += nodePower.Generation
+	}
+
+	result.Satisfaction = 1
+	if hasGenerators {
+		result.Satisfaction = Satisfaction(result.Generation, result.Demand)
+	}
+	return result, nil
+}
+
+// Satisfaction returns the fraction of the demand the generation covers, capped at 1
+func Satisfaction(generation float64, demand float64) float64 {
+	if demand <= 0 {
+		return 1
+	}
+	return math.Min(1, generation/demand)
+}
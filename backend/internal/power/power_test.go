@@ -0,0 +1,94 @@
+package power
+
+import (
+	"testing"
+
+	"github.com/fasim/backend/internal/models"
+	"github.com/fasim/backend/internal/throughput"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	ore   = models.NewItemFromParams(1, "Ore", "", models.ItemKindSolid)
+	plate = models.NewItemFromParams(2, "Plate", "", models.ItemKindSolid)
+)
+
+// newTestPipeline connects a miner running at half the speed of its furnace and adds a
+// generator node with the given number of machines. The miner gets node ID 1, the furnace
+// ID 2 and the generator ID 3.
+func newTestPipeline(generators int) *models.Pipeline {
+	miner := models.NewFacility("Miner", "", 4000)
+	miner.AddOutputDefinition(models.NewOutputDefinition(ore, 1))
+	miner.SetPower(models.NewPowerProfile(2, 0.5, 0))
+
+	furnace := models.NewFacility("Furnace", "", 2000)
+	furnace.AddInputRequirement(models.NewInputRequirement(ore, 1))
+	furnace.AddOutputDefinition(models.NewOutputDefinition(plate, 1))
+	furnace.SetPower(models.NewPowerProfile(4, 1, 0))
+
+	pipeline := models.NewPipeline("Test Pipeline", "")
+	minerNode := models.NewPipelineNode(miner, 1, 1)
+	pipeline.AddNode(minerNode)
+	furnaceNode := models.NewPipelineNode(furnace, 1, 1)
+	pipeline.AddNode(furnaceNode)
+	minerNode.AddNextNodeID(furnaceNode.ID())
+
+	if generators > 0 {
+		generator := models.NewFacility("Generator", "", 1000)
+		generator.SetPower(models.NewPowerProfile(0, 0, 3))
+		pipeline.AddNode(models.NewPipelineNode(generator, generators, 1))
+	}
+	return pipeline
+}
+
+func TestCalculate(t *testing.T) {
+	testCases := []struct {
+		name         string
+		pipeline     *models.Pipeline
+		demand       map[int]float64
+		generation   float64
+		satisfaction float64
+	}{
+		{
+			name:         "pipeline without generators draws from an external grid",
+			pipeline:     newTestPipeline(0),
+			demand:       map[int]float64{1: 2, 2: 2.5},
+			generation:   0,
+			satisfaction: 1,
+		},
+		{
+			name:         "under-supplied grid",
+			pipeline:     newTestPipeline(1),
+			demand:       map[int]float64{1: 2, 2: 2.5, 3: 0},
+			generation:   3,
+			satisfaction: 3 / 4.5,
+		},
+		{
+			name:         "generation exceeding the demand",
+			pipeline:     newTestPipeline(2),
+			demand:       map[int]float64{1: 2, 2: 2.5, 3: 0},
+			generation:   6,
+			satisfaction: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rates, err := throughput.Calculate(tc.pipeline)
+			require.NoError(t, err)
+
+			result, err := Calculate(tc.pipeline, rates)
+			require.NoError(t, err)
+
+			require.Len(t, result.Nodes, len(tc.demand))
+			for nodeID, demand := range tc.demand {
+				assert.InDelta(t, demand, result.Nodes[nodeID].Demand, 1e-9, "demand of node %d", nodeID)
+			}
+			assert.InDelta(t, 4.5, result.Demand, 1e-9)
+			assert.InDelta(t, 6, result.PeakDemand, 1e-9)
+			assert.InDelta(t, tc.generation, result.Generation, 1e-9)
+			assert.InDelta(t, tc.satisfaction, result.Satisfaction, 1e-9)
+		})
+	}
+}
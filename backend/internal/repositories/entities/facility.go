@@ -11,7 +11,7 @@ type InputRequirementEntity struct {
 	ID         int `gorm:"primaryKey;autoIncrement"`
 	FacilityID int `gorm:"index:idx_facility_item"`
 	ItemID     int `gorm:"index:idx_facility_item"`
-	Quantity   int
+	Quantity   float64
 	Item       ItemEntity `gorm:"foreignKey:ItemID"`
 	Facility   *FacilityEntity `gorm:"foreignKey:FacilityID"`
 }
@@ -26,7 +26,8 @@ type OutputDefinitionEntity struct {
 	ID         int `gorm:"primaryKey;autoIncrement"`
 	FacilityID int `gorm:"index:idx_facility_item_out"`
 	ItemID     int `gorm:"index:idx_facility_item_out"`
-	Quantity   int
+	Quantity   float64
+	Probability float64 `gorm:"not null;default:1"`
 	Item       ItemEntity `gorm:"foreignKey:ItemID"`
 	Facility   *FacilityEntity `gorm:"foreignKey:FacilityID"`
 }
@@ -35,7 +36,9 @@ func (OutputDefinitionEntity) TableName() string {
 	return "output_definitions"
 }
 
-// FacilityEntity represents a production facility and its input/output relationships
+// FacilityEntity represents a production facility and its input/output relationships.
+// Facilities bound to a recipe reference it instead of storing their own inputs and outputs;
+// the references are nullable so that facilities defining their own recipe stay valid.
 type FacilityEntity struct {
 	gorm.Model
 	ID               int `gorm:"primaryKey;autoIncrement"`
@@ -44,6 +47,13 @@ type FacilityEntity struct {
 	ProcessingTime   int64
 	InputRequirements []InputRequirementEntity `gorm:"foreignKey:FacilityID"`
 	OutputDefinitions []OutputDefinitionEntity `gorm:"foreignKey:FacilityID"`
+	MachineTypeID    *int `gorm:"index"`
+	MachineType      *MachineTypeEntity `gorm:"foreignKey:MachineTypeID"`
+	RecipeID         *int `gorm:"index"`
+	Recipe           *RecipeEntity `gorm:"foreignKey:RecipeID"`
+	ActivePower      float64
+	IdlePower        float64
+	PowerGeneration  float64
 }
 
 func (FacilityEntity) TableName() string {
@@ -60,7 +70,16 @@ func (e *FacilityEntity) ToModel() *models.Facility {
 	// Convert output definitions
 	outputDefs := make([]*models.OutputDefinition, len(e.OutputDefinitions))
 	for i, output := range e.OutputDefinitions {
-		outputDefs[i] = models.NewOutputDefinition(output.Item.ToModel(), output.Quantity)
+		outputDefs[i] = models.NewChanceOutputDefinition(output.Item.ToModel(), output.Quantity, output.Probability)
+	}
+
+	var machineType *models.MachineType
+	if e.MachineType != nil {
+		machineType = e.MachineType.ToModel()
+	}
+	var recipe *models.Recipe
+	if e.Recipe != nil {
+		recipe = e.Recipe.ToModel()
 	}
 
 	return models.NewFacilityFromParams(
@@ -70,16 +89,36 @@ func (e *FacilityEntity) ToModel() *models.Facility {
 		inputReqs,
 		outputDefs,
 		e.ProcessingTime,
+		machineType,
+		recipe,
+		models.NewPowerProfile(e.ActivePower, e.IdlePower, e.PowerGeneration),
 	)
 }
 
 // FromModel creates an entity from a domain model
 func FacilityEntityFromModel(m *models.Facility) *FacilityEntity {
 	facility := &FacilityEntity{
-		ID:             m.ID(),
-		Name:           m.Name(),
-		Description:    m.Description(),
-		ProcessingTime: m.ProcessingTime(),
+		ID:              m.ID(),
+		Name:            m.Name(),
+		Description:     m.Description(),
+		ProcessingTime:  m.ProcessingTime(),
+		ActivePower:     m.Power().ActiveDraw(),
+		IdlePower:       m.Power().IdleDraw(),
+		PowerGeneration: m.Power().Generation(),
+	}
+
+	if m.MachineType() != nil {
+		machineTypeID := m.MachineType().ID()
+		facility.MachineTypeID = &machineTypeID
+	}
+	if m.Recipe() != nil {
+		recipeID := m.Recipe().ID()
+		facility.RecipeID = &recipeID
+		// The recipe defines inputs, outputs and processing time
+		facility.ProcessingTime = 0
+		facility.InputRequirements = make([]InputRequirementEntity, 0)
+		facility.OutputDefinitions = make([]OutputDefinitionEntity, 0)
+		return facility
 	}
 
 	// Convert input requirements
@@ -96,9 +135,10 @@ func FacilityEntityFromModel(m *models.Facility) *FacilityEntity {
 	facility.OutputDefinitions = make([]OutputDefinitionEntity, len(m.OutputDefinitions()))
 	for i, output := range m.OutputDefinitions() {
 		facility.OutputDefinitions[i] = OutputDefinitionEntity{
-			FacilityID: m.ID(),
-			ItemID:     output.Item().ID(),
-			Quantity:   output.Quantity(),
+			FacilityID:  m.ID(),
+			ItemID:      output.Item().ID(),
+			Quantity:    output.Quantity(),
+			Probability: output.Probability(),
 		}
 	}
 
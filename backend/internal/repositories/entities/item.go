@@ -10,6 +10,7 @@ type ItemEntity struct {
 	gorm.Model
 	Name        string `gorm:"not null;uniqueIndex"`
 	Description string
+	Kind        string `gorm:"not null;default:solid"`
 }
 
 func (ItemEntity) TableName() string {
@@ -21,6 +22,7 @@ func (e *ItemEntity) ToModel() *models.Item {
 		int(e.ID),
 		e.Name,
 		e.Description,
+		models.ItemKind(e.Kind),
 	)
 }
 
@@ -32,5 +34,6 @@ func ItemEntityFromModel(m *models.Item) *ItemEntity {
 		},
 		Name:        m.Name(),
 		Description: m.Description(),
+		Kind:        string(m.Kind()),
 	}
 }
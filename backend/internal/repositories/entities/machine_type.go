@@ -0,0 +1,38 @@
+package entities
+
+import (
+	"github.com/fasim/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// MachineTypeEntity represents a kind of machine and its crafting speed
+type MachineTypeEntity struct {
+	gorm.Model
+	ID            int    `gorm:"primaryKey;autoIncrement"`
+	Name          string `gorm:"not null;uniqueIndex"`
+	Description   string
+	CraftingSpeed float64 `gorm:"not null;default:1"`
+}
+
+func (MachineTypeEntity) TableName() string {
+	return "machine_types"
+}
+
+func (e *MachineTypeEntity) ToModel() *models.MachineType {
+	return models.NewMachineTypeFromParams(
+		e.ID,
+		e.Name,
+		e.Description,
+		e.CraftingSpeed,
+	)
+}
+
+// FromModel creates an entity from a domain model
+func MachineTypeEntityFromModel(m *models.MachineType) *MachineTypeEntity {
+	return &MachineTypeEntity{
+		ID:            m.ID(),
+		Name:          m.Name(),
+		Description:   m.Description(),
+		CraftingSpeed: m.CraftingSpeed(),
+	}
+}
@@ -7,8 +7,19 @@ func GetModels() []interface{} {
 		&FacilityEntity{},
 		&InputRequirementEntity{},
 		&OutputDefinitionEntity{},
+		&MachineTypeEntity{},
+		&RecipeEntity{},
+		&RecipeInputEntity{},
+		&RecipeOutputEntity{},
+		&TransportEntity{},
 		&PipelineEntity{},
 		&PipelineNodeEntity{},
 		&PipelineNodeConnectionEntity{},
+		&SimulationRunEntity{},
+		&SimulationNodeResultEntity{},
+		&SimulationItemCountEntity{},
+		&SimulationSampleEntity{},
+		&SimulationSampleItemEntity{},
+		&SimulationLinkResultEntity{},
 	}
 }
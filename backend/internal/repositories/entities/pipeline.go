@@ -5,13 +5,22 @@ import (
 	"gorm.io/gorm"
 )
 
-// PipelineNodeEntity represents a facility node within a production pipeline
+// PipelineNodeEntity represents a node within a production pipeline: a facility, or a
+// source or sink of a single item
 type PipelineNodeEntity struct {
 	gorm.Model
 	ID           int `gorm:"primaryKey;autoIncrement"`
 	PipelineID   int `gorm:"index:idx_pipeline_facility"`
-	FacilityID   int `gorm:"index:idx_pipeline_facility"`
-	Facility     FacilityEntity `gorm:"foreignKey:FacilityID"`
+	FacilityID   *int `gorm:"index:idx_pipeline_facility"`
+	Kind          string `gorm:"not null;default:facility"`
+	ItemID        *int `gorm:"index"`
+	PerMinute     float64
+	InstanceCount int `gorm:"not null;default:1"`
+	ClockSpeed    float64 `gorm:"not null;default:1"`
+	InputBuffer   int `gorm:"not null;default:0"`
+	OutputBuffer  int `gorm:"not null;default:0"`
+	Facility     *FacilityEntity `gorm:"foreignKey:FacilityID"`
+	Item         *ItemEntity `gorm:"foreignKey:ItemID"`
 	Pipeline     *PipelineEntity `gorm:"foreignKey:PipelineID"`
 	NextNodes    []PipelineNodeConnectionEntity `gorm:"foreignKey:SourceNodeID"`
 }
@@ -20,14 +29,22 @@ func (PipelineNodeEntity) TableName() string {
 	return "pipeline_nodes"
 }
 
-// PipelineNodeConnectionEntity represents a connection between two pipeline nodes
+// PipelineNodeConnectionEntity represents a connection between two pipeline nodes,
+// optionally restricted to a single item and carried by a transport tier
 type PipelineNodeConnectionEntity struct {
 	gorm.Model
-	ID           int `gorm:"primaryKey;autoIncrement"`
-	SourceNodeID int `gorm:"index"`
-	TargetNodeID int `gorm:"index"`
-	SourceNode   PipelineNodeEntity `gorm:"foreignKey:SourceNodeID"`
-	TargetNode   PipelineNodeEntity `gorm:"foreignKey:TargetNodeID"`
+	ID            int `gorm:"primaryKey;autoIncrement"`
+	SourceNodeID  int `gorm:"index"`
+	TargetNodeID  int `gorm:"index"`
+	ItemID        *int
+	Ratio         float64
+	Priority      int
+	MaxThroughput float64
+	TransportID   *int `gorm:"index"`
+	SourceNode    PipelineNodeEntity `gorm:"foreignKey:SourceNodeID"`
+	TargetNode    PipelineNodeEntity `gorm:"foreignKey:TargetNodeID"`
+	Item          *ItemEntity `gorm:"foreignKey:ItemID"`
+	Transport     *TransportEntity `gorm:"foreignKey:TransportID"`
 }
 
 func (PipelineNodeConnectionEntity) TableName() string {
@@ -42,18 +59,95 @@ func (e *PipelineNodeEntity) GetNextNodeIDs() []int {
 	return ids
 }
 
+func (e *PipelineNodeConnectionEntity) ToModel() *models.PipelineConnection {
+	var item *models.Item
+	if e.Item != nil {
+		item = e.Item.ToModel()
+	}
+	var transport *models.Transport
+	if e.Transport != nil {
+		transport = e.Transport.ToModel()
+	}
+	return models.NewPipelineConnection(
+		e.TargetNodeID,
+		item,
+		e.Ratio,
+		e.Priority,
+		e.MaxThroughput,
+		transport,
+	)
+}
+
+// PipelineNodeConnectionEntityFromModel creates a connection entity between persisted nodes
+func PipelineNodeConnectionEntityFromModel(m *models.PipelineConnection, sourceNodeID int, targetNodeID int) *PipelineNodeConnectionEntity {
+	conn := &PipelineNodeConnectionEntity{
+		SourceNodeID:  sourceNodeID,
+		TargetNodeID:  targetNodeID,
+		Ratio:         m.Ratio(),
+		Priority:      m.Priority(),
+		MaxThroughput: m.MaxThroughput(),
+	}
+	if m.Item() != nil {
+		itemID := m.Item().ID()
+		conn.ItemID = &itemID
+	}
+	if m.Transport() != nil {
+		transportID := m.Transport().ID()
+		conn.TransportID = &transportID
+	}
+	return conn
+}
+
 func (e *PipelineNodeEntity) ToModel() *models.PipelineNode {
-	nextNodeIDs := make([]int, len(e.NextNodes))
+	connections := make([]*models.PipelineConnection, len(e.NextNodes))
 	for i, conn := range e.NextNodes {
-		nextNodeIDs[i] = conn.TargetNodeID
+		connections[i] = conn.ToModel()
+	}
+	buffers := models.NewBufferCapacity(e.InputBuffer, e.OutputBuffer)
+	if kind := models.NodeKind(e.Kind); kind == models.NodeKindSource || kind == models.NodeKindSink {
+		var item *models.Item
+		if e.Item != nil {
+			item = e.Item.ToModel()
+		}
+		return models.NewBoundaryNodeFromParams(e.ID, kind, item, e.PerMinute, buffers, connections)
+	}
+	var facility *models.Facility
+	if e.Facility != nil {
+		facility = e.Facility.ToModel()
 	}
 	return models.NewPipelineNodeFromParams(
 		e.ID,
-		e.Facility.ToModel(),
-		nextNodeIDs,
+		facility,
+		e.InstanceCount,
+		e.ClockSpeed,
+		buffers,
+		connections,
 	)
 }
 
+// PipelineNodeEntityFromModel creates a node entity without connections from a domain model
+func PipelineNodeEntityFromModel(m *models.PipelineNode, pipelineID int) *PipelineNodeEntity {
+	node := &PipelineNodeEntity{
+		PipelineID:    pipelineID,
+		Kind:          string(m.Kind()),
+		PerMinute:     m.PerMinute(),
+		InstanceCount: m.InstanceCount(),
+		ClockSpeed:    m.ClockSpeed(),
+		InputBuffer:   m.Buffers().Input(),
+		OutputBuffer:  m.Buffers().Output(),
+		NextNodes:     make([]PipelineNodeConnectionEntity, 0),
+	}
+	if m.Facility() != nil {
+		facilityID := m.Facility().ID()
+		node.FacilityID = &facilityID
+	}
+	if m.Item() != nil {
+		itemID := m.Item().ID()
+		node.ItemID = &itemID
+	}
+	return node
+}
+
 // PipelineEntity represents a complete production line configuration,
 // consisting of interconnected facility nodes
 type PipelineEntity struct {
@@ -95,11 +189,7 @@ func PipelineEntityFromModel(m *models.Pipeline) *PipelineEntity {
 	// Create nodes first
 	nodeMap := make(map[int]*PipelineNodeEntity) // Map from model node ID to entity node
 	for _, node := range m.Nodes() {
-		pipelineNode := &PipelineNodeEntity{
-			PipelineID: m.ID(),
-			FacilityID: node.Facility().ID(),
-			NextNodes:  make([]PipelineNodeConnectionEntity, 0),
-		}
+		pipelineNode := PipelineNodeEntityFromModel(node, m.ID())
 		pipeline.Nodes = append(pipeline.Nodes, *pipelineNode)
 		nodeMap[node.ID()] = pipelineNode
 	}
@@ -107,13 +197,10 @@ func PipelineEntityFromModel(m *models.Pipeline) *PipelineEntity {
 	// Create connections using the node map
 	for _, node := range m.Nodes() {
 		sourceNode := nodeMap[node.ID()]
-		for _, targetID := range node.NextNodeIDs() {
-			targetNode := nodeMap[targetID]
-			connection := PipelineNodeConnectionEntity{
-				SourceNodeID: sourceNode.ID,
-				TargetNodeID: targetNode.ID,
-			}
-			sourceNode.NextNodes = append(sourceNode.NextNodes, connection)
+		for _, conn := range node.Connections() {
+			targetNode := nodeMap[conn.TargetNodeID()]
+			connection := PipelineNodeConnectionEntityFromModel(conn, sourceNode.ID, targetNode.ID)
+			sourceNode.NextNodes = append(sourceNode.NextNodes, *connection)
 		}
 	}
 
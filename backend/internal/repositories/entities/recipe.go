@@ -0,0 +1,104 @@
+package entities
+
+import (
+	"github.com/fasim/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// RecipeInputEntity maps the relationship between recipes and their required input items
+type RecipeInputEntity struct {
+	gorm.Model
+	ID       int `gorm:"primaryKey;autoIncrement"`
+	RecipeID int `gorm:"index:idx_recipe_item"`
+	ItemID   int `gorm:"index:idx_recipe_item"`
+	Quantity float64
+	Item     ItemEntity    `gorm:"foreignKey:ItemID"`
+	Recipe   *RecipeEntity `gorm:"foreignKey:RecipeID"`
+}
+
+func (RecipeInputEntity) TableName() string {
+	return "recipe_inputs"
+}
+
+// RecipeOutputEntity maps the relationship between recipes and their produced output items
+type RecipeOutputEntity struct {
+	gorm.Model
+	ID          int `gorm:"primaryKey;autoIncrement"`
+	RecipeID    int `gorm:"index:idx_recipe_item_out"`
+	ItemID      int `gorm:"index:idx_recipe_item_out"`
+	Quantity    float64
+	Probability float64       `gorm:"not null;default:1"`
+	Item        ItemEntity    `gorm:"foreignKey:ItemID"`
+	Recipe      *RecipeEntity `gorm:"foreignKey:RecipeID"`
+}
+
+func (RecipeOutputEntity) TableName() string {
+	return "recipe_outputs"
+}
+
+// RecipeEntity represents a recipe and its input/output relationships
+type RecipeEntity struct {
+	gorm.Model
+	ID                int    `gorm:"primaryKey;autoIncrement"`
+	Name              string `gorm:"not null;uniqueIndex"`
+	Description       string
+	CraftingTime      int64
+	InputRequirements []RecipeInputEntity  `gorm:"foreignKey:RecipeID"`
+	OutputDefinitions []RecipeOutputEntity `gorm:"foreignKey:RecipeID"`
+}
+
+func (RecipeEntity) TableName() string {
+	return "recipes"
+}
+
+func (e *RecipeEntity) ToModel() *models.Recipe {
+	inputReqs := make([]*models.InputRequirement, len(e.InputRequirements))
+	for i, input := range e.InputRequirements {
+		inputReqs[i] = models.NewInputRequirement(input.Item.ToModel(), input.Quantity)
+	}
+
+	outputDefs := make([]*models.OutputDefinition, len(e.OutputDefinitions))
+	for i, output := range e.OutputDefinitions {
+		outputDefs[i] = models.NewChanceOutputDefinition(output.Item.ToModel(), output.Quantity, output.Probability)
+	}
+
+	return models.NewRecipeFromParams(
+		e.ID,
+		e.Name,
+		e.Description,
+		inputReqs,
+		outputDefs,
+		e.CraftingTime,
+	)
+}
+
+// FromModel creates an entity from a domain model
+func RecipeEntityFromModel(m *models.Recipe) *RecipeEntity {
+	recipe := &RecipeEntity{
+		ID:           m.ID(),
+		Name:         m.Name(),
+		Description:  m.Description(),
+		CraftingTime: m.CraftingTime(),
+	}
+
+	recipe.InputRequirements = make([]RecipeInputEntity, len(m.InputRequirements()))
+	for i, input := range m.InputRequirements() {
+		recipe.InputRequirements[i] = RecipeInputEntity{
+			RecipeID: m.ID(),
+			ItemID:   input.Item().ID(),
+			Quantity: input.Quantity(),
+		}
+	}
+
+	recipe.OutputDefinitions = make([]RecipeOutputEntity, len(m.OutputDefinitions()))
+	for i, output := range m.OutputDefinitions() {
+		recipe.OutputDefinitions[i] = RecipeOutputEntity{
+			RecipeID:    m.ID(),
+			ItemID:      output.Item().ID(),
+			Quantity:    output.Quantity(),
+			Probability: output.Probability(),
+		}
+	}
+
+	return recipe
+}
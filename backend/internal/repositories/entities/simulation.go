@@ -0,0 +1,321 @@
+package entities
+
+import (
+	"sort"
+	"time"
+
+	"github.com/fasim/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// SimulationRunEntity represents a simulation run of a pipeline and the settings it was
+// started with. Durations are stored in nanoseconds.
+type SimulationRunEntity struct {
+	gorm.Model
+	ID              int    `gorm:"primaryKey;autoIncrement"`
+	PipelineID      int    `gorm:"index"`
+	Status          string `gorm:"not null;default:pending"`
+	ErrorMessage    string
+	Duration        int64
+	TimeStep        int64
+	Seed            int64
+	BufferCapacity  int
+	FinishedAt      *time.Time
+	PowerDemand     float64
+	PowerGeneration float64
+	Nodes           []SimulationNodeResultEntity `gorm:"foreignKey:RunID"`
+	Links           []SimulationLinkResultEntity `gorm:"foreignKey:RunID"`
+}
+
+func (SimulationRunEntity) TableName() string {
+	return "simulation_runs"
+}
+
+// SimulationNodeResultEntity holds the statistics a run collected for a pipeline node
+type SimulationNodeResultEntity struct {
+	gorm.Model
+	ID          int `gorm:"primaryKey;autoIncrement"`
+	RunID       int `gorm:"index"`
+	NodeID      int
+	FacilityID  int
+	Cycles      int
+	BusyTime    int64
+	IdleTime    int64
+	StarvedTime int64
+	BlockedTime int64
+	Items       []SimulationItemCountEntity `gorm:"foreignKey:NodeResultID"`
+	Samples     []SimulationSampleEntity    `gorm:"foreignKey:NodeResultID"`
+}
+
+func (SimulationNodeResultEntity) TableName() string {
+	return "simulation_node_results"
+}
+
+// SimulationItemCountEntity holds the units of an item a node produced and consumed during a run
+type SimulationItemCountEntity struct {
+	gorm.Model
+	ID           int `gorm:"primaryKey;autoIncrement"`
+	NodeResultID int `gorm:"index"`
+	ItemID       int
+	Produced     float64
+	Consumed     float64
+}
+
+func (SimulationItemCountEntity) TableName() string {
+	return "simulation_item_counts"
+}
+
+// SimulationSampleEntity holds the buffers of a node at a point in simulated time
+type SimulationSampleEntity struct {
+	gorm.Model
+	ID           int `gorm:"primaryKey;autoIncrement"`
+	NodeResultID int `gorm:"index"`
+	Time         int64
+	Items        []SimulationSampleItemEntity `gorm:"foreignKey:SampleID"`
+}
+
+func (SimulationSampleEntity) TableName() string {
+	return "simulation_samples"
+}
+
+// SimulationSampleItemEntity holds the units of an item in the buffers of a sample and the
+// units produced and consumed up to it
+type SimulationSampleItemEntity struct {
+	gorm.Model
+	ID           int `gorm:"primaryKey;autoIncrement"`
+	SampleID     int `gorm:"index"`
+	ItemID       int
+	InputBuffer  float64
+	OutputBuffer float64
+	Produced     float64
+	Consumed     float64
+}
+
+func (SimulationSampleItemEntity) TableName() string {
+	return "simulation_sample_items"
+}
+
+// SimulationLinkResultEntity holds the statistics a run collected for a connection carried by a transport
+type SimulationLinkResultEntity struct {
+	gorm.Model
+	ID                int `gorm:"primaryKey;autoIncrement"`
+	RunID             int `gorm:"index"`
+	SourceNodeID      int
+	TargetNodeID      int
+	TransportID       int
+	Moved             float64
+	CapacityPerMinute float64
+	Saturated         bool
+}
+
+func (SimulationLinkResultEntity) TableName() string {
+	return "simulation_link_results"
+}
+
+func (e *SimulationRunEntity) ToModel() *models.SimulationRun {
+	settings := models.NewSimulationSettings(
+		time.Duration(e.Duration),
+		time.Duration(e.TimeStep),
+		uint64(e.Seed),
+		e.BufferCapacity,
+	)
+
+	var finishedAt time.Time
+	if e.FinishedAt != nil {
+		finishedAt = *e.FinishedAt
+	}
+
+	var result *models.SimulationResult
+	if models.SimulationStatus(e.Status) == models.SimulationStatusCompleted {
+		nodes := make([]*models.SimulationNodeStats, len(e.Nodes))
+		for i, node := range e.Nodes {
+			nodes[i] = node.ToModel()
+		}
+		sort.Slice(nodes, func(i, j int) bool {
+			return nodes[i].NodeID() < nodes[j].NodeID()
+		})
+		links := make([]*models.SimulationLinkStats, len(e.Links))
+		for i, link := range e.Links {
+			links[i] = link.ToModel()
+		}
+		sort.SliceStable(links, func(i, j int) bool {
+			if links[i].SourceNodeID() != links[j].SourceNodeID() {
+				return links[i].SourceNodeID() < links[j].SourceNodeID()
+			}
+			return links[i].TargetNodeID() < links[j].TargetNodeID()
+		})
+		result = models.NewSimulationResult(e.PowerDemand, e.PowerGeneration, nodes, links)
+	}
+
+	return models.NewSimulationRunFromParams(
+		e.ID,
+		e.PipelineID,
+		settings,
+		models.SimulationStatus(e.Status),
+		e.ErrorMessage,
+		e.CreatedAt,
+		finishedAt,
+		result,
+	)
+}
+
+func (e *SimulationNodeResultEntity) ToModel() *models.SimulationNodeStats {
+	produced := make(map[int]float64)
+	consumed := make(map[int]float64)
+	for _, item := range e.Items {
+		if item.Produced != 0 {
+			produced[item.ItemID] = item.Produced
+		}
+		if item.Consumed != 0 {
+			consumed[item.ItemID] = item.Consumed
+		}
+	}
+
+	samples := make([]*models.SimulationSample, len(e.Samples))
+	for i, sample := range e.Samples {
+		samples[i] = sample.ToModel()
+	}
+	sort.Slice(samples, func(i, j int) bool {
+		return samples[i].Time() < samples[j].Time()
+	})
+
+	return models.NewSimulationNodeStats(
+		e.NodeID,
+		e.FacilityID,
+		e.Cycles,
+		produced,
+		consumed,
+		time.Duration(e.BusyTime),
+		time.Duration(e.IdleTime),
+		time.Duration(e.StarvedTime),
+		time.Duration(e.BlockedTime),
+		samples,
+	)
+}
+
+func (e *SimulationSampleEntity) ToModel() *models.SimulationSample {
+	inputBuffer := make(map[int]float64)
+	outputBuffer := make(map[int]float64)
+	produced := make(map[int]float64)
+	consumed := make(map[int]float64)
+	for _, item := range e.Items {
+		if item.InputBuffer != 0 {
+			inputBuffer[item.ItemID] = item.InputBuffer
+		}
+		if item.OutputBuffer != 0 {
+			outputBuffer[item.ItemID] = item.OutputBuffer
+		}
+		if item.Produced != 0 {
+			produced[item.ItemID] = item.Produced
+		}
+		if item.Consumed != 0 {
+			consumed[item.ItemID] = item.Consumed
+		}
+	}
+	return models.NewSimulationSample(time.Duration(e.Time), inputBuffer, outputBuffer, produced, consumed)
+}
+
+func (e *SimulationLinkResultEntity) ToModel() *models.SimulationLinkStats {
+	return models.NewSimulationLinkStats(
+		e.SourceNodeID,
+		e.TargetNodeID,
+		e.TransportID,
+		e.Moved,
+		e.CapacityPerMinute,
+		e.Saturated,
+	)
+}
+
+// SimulationRunEntityFromModel creates an entity from a domain model, including the
+// statistics of completed runs
+func SimulationRunEntityFromModel(m *models.SimulationRun) *SimulationRunEntity {
+	settings := m.Settings()
+	run := &SimulationRunEntity{
+		ID:             m.ID(),
+		PipelineID:     m.PipelineID(),
+		Status:         string(m.Status()),
+		ErrorMessage:   m.ErrorMessage(),
+		Duration:       int64(settings.Duration()),
+		TimeStep:       int64(settings.TimeStep()),
+		Seed:           int64(settings.Seed()),
+		BufferCapacity: settings.BufferCapacity(),
+	}
+	if !m.FinishedAt().IsZero() {
+		finishedAt := m.FinishedAt()
+		run.FinishedAt = &finishedAt
+	}
+
+	result := m.Result()
+	if result == nil {
+		return run
+	}
+	run.PowerDemand = result.PowerDemand()
+	run.PowerGeneration = result.PowerGeneration()
+	for _, node := range result.Nodes() {
+		run.Nodes = append(run.Nodes, *simulationNodeResultEntityFromModel(node, m.ID()))
+	}
+	for _, link := range result.Links() {
+		run.Links = append(run.Links, SimulationLinkResultEntity{
+			RunID:             m.ID(),
+			SourceNodeID:      link.SourceNodeID(),
+			TargetNodeID:      link.TargetNodeID(),
+			TransportID:       link.TransportID(),
+			Moved:             link.Moved(),
+			CapacityPerMinute: link.CapacityPerMinute(),
+			Saturated:         link.Saturated(),
+		})
+	}
+	return run
+}
+
+func simulationNodeResultEntityFromModel(m *models.SimulationNodeStats, runID int) *SimulationNodeResultEntity {
+	node := &SimulationNodeResultEntity{
+		RunID:       runID,
+		NodeID:      m.NodeID(),
+		FacilityID:  m.FacilityID(),
+		Cycles:      m.Cycles(),
+		BusyTime:    int64(m.BusyTime()),
+		IdleTime:    int64(m.IdleTime()),
+		StarvedTime: int64(m.StarvedTime()),
+		BlockedTime: int64(m.BlockedTime()),
+	}
+
+	for _, itemID := range itemIDs(m.Produced(), m.Consumed()) {
+		node.Items = append(node.Items, SimulationItemCountEntity{
+			ItemID:   itemID,
+			Produced: m.Produced()[itemID],
+			Consumed: m.Consumed()[itemID],
+		})
+	}
+
+	for _, sample := range m.Samples() {
+		sampleEntity := SimulationSampleEntity{Time: int64(sample.Time())}
+		for _, itemID := range itemIDs(sample.InputBuffer(), sample.OutputBuffer(), sample.Produced(), sample.Consumed()) {
+			sampleEntity.Items = append(sampleEntity.Items, SimulationSampleItemEntity{
+				ItemID:       itemID,
+				InputBuffer:  sample.InputBuffer()[itemID],
+				OutputBuffer: sample.OutputBuffer()[itemID],
+				Produced:     sample.Produced()[itemID],
+				Consumed:     sample.Consumed()[itemID],
+			})
+		}
+		node.Samples = append(node.Samples, sampleEntity)
+	}
+	return node
+}
+
+// itemIDs returns the item IDs keying any of the amounts, in ascending order
+func itemIDs(amounts ...map[int]float64) []int {
+	seen := make(map[int]bool)
+	ids := make([]int, 0)
+	for _, byItem := range amounts {
+		for itemID := range byItem {
+			if !seen[itemID] {
+				seen[itemID] = true
+				ids = append(ids, itemID)
+			}
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
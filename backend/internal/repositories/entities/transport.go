@@ -0,0 +1,41 @@
+package entities
+
+import (
+	"github.com/fasim/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// TransportEntity represents a belt or pipe tier and its capacity
+type TransportEntity struct {
+	gorm.Model
+	ID             int    `gorm:"primaryKey;autoIncrement"`
+	Name           string `gorm:"not null;uniqueIndex"`
+	Description    string
+	Kind           string `gorm:"not null;default:solid"`
+	ItemsPerSecond float64
+}
+
+func (TransportEntity) TableName() string {
+	return "transports"
+}
+
+func (e *TransportEntity) ToModel() *models.Transport {
+	return models.NewTransportFromParams(
+		e.ID,
+		e.Name,
+		e.Description,
+		models.ItemKind(e.Kind),
+		e.ItemsPerSecond,
+	)
+}
+
+// FromModel creates an entity from a domain model
+func TransportEntityFromModel(m *models.Transport) *TransportEntity {
+	return &TransportEntity{
+		ID:             m.ID(),
+		Name:           m.Name(),
+		Description:    m.Description(),
+		Kind:           string(m.Kind()),
+		ItemsPerSecond: m.ItemsPerSecond(),
+	}
+}
@@ -2,10 +2,15 @@ package repositories
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/fasim/backend/internal/models"
 )
 
+// ErrInUse is returned when deleting a record that other records still reference
+var ErrInUse = errors.New("record is still in use")
+
 // ItemRepository provides CRUD operations for items in the storage layer
 type ItemRepository interface {
 	Create(ctx context.Context, item *models.Item) error
@@ -15,7 +20,8 @@ type ItemRepository interface {
 	Delete(ctx context.Context, id int) error
 }
 
-// FacilityRepository provides CRUD operations for facilities in the storage layer
+// FacilityRepository provides CRUD operations for facilities in the storage layer.
+// Delete returns an error wrapping ErrInUse while pipeline nodes use the facility.
 type FacilityRepository interface {
 	Create(ctx context.Context, facility *models.Facility) error
 	Get(ctx context.Context, id int) (*models.Facility, error)
@@ -24,7 +30,38 @@ type FacilityRepository interface {
 	Delete(ctx context.Context, id int) error
 }
 
-// PipelineRepository provides CRUD operations for production pipelines in the storage layer
+// MachineTypeRepository provides CRUD operations for machine types in the storage layer.
+// Delete returns an error wrapping ErrInUse while facilities use the machine type.
+type MachineTypeRepository interface {
+	Create(ctx context.Context, machineType *models.MachineType) error
+	Get(ctx context.Context, id int) (*models.MachineType, error)
+	List(ctx context.Context) ([]*models.MachineType, error)
+	Update(ctx context.Context, machineType *models.MachineType) error
+	Delete(ctx context.Context, id int) error
+}
+
+// RecipeRepository provides CRUD operations for recipes in the storage layer.
+// Delete returns an error wrapping ErrInUse while facilities use the recipe.
+type RecipeRepository interface {
+	Create(ctx context.Context, recipe *models.Recipe) error
+	Get(ctx context.Context, id int) (*models.Recipe, error)
+	List(ctx context.Context) ([]*models.Recipe, error)
+	Update(ctx context.Context, recipe *models.Recipe) error
+	Delete(ctx context.Context, id int) error
+}
+
+// TransportRepository provides CRUD operations for transport tiers in the storage layer.
+// Delete returns an error wrapping ErrInUse while pipeline connections use the transport.
+type TransportRepository interface {
+	Create(ctx context.Context, transport *models.Transport) error
+	Get(ctx context.Context, id int) (*models.Transport, error)
+	List(ctx context.Context) ([]*models.Transport, error)
+	Update(ctx context.Context, transport *models.Transport) error
+	Delete(ctx context.Context, id int) error
+}
+
+// PipelineRepository provides CRUD operations for production pipelines in the storage layer.
+// Create and Update reject pipelines with validation errors by returning a *models.ValidationError.
 type PipelineRepository interface {
 	Create(ctx context.Context, pipeline *models.Pipeline) error
 	Get(ctx context.Context, id int) (*models.Pipeline, error)
@@ -33,9 +70,26 @@ type PipelineRepository interface {
 	Delete(ctx context.Context, id int) error
 }
 
+// SimulationRunRepository provides CRUD operations for simulation runs and their outcome
+// in the storage layer. Update replaces the stored statistics with those of the run.
+// ListByPipeline leaves out the buffer samples of the runs, which only Get loads, and
+// FailUnfinished fails every pending or running run at once.
+type SimulationRunRepository interface {
+	Create(ctx context.Context, run *models.SimulationRun) error
+	Get(ctx context.Context, id int) (*models.SimulationRun, error)
+	ListByPipeline(ctx context.Context, pipelineID int) ([]*models.SimulationRun, error)
+	Update(ctx context.Context, run *models.SimulationRun) error
+	FailUnfinished(ctx context.Context, message string, at time.Time) error
+	Delete(ctx context.Context, id int) error
+}
+
 // Repositories provides access to all storage operations through a unified interface
 type Repositories struct {
-	Items      ItemRepository
-	Facilities FacilityRepository
-	Pipelines  PipelineRepository
+	Items        ItemRepository
+	Facilities   FacilityRepository
+	MachineTypes MachineTypeRepository
+	Recipes      RecipeRepository
+	Transports   TransportRepository
+	Pipelines    PipelineRepository
+	Simulations  SimulationRunRepository
 }
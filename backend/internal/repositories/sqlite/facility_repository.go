@@ -2,6 +2,7 @@ package sqlite
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/fasim/backend/internal/models"
 	"github.com/fasim/backend/internal/repositories"
@@ -20,23 +21,35 @@ func NewFacilityRepository(db *db.DB) repositories.FacilityRepository {
 	return &FacilityRepository{db: db}
 }
 
+// preloadFacility loads all relationships needed to build a facility model. The prefix
+// is the association path leading to the facility, ending with a dot, or empty.
+func preloadFacility(tx *gorm.DB, prefix string) *gorm.DB {
+	return tx.
+		Preload(prefix + "InputRequirements.Item").
+		Preload(prefix + "OutputDefinitions.Item").
+		Preload(prefix + "MachineType").
+		Preload(prefix + "Recipe.InputRequirements.Item").
+		Preload(prefix + "Recipe.OutputDefinitions.Item")
+}
+
 // Create stores a new facility
 func (r *FacilityRepository) Create(ctx context.Context, facility *models.Facility) error {
 	entity := entities.FacilityEntityFromModel(facility)
 	if err := r.db.WithContext(ctx).Create(entity).Error; err != nil {
 		return err
 	}
-	newFacility := entity.ToModel()
-	*facility = *newFacility
+	created, err := r.Get(ctx, entity.ID)
+	if err != nil {
+		return err
+	}
+	*facility = *created
 	return nil
 }
 
 // Get retrieves a facility by ID
 func (r *FacilityRepository) Get(ctx context.Context, id int) (*models.Facility, error) {
 	var entity entities.FacilityEntity
-	if err := r.db.WithContext(ctx).
-		Preload("InputRequirements.Item").
-		Preload("OutputDefinitions.Item").
+	if err := preloadFacility(r.db.WithContext(ctx), "").
 		First(&entity, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, nil
@@ -49,9 +62,7 @@ func (r *FacilityRepository) Get(ctx context.Context, id int) (*models.Facility,
 // List retrieves all facilities
 func (r *FacilityRepository) List(ctx context.Context) ([]*models.Facility, error) {
 	var entities []entities.FacilityEntity
-	if err := r.db.WithContext(ctx).
-		Preload("InputRequirements.Item").
-		Preload("OutputDefinitions.Item").
+	if err := preloadFacility(r.db.WithContext(ctx), "").
 		Find(&entities).Error; err != nil {
 		return nil, err
 	}
@@ -90,26 +101,35 @@ func (r *FacilityRepository) Update(ctx context.Context, facility *models.Facili
 		if err := tx.Model(&entities.FacilityEntity{}).
 			Where("id = ?", facility.ID()).
 			Updates(map[string]interface{}{
-				"name":            entity.Name,
-				"description":     entity.Description,
-				"processing_time": entity.ProcessingTime,
+				"name":             entity.Name,
+				"description":      entity.Description,
+				"processing_time":  entity.ProcessingTime,
+				"machine_type_id":  entity.MachineTypeID,
+				"recipe_id":        entity.RecipeID,
+				"active_power":     entity.ActivePower,
+				"idle_power":       entity.IdlePower,
+				"power_generation": entity.PowerGeneration,
 			}).Error; err != nil {
 			return err
 		}
 
 		// Create new relationships
-		if err := tx.Create(&entity.InputRequirements).Error; err != nil {
-			return err
+		if len(entity.InputRequirements) > 0 {
+			if err := tx.Create(&entity.InputRequirements).Error; err != nil {
+				return err
+			}
 		}
-		if err := tx.Create(&entity.OutputDefinitions).Error; err != nil {
-			return err
+		if len(entity.OutputDefinitions) > 0 {
+			if err := tx.Create(&entity.OutputDefinitions).Error; err != nil {
+				return err
+			}
 		}
 
 		return nil
 	})
 }
 
-// Delete removes a facility by ID
+// Delete removes a facility by ID. Facilities used by pipeline nodes cannot be deleted.
 func (r *FacilityRepository) Delete(ctx context.Context, id int) error {
 	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		// Check if facility exists
@@ -121,6 +141,14 @@ func (r *FacilityRepository) Delete(ctx context.Context, id int) error {
 			return gorm.ErrRecordNotFound
 		}
 
+		var users int64
+		if err := tx.Model(&entities.PipelineNodeEntity{}).Where("facility_id = ?", id).Count(&users).Error; err != nil {
+			return err
+		}
+		if users > 0 {
+			return fmt.Errorf("facility %d is used by %d pipeline nodes: %w", id, users, repositories.ErrInUse)
+		}
+
 		// Delete relationships first
 		if err := tx.Where("facility_id = ?", id).Delete(&entities.InputRequirementEntity{}).Error; err != nil {
 			return err
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/fasim/backend/internal/models"
+	"github.com/fasim/backend/internal/repositories"
 	"github.com/fasim/backend/internal/repositories/entities"
 	"github.com/stretchr/testify/suite"
 	"gorm.io/gorm"
@@ -11,8 +12,9 @@ import (
 
 type FacilityRepositoryTestSuite struct {
 	BaseSQLiteTestSuite
-	repo     *FacilityRepository
-	itemRepo *ItemRepository
+	repo         *FacilityRepository
+	itemRepo     *ItemRepository
+	pipelineRepo *PipelineRepository
 }
 
 func TestFacilityRepositorySuite(t *testing.T) {
@@ -22,12 +24,21 @@ func TestFacilityRepositorySuite(t *testing.T) {
 func (s *FacilityRepositoryTestSuite) SetupSuite() {
 	s.SetupDockerAndDB(
 		&entities.ItemEntity{},
+		&entities.MachineTypeEntity{},
+		&entities.RecipeEntity{},
+		&entities.RecipeInputEntity{},
+		&entities.RecipeOutputEntity{},
 		&entities.FacilityEntity{},
 		&entities.InputRequirementEntity{},
 		&entities.OutputDefinitionEntity{},
+		&entities.TransportEntity{},
+		&entities.PipelineEntity{},
+		&entities.PipelineNodeEntity{},
+		&entities.PipelineNodeConnectionEntity{},
 	)
 	s.repo = &FacilityRepository{db: s.db}
 	s.itemRepo = &ItemRepository{db: s.db}
+	s.pipelineRepo = &PipelineRepository{db: s.db}
 }
 
 func (s *FacilityRepositoryTestSuite) TearDownSuite() {
@@ -35,6 +46,9 @@ func (s *FacilityRepositoryTestSuite) TearDownSuite() {
 }
 
 func (s *FacilityRepositoryTestSuite) SetupTest() {
+	s.NoError(s.db.Exec("DELETE FROM pipeline_node_connections").Error)
+	s.NoError(s.db.Exec("DELETE FROM pipeline_nodes").Error)
+	s.NoError(s.db.Exec("DELETE FROM pipelines").Error)
 	s.NoError(s.db.Exec("DELETE FROM facilities").Error)
 	s.NoError(s.db.Exec("DELETE FROM input_requirements").Error)
 	s.NoError(s.db.Exec("DELETE FROM output_definitions").Error)
@@ -43,7 +57,7 @@ func (s *FacilityRepositoryTestSuite) SetupTest() {
 
 // createTestItem creates and persists a test item
 func (s *FacilityRepositoryTestSuite) createTestItem(name string) *models.Item {
-	item := models.NewItemFromParams(0, name, "Test Description for "+name)
+	item := models.NewItemFromParams(0, name, "Test Description for "+name, models.ItemKindSolid)
 	err := s.itemRepo.Create(s.T().Context(), item)
 	s.NoError(err)
 	return item
@@ -55,13 +69,13 @@ func (s *FacilityRepositoryTestSuite) createTestFacility(name string, inputItems
 
 	// Add input requirements
 	for i, item := range inputItems {
-		req := models.NewInputRequirement(item, i+1)
+		req := models.NewInputRequirement(item, float64(i+1))
 		facility.AddInputRequirement(req)
 	}
 
 	// Add output definitions
 	for i, item := range outputItems {
-		def := models.NewOutputDefinition(item, i+2)
+		def := models.NewOutputDefinition(item, float64(i+2))
 		facility.AddOutputDefinition(def)
 	}
 
@@ -86,7 +100,7 @@ func (s *FacilityRepositoryTestSuite) TestCreate() {
 			},
 			input: func(inputItem, outputItem *models.Item) *models.Facility {
 				facility := models.NewFacility("Test Facility", "Test Description", 100)
-				facility.AddInputRequirement(models.NewInputRequirement(inputItem, 2))
+				facility.AddInputRequirement(models.NewInputRequirement(inputItem, 2.5))
 				facility.AddOutputDefinition(models.NewOutputDefinition(outputItem, 1))
 				return facility
 			},
@@ -143,16 +157,49 @@ func (s *FacilityRepositoryTestSuite) TestCreate() {
 
 				s.Len(entity.InputRequirements, 1)
 				s.Equal(inputItem.ID(), int(entity.InputRequirements[0].ItemID))
-				s.Equal(2, entity.InputRequirements[0].Quantity)
+				s.Equal(2.5, entity.InputRequirements[0].Quantity)
 
 				s.Len(entity.OutputDefinitions, 1)
 				s.Equal(outputItem.ID(), int(entity.OutputDefinitions[0].ItemID))
-				s.Equal(1, entity.OutputDefinitions[0].Quantity)
+				s.Equal(1.0, entity.OutputDefinitions[0].Quantity)
 			}
 		})
 	}
 }
 
+func (s *FacilityRepositoryTestSuite) TestCreateWithRecipe() {
+	s.SetupTest()
+	s.NoError(s.db.Exec("DELETE FROM recipes").Error)
+	s.NoError(s.db.Exec("DELETE FROM machine_types").Error)
+
+	plate := s.createTestItem("Plate")
+	gear := s.createTestItem("Gear")
+	machineType := models.NewMachineType("Assembler Mk2", "", 2)
+	s.NoError((&MachineTypeRepository{db: s.db}).Create(s.T().Context(), machineType))
+	recipe := models.NewRecipe("Gear", "", 3000)
+	recipe.AddInputRequirement(models.NewInputRequirement(plate, 2))
+	recipe.AddOutputDefinition(models.NewOutputDefinition(gear, 1))
+	s.NoError((&RecipeRepository{db: s.db}).Create(s.T().Context(), recipe))
+
+	facility := models.NewRecipeFacility("Gear Assembler", "", machineType, recipe)
+	s.NoError(s.repo.Create(s.T().Context(), facility))
+
+	result, err := s.repo.Get(s.T().Context(), facility.ID())
+	s.NoError(err)
+	s.NotNil(result)
+	s.Equal(machineType.ID(), result.MachineType().ID())
+	s.Equal(recipe.ID(), result.Recipe().ID())
+	s.Equal(int64(1500), result.ProcessingTime())
+	s.Len(result.InputRequirements(), 1)
+	s.Equal(plate.ID(), result.InputRequirements()[0].Item().ID())
+	s.Len(result.OutputDefinitions(), 1)
+	s.Equal(gear.ID(), result.OutputDefinitions()[0].Item().ID())
+
+	var count int64
+	s.NoError(s.db.Model(&entities.InputRequirementEntity{}).Where("facility_id = ?", facility.ID()).Count(&count).Error)
+	s.Equal(int64(0), count)
+}
+
 func (s *FacilityRepositoryTestSuite) TestGet() {
 	// Create test items
 	inputItem := s.createTestItem("Input Item")
@@ -212,11 +259,11 @@ func (s *FacilityRepositoryTestSuite) TestGet() {
 				// Verify relationships
 				s.Len(result.InputRequirements(), 1)
 				s.Equal(inputItem.ID(), result.InputRequirements()[0].Item().ID())
-				s.Equal(1, result.InputRequirements()[0].Quantity())
+				s.Equal(1.0, result.InputRequirements()[0].Quantity())
 
 				s.Len(result.OutputDefinitions(), 1)
 				s.Equal(outputItem.ID(), result.OutputDefinitions()[0].Item().ID())
-				s.Equal(2, result.OutputDefinitions()[0].Quantity())
+				s.Equal(2.0, result.OutputDefinitions()[0].Quantity())
 			} else {
 				s.Nil(result)
 			}
@@ -273,6 +320,9 @@ func (s *FacilityRepositoryTestSuite) TestUpdate() {
 		[]*models.InputRequirement{models.NewInputRequirement(inputItem2, 3)},
 		[]*models.OutputDefinition{models.NewOutputDefinition(outputItem2, 4)},
 		200,
+		nil,
+		nil,
+		models.NewPowerProfile(4, 0.5, 0),
 	)
 
 	err := s.repo.Update(s.T().Context(), updatedFacility)
@@ -287,15 +337,16 @@ func (s *FacilityRepositoryTestSuite) TestUpdate() {
 	s.Equal(updatedFacility.Name(), updated.Name())
 	s.Equal(updatedFacility.Description(), updated.Description())
 	s.Equal(updatedFacility.ProcessingTime(), updated.ProcessingTime())
+	s.Equal(updatedFacility.Power(), updated.Power())
 
 	// Verify relationships
 	s.Len(updated.InputRequirements(), 1)
 	s.Equal(inputItem2.ID(), updated.InputRequirements()[0].Item().ID())
-	s.Equal(3, updated.InputRequirements()[0].Quantity())
+	s.Equal(3.0, updated.InputRequirements()[0].Quantity())
 
 	s.Len(updated.OutputDefinitions(), 1)
 	s.Equal(outputItem2.ID(), updated.OutputDefinitions()[0].Item().ID())
-	s.Equal(4, updated.OutputDefinitions()[0].Quantity())
+	s.Equal(4.0, updated.OutputDefinitions()[0].Quantity())
 
 	// Test updating non-existent facility
 	nonExistentFacility := models.NewFacilityFromParams(
@@ -305,6 +356,9 @@ func (s *FacilityRepositoryTestSuite) TestUpdate() {
 		[]*models.InputRequirement{},
 		[]*models.OutputDefinition{},
 		100,
+		nil,
+		nil,
+		models.PowerProfile{},
 	)
 	err = s.repo.Update(s.T().Context(), nonExistentFacility)
 	s.Equal(gorm.ErrRecordNotFound, err)
@@ -331,6 +385,20 @@ func (s *FacilityRepositoryTestSuite) TestDelete() {
 			},
 			expectErr: nil,
 		},
+		{
+			name: "rejects facilities used by pipeline nodes",
+			setupFunc: func() *models.Facility {
+				facility := s.createTestFacility("Used Facility", []*models.Item{inputItem}, []*models.Item{outputItem})
+				pipeline := models.NewPipeline("Test Pipeline", "")
+				pipeline.AddNode(models.NewPipelineNode(facility, 1, 1))
+				s.NoError(s.pipelineRepo.Create(s.T().Context(), pipeline))
+				return facility
+			},
+			getID: func(facility *models.Facility) int {
+				return facility.ID()
+			},
+			expectErr: repositories.ErrInUse,
+		},
 		{
 			name:      "returns error when ID does not exist",
 			setupFunc: nil,
@@ -350,7 +418,7 @@ func (s *FacilityRepositoryTestSuite) TestDelete() {
 			err := s.repo.Delete(s.T().Context(), inputID)
 
 			if tc.expectErr != nil {
-				s.Equal(tc.expectErr, err)
+				s.ErrorIs(err, tc.expectErr)
 			} else {
 				s.NoError(err)
 
@@ -65,6 +65,7 @@ func (r *ItemRepository) Update(ctx context.Context, item *models.Item) error {
 		Updates(map[string]interface{}{
 			"name":        entity.Name,
 			"description": entity.Description,
+			"kind":        entity.Kind,
 		})
 
 	if result.Error != nil {
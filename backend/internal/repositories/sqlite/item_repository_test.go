@@ -34,7 +34,7 @@ func (s *ItemRepositoryTestSuite) SetupTest() {
 // createTestItem creates and persists a test item with the given name and description.
 // Returns the created item with its auto-generated ID.
 func (s *ItemRepositoryTestSuite) createTestItem(name, description string) *models.Item {
-	item := models.NewItemFromParams(0, name, description)
+	item := models.NewItemFromParams(0, name, description, models.ItemKindSolid)
 	err := s.repo.Create(s.T().Context(), item)
 	s.NoError(err)
 	s.Greater(item.ID(), 0)
@@ -51,16 +51,16 @@ func (s *ItemRepositoryTestSuite) TestCreate() {
 	}{
 		{
 			name:        "creates a new item",
-			input:       models.NewItemFromParams(0, "Test Item", "Test Description"),
+			input:       models.NewItemFromParams(0, "Test Item", "Test Description", models.ItemKindSolid),
 			expectError: false,
 		},
 		{
 			name: "enforces unique name constraint",
 			setup: func() {
-				existingItem := models.NewItemFromParams(0, "Test Item", "Original Description")
+				existingItem := models.NewItemFromParams(0, "Test Item", "Original Description", models.ItemKindSolid)
 				s.NoError(s.repo.Create(s.T().Context(), existingItem))
 			},
-			input:       models.NewItemFromParams(0, "Test Item", "Different Description"),
+			input:       models.NewItemFromParams(0, "Test Item", "Different Description", models.ItemKindSolid),
 			expectError: true,
 			errorMsg:    "UNIQUE constraint failed",
 		},
@@ -89,6 +89,7 @@ func (s *ItemRepositoryTestSuite) TestCreate() {
 				s.Equal(tc.input.ID(), int(entity.ID))
 				s.Equal(tc.input.Name(), entity.Name)
 				s.Equal(tc.input.Description(), entity.Description)
+				s.Equal(string(tc.input.Kind()), entity.Kind)
 			}
 		})
 	}
@@ -171,7 +172,7 @@ func (s *ItemRepositoryTestSuite) TestList() {
 func (s *ItemRepositoryTestSuite) TestUpdate() {
 	item := s.createTestItem("Original Name", "Original Description")
 
-	updatedItem := models.NewItemFromParams(item.ID(), "Updated Name", "Updated Description")
+	updatedItem := models.NewItemFromParams(item.ID(), "Updated Name", "Updated Description", models.ItemKindFluid)
 	err := s.repo.Update(s.T().Context(), updatedItem)
 	s.NoError(err)
 
@@ -180,8 +181,9 @@ func (s *ItemRepositoryTestSuite) TestUpdate() {
 	s.NoError(err)
 	s.Equal(updatedItem.Name(), updatedEntity.Name)
 	s.Equal(updatedItem.Description(), updatedEntity.Description)
+	s.Equal(string(updatedItem.Kind()), updatedEntity.Kind)
 
-	nonExistentItem := models.NewItemFromParams(999, "Non-existent", "Non-existent")
+	nonExistentItem := models.NewItemFromParams(999, "Non-existent", "Non-existent", models.ItemKindSolid)
 	err = s.repo.Update(s.T().Context(), nonExistentItem)
 	s.Equal(gorm.ErrRecordNotFound, err)
 }
@@ -0,0 +1,101 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fasim/backend/internal/models"
+	"github.com/fasim/backend/internal/repositories"
+	"github.com/fasim/backend/internal/repositories/db"
+	"github.com/fasim/backend/internal/repositories/entities"
+	"gorm.io/gorm"
+)
+
+// MachineTypeRepository implements the MachineTypeRepository interface using SQLite with GORM
+type MachineTypeRepository struct {
+	db *db.DB
+}
+
+// NewMachineTypeRepository creates a new SQLite-backed machine type repository
+func NewMachineTypeRepository(db *db.DB) repositories.MachineTypeRepository {
+	return &MachineTypeRepository{db: db}
+}
+
+// Create stores a new machine type
+func (r *MachineTypeRepository) Create(ctx context.Context, machineType *models.MachineType) error {
+	entity := entities.MachineTypeEntityFromModel(machineType)
+	if err := r.db.WithContext(ctx).Create(entity).Error; err != nil {
+		return err
+	}
+	newMachineType := entity.ToModel()
+	*machineType = *newMachineType
+	return nil
+}
+
+// Get retrieves a machine type by ID
+func (r *MachineTypeRepository) Get(ctx context.Context, id int) (*models.MachineType, error) {
+	var entity entities.MachineTypeEntity
+	if err := r.db.WithContext(ctx).First(&entity, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return entity.ToModel(), nil
+}
+
+// List retrieves all machine types
+func (r *MachineTypeRepository) List(ctx context.Context) ([]*models.MachineType, error) {
+	var entities []entities.MachineTypeEntity
+	if err := r.db.WithContext(ctx).Find(&entities).Error; err != nil {
+		return nil, err
+	}
+
+	machineTypes := make([]*models.MachineType, len(entities))
+	for i, entity := range entities {
+		machineTypes[i] = entity.ToModel()
+	}
+	return machineTypes, nil
+}
+
+// Update updates an existing machine type
+func (r *MachineTypeRepository) Update(ctx context.Context, machineType *models.MachineType) error {
+	entity := entities.MachineTypeEntityFromModel(machineType)
+	result := r.db.WithContext(ctx).Model(&entities.MachineTypeEntity{}).
+		Where("id = ?", machineType.ID()).
+		Updates(map[string]interface{}{
+			"name":           entity.Name,
+			"description":    entity.Description,
+			"crafting_speed": entity.CraftingSpeed,
+		})
+
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Delete removes a machine type by ID. Machine types used by facilities cannot be deleted.
+func (r *MachineTypeRepository) Delete(ctx context.Context, id int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var users int64
+		if err := tx.Model(&entities.FacilityEntity{}).Where("machine_type_id = ?", id).Count(&users).Error; err != nil {
+			return err
+		}
+		if users > 0 {
+			return fmt.Errorf("machine type %d is used by %d facilities: %w", id, users, repositories.ErrInUse)
+		}
+
+		result := tx.Delete(&entities.MachineTypeEntity{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
@@ -0,0 +1,202 @@
+package sqlite
+
+import (
+	"testing"
+
+	"github.com/fasim/backend/internal/models"
+	"github.com/fasim/backend/internal/repositories"
+	"github.com/fasim/backend/internal/repositories/entities"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type MachineTypeRepositoryTestSuite struct {
+	BaseSQLiteTestSuite
+	repo         *MachineTypeRepository
+	recipeRepo   *RecipeRepository
+	facilityRepo *FacilityRepository
+}
+
+func TestMachineTypeRepositorySuite(t *testing.T) {
+	suite.Run(t, new(MachineTypeRepositoryTestSuite))
+}
+
+func (s *MachineTypeRepositoryTestSuite) SetupSuite() {
+	s.SetupDockerAndDB(
+		&entities.ItemEntity{},
+		&entities.MachineTypeEntity{},
+		&entities.RecipeEntity{},
+		&entities.RecipeInputEntity{},
+		&entities.RecipeOutputEntity{},
+		&entities.FacilityEntity{},
+		&entities.InputRequirementEntity{},
+		&entities.OutputDefinitionEntity{},
+	)
+	s.repo = &MachineTypeRepository{db: s.db}
+	s.recipeRepo = &RecipeRepository{db: s.db}
+	s.facilityRepo = &FacilityRepository{db: s.db}
+}
+
+func (s *MachineTypeRepositoryTestSuite) TearDownSuite() {
+	s.TearDownDocker()
+}
+
+func (s *MachineTypeRepositoryTestSuite) SetupTest() {
+	s.NoError(s.db.Exec("DELETE FROM facilities").Error)
+	s.NoError(s.db.Exec("DELETE FROM recipes").Error)
+	s.NoError(s.db.Exec("DELETE FROM machine_types").Error)
+}
+
+// createTestMachineType creates and persists a test machine type
+func (s *MachineTypeRepositoryTestSuite) createTestMachineType(name string, craftingSpeed float64) *models.MachineType {
+	machineType := models.NewMachineType(name, "Test Description for "+name, craftingSpeed)
+	err := s.repo.Create(s.T().Context(), machineType)
+	s.NoError(err)
+	s.Greater(machineType.ID(), 0)
+	return machineType
+}
+
+func (s *MachineTypeRepositoryTestSuite) TestCreate() {
+	testCases := []struct {
+		name        string
+		setup       func()
+		input       *models.MachineType
+		expectError bool
+		errorMsg    string
+	}{
+		{
+			name:        "creates a new machine type",
+			input:       models.NewMachineType("Assembler Mk2", "Test Description", 0.75),
+			expectError: false,
+		},
+		{
+			name: "enforces unique name constraint",
+			setup: func() {
+				s.createTestMachineType("Assembler Mk2", 0.75)
+			},
+			input:       models.NewMachineType("Assembler Mk2", "Different Description", 1.25),
+			expectError: true,
+			errorMsg:    "UNIQUE constraint failed",
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			s.SetupTest()
+
+			if tc.setup != nil {
+				tc.setup()
+			}
+
+			err := s.repo.Create(s.T().Context(), tc.input)
+
+			if tc.expectError {
+				s.Error(err)
+				s.Contains(err.Error(), tc.errorMsg)
+			} else {
+				s.NoError(err)
+				s.Greater(tc.input.ID(), 0)
+
+				var entity entities.MachineTypeEntity
+				s.NoError(s.db.First(&entity, tc.input.ID()).Error)
+				s.Equal(tc.input.Name(), entity.Name)
+				s.Equal(tc.input.Description(), entity.Description)
+				s.Equal(tc.input.CraftingSpeed(), entity.CraftingSpeed)
+			}
+		})
+	}
+}
+
+func (s *MachineTypeRepositoryTestSuite) TestGetAndList() {
+	s.SetupTest()
+	machineTypes := []*models.MachineType{
+		s.createTestMachineType("Assembler Mk1", 0.5),
+		s.createTestMachineType("Assembler Mk2", 0.75),
+	}
+
+	result, err := s.repo.Get(s.T().Context(), machineTypes[1].ID())
+	s.NoError(err)
+	s.NotNil(result)
+	s.Equal(machineTypes[1].Name(), result.Name())
+	s.Equal(machineTypes[1].CraftingSpeed(), result.CraftingSpeed())
+
+	missing, err := s.repo.Get(s.T().Context(), 999)
+	s.NoError(err)
+	s.Nil(missing)
+
+	results, err := s.repo.List(s.T().Context())
+	s.NoError(err)
+	s.Len(results, len(machineTypes))
+	for i, result := range results {
+		s.Equal(machineTypes[i].ID(), result.ID())
+		s.Equal(machineTypes[i].Name(), result.Name())
+	}
+}
+
+func (s *MachineTypeRepositoryTestSuite) TestUpdate() {
+	s.SetupTest()
+	machineType := s.createTestMachineType("Assembler", 0.5)
+
+	updatedMachineType := models.NewMachineTypeFromParams(machineType.ID(), "Assembler Mk3", "Updated Description", 1.25)
+	s.NoError(s.repo.Update(s.T().Context(), updatedMachineType))
+
+	result, err := s.repo.Get(s.T().Context(), machineType.ID())
+	s.NoError(err)
+	s.Equal(updatedMachineType.Name(), result.Name())
+	s.Equal(updatedMachineType.Description(), result.Description())
+	s.Equal(updatedMachineType.CraftingSpeed(), result.CraftingSpeed())
+
+	nonExistentMachineType := models.NewMachineTypeFromParams(999, "Non-existent", "Non-existent", 1)
+	err = s.repo.Update(s.T().Context(), nonExistentMachineType)
+	s.Equal(gorm.ErrRecordNotFound, err)
+}
+
+func (s *MachineTypeRepositoryTestSuite) TestDelete() {
+	testCases := []struct {
+		name      string
+		setupFunc func() int
+		expectErr error
+	}{
+		{
+			name: "successfully deletes an unused machine type",
+			setupFunc: func() int {
+				return s.createTestMachineType("Assembler", 1).ID()
+			},
+		},
+		{
+			name: "rejects machine types used by facilities",
+			setupFunc: func() int {
+				machineType := s.createTestMachineType("Assembler", 1)
+				recipe := models.NewRecipe("Gear", "", 500)
+				s.NoError(s.recipeRepo.Create(s.T().Context(), recipe))
+				s.NoError(s.facilityRepo.Create(s.T().Context(), models.NewRecipeFacility("Gear Assembler", "", machineType, recipe)))
+				return machineType.ID()
+			},
+			expectErr: repositories.ErrInUse,
+		},
+		{
+			name:      "returns error when ID does not exist",
+			setupFunc: func() int { return 999 },
+			expectErr: gorm.ErrRecordNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			s.SetupTest()
+
+			id := tc.setupFunc()
+			err := s.repo.Delete(s.T().Context(), id)
+
+			if tc.expectErr != nil {
+				s.ErrorIs(err, tc.expectErr)
+			} else {
+				s.NoError(err)
+
+				var count int64
+				s.NoError(s.db.Model(&entities.MachineTypeEntity{}).Where("id = ?", id).Count(&count).Error)
+				s.Equal(int64(0), count)
+			}
+		})
+	}
+}
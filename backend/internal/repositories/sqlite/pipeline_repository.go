@@ -20,8 +20,48 @@ func NewPipelineRepository(db *db.DB) repositories.PipelineRepository {
 	return &PipelineRepository{db: db}
 }
 
+// preloadPipeline loads all relationships needed to build a pipeline model
+func preloadPipeline(tx *gorm.DB) *gorm.DB {
+	return preloadFacility(tx, "Nodes.Facility.").
+		Preload("Nodes.Item").
+		Preload("Nodes.NextNodes").
+		Preload("Nodes.NextNodes.TargetNode").
+		Preload("Nodes.NextNodes.Item").
+		Preload("Nodes.NextNodes.Transport")
+}
+
+// createNodes stores the nodes of a pipeline and their connections, translating the
+// temporary node IDs referenced by connections into the generated ones
+func createNodes(tx *gorm.DB, pipelineID int, pipeline *models.Pipeline) error {
+	// Create nodes with auto-generated IDs
+	nodeIDMap := make(map[int]int) // Map from temporary ID to actual ID
+	for _, node := range pipeline.Nodes() {
+		nodeEntity := entities.PipelineNodeEntityFromModel(node, pipelineID)
+		if err := tx.Create(nodeEntity).Error; err != nil {
+			return err
+		}
+		nodeIDMap[node.ID()] = nodeEntity.ID
+	}
+
+	// Create node connections using actual IDs
+	for _, node := range pipeline.Nodes() {
+		for _, conn := range node.Connections() {
+			connEntity := entities.PipelineNodeConnectionEntityFromModel(conn, nodeIDMap[node.ID()], nodeIDMap[conn.TargetNodeID()])
+			if err := tx.Create(connEntity).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
 // Create stores a new pipeline
 func (r *PipelineRepository) Create(ctx context.Context, pipeline *models.Pipeline) error {
+	if err := pipeline.EnsureValid(); err != nil {
+		return err
+	}
+
 	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		// Create pipeline first
 		pipelineEntity := &entities.PipelineEntity{
@@ -32,42 +72,13 @@ func (r *PipelineRepository) Create(ctx context.Context, pipeline *models.Pipeli
 			return err
 		}
 
-		// Create nodes with auto-generated IDs
-		nodeMap := make(map[int]*entities.PipelineNodeEntity)
-		nodeIDMap := make(map[int]int) // Map from temporary ID to actual ID
-		for _, node := range pipeline.Nodes() {
-			nodeEntity := &entities.PipelineNodeEntity{
-				PipelineID: pipelineEntity.ID,
-				FacilityID: node.Facility().ID(),
-			}
-			if err := tx.Create(nodeEntity).Error; err != nil {
-				return err
-			}
-			nodeMap[node.ID()] = nodeEntity
-			nodeIDMap[node.ID()] = nodeEntity.ID
-		}
-
-		// Create node connections using actual IDs
-		for _, node := range pipeline.Nodes() {
-			for _, targetID := range node.NextNodeIDs() {
-				conn := &entities.PipelineNodeConnectionEntity{
-					SourceNodeID: nodeIDMap[node.ID()],
-					TargetNodeID: nodeIDMap[targetID],
-				}
-				if err := tx.Create(conn).Error; err != nil {
-					return err
-				}
-			}
+		if err := createNodes(tx, pipelineEntity.ID, pipeline); err != nil {
+			return err
 		}
 
 		// Get the complete pipeline with all relationships
 		var entity entities.PipelineEntity
-		if err := tx.Preload("Nodes.Facility").
-			Preload("Nodes.NextNodes").
-			Preload("Nodes.NextNodes.TargetNode").
-			Preload("Nodes.Facility.InputRequirements.Item").
-			Preload("Nodes.Facility.OutputDefinitions.Item").
-			First(&entity, pipelineEntity.ID).Error; err != nil {
+		if err := preloadPipeline(tx).First(&entity, pipelineEntity.ID).Error; err != nil {
 			return err
 		}
 
@@ -82,12 +93,7 @@ func (r *PipelineRepository) Create(ctx context.Context, pipeline *models.Pipeli
 // Get retrieves a pipeline by ID
 func (r *PipelineRepository) Get(ctx context.Context, id int) (*models.Pipeline, error) {
 	var entity entities.PipelineEntity
-	if err := r.db.WithContext(ctx).
-		Preload("Nodes.Facility.InputRequirements.Item").
-		Preload("Nodes.Facility.OutputDefinitions.Item").
-		Preload("Nodes.NextNodes").
-		Preload("Nodes.NextNodes.TargetNode").
-		First(&entity, id).Error; err != nil {
+	if err := preloadPipeline(r.db.WithContext(ctx)).First(&entity, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, nil
 		}
@@ -99,12 +105,7 @@ func (r *PipelineRepository) Get(ctx context.Context, id int) (*models.Pipeline,
 // List retrieves all pipelines
 func (r *PipelineRepository) List(ctx context.Context) ([]*models.Pipeline, error) {
 	var entities []entities.PipelineEntity
-	if err := r.db.WithContext(ctx).
-		Preload("Nodes.Facility.InputRequirements.Item").
-		Preload("Nodes.Facility.OutputDefinitions.Item").
-		Preload("Nodes.NextNodes").
-		Preload("Nodes.NextNodes.TargetNode").
-		Find(&entities).Error; err != nil {
+	if err := preloadPipeline(r.db.WithContext(ctx)).Find(&entities).Error; err != nil {
 		return nil, err
 	}
 
@@ -117,6 +118,10 @@ func (r *PipelineRepository) List(ctx context.Context) ([]*models.Pipeline, erro
 
 // Update updates an existing pipeline
 func (r *PipelineRepository) Update(ctx context.Context, pipeline *models.Pipeline) error {
+	if err := pipeline.EnsureValid(); err != nil {
+		return err
+	}
+
 	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		// Check if pipeline exists
 		var count int64
@@ -146,42 +151,13 @@ func (r *PipelineRepository) Update(ctx context.Context, pipeline *models.Pipeli
 			return err
 		}
 
-		// Create nodes with auto-generated IDs
-		nodeMap := make(map[int]*entities.PipelineNodeEntity)
-		nodeIDMap := make(map[int]int) // Map from temporary ID to actual ID
-		for _, node := range pipeline.Nodes() {
-			nodeEntity := &entities.PipelineNodeEntity{
-				PipelineID: pipeline.ID(),
-				FacilityID: node.Facility().ID(),
-			}
-			if err := tx.Create(nodeEntity).Error; err != nil {
-				return err
-			}
-			nodeMap[node.ID()] = nodeEntity
-			nodeIDMap[node.ID()] = nodeEntity.ID
-		}
-
-		// Create node connections using actual IDs
-		for _, node := range pipeline.Nodes() {
-			for _, targetID := range node.NextNodeIDs() {
-				conn := &entities.PipelineNodeConnectionEntity{
-					SourceNodeID: nodeIDMap[node.ID()],
-					TargetNodeID: nodeIDMap[targetID],
-				}
-				if err := tx.Create(conn).Error; err != nil {
-					return err
-				}
-			}
+		if err := createNodes(tx, pipeline.ID(), pipeline); err != nil {
+			return err
 		}
 
 		// Get the complete pipeline with all relationships
 		var entity entities.PipelineEntity
-		if err := tx.Preload("Nodes.Facility").
-			Preload("Nodes.NextNodes").
-			Preload("Nodes.NextNodes.TargetNode").
-			Preload("Nodes.Facility.InputRequirements.Item").
-			Preload("Nodes.Facility.OutputDefinitions.Item").
-			First(&entity, pipeline.ID()).Error; err != nil {
+		if err := preloadPipeline(tx).First(&entity, pipeline.ID()).Error; err != nil {
 			return err
 		}
 
@@ -11,9 +11,10 @@ import (
 
 type PipelineRepositoryTestSuite struct {
 	BaseSQLiteTestSuite
-	repo         *PipelineRepository
-	facilityRepo *FacilityRepository
-	itemRepo     *ItemRepository
+	repo          *PipelineRepository
+	facilityRepo  *FacilityRepository
+	itemRepo      *ItemRepository
+	transportRepo *TransportRepository
 }
 
 func TestPipelineRepositorySuite(t *testing.T) {
@@ -23,9 +24,14 @@ func TestPipelineRepositorySuite(t *testing.T) {
 func (s *PipelineRepositoryTestSuite) SetupSuite() {
 	s.SetupDockerAndDB(
 		&entities.ItemEntity{},
+		&entities.MachineTypeEntity{},
+		&entities.RecipeEntity{},
+		&entities.RecipeInputEntity{},
+		&entities.RecipeOutputEntity{},
 		&entities.FacilityEntity{},
 		&entities.InputRequirementEntity{},
 		&entities.OutputDefinitionEntity{},
+		&entities.TransportEntity{},
 		&entities.PipelineEntity{},
 		&entities.PipelineNodeEntity{},
 		&entities.PipelineNodeConnectionEntity{},
@@ -33,6 +39,7 @@ func (s *PipelineRepositoryTestSuite) SetupSuite() {
 	s.repo = &PipelineRepository{db: s.db}
 	s.facilityRepo = &FacilityRepository{db: s.db}
 	s.itemRepo = &ItemRepository{db: s.db}
+	s.transportRepo = &TransportRepository{db: s.db}
 }
 
 func (s *PipelineRepositoryTestSuite) TearDownSuite() {
@@ -43,6 +50,7 @@ func (s *PipelineRepositoryTestSuite) SetupTest() {
 	s.NoError(s.db.Exec("DELETE FROM pipeline_node_connections").Error)
 	s.NoError(s.db.Exec("DELETE FROM pipeline_nodes").Error)
 	s.NoError(s.db.Exec("DELETE FROM pipelines").Error)
+	s.NoError(s.db.Exec("DELETE FROM transports").Error)
 	s.NoError(s.db.Exec("DELETE FROM facilities").Error)
 	s.NoError(s.db.Exec("DELETE FROM input_requirements").Error)
 	s.NoError(s.db.Exec("DELETE FROM output_definitions").Error)
@@ -51,7 +59,7 @@ func (s *PipelineRepositoryTestSuite) SetupTest() {
 
 // createTestItem creates and persists a test item
 func (s *PipelineRepositoryTestSuite) createTestItem(name string) *models.Item {
-	item := models.NewItemFromParams(0, name, "Test Description for "+name)
+	item := models.NewItemFromParams(0, name, "Test Description for "+name, models.ItemKindSolid)
 	err := s.itemRepo.Create(s.T().Context(), item)
 	s.NoError(err)
 	return item
@@ -63,13 +71,13 @@ func (s *PipelineRepositoryTestSuite) createTestFacility(name string, inputItems
 
 	// Add input requirements
 	for i, item := range inputItems {
-		req := models.NewInputRequirement(item, i+1)
+		req := models.NewInputRequirement(item, float64(i+1))
 		facility.AddInputRequirement(req)
 	}
 
 	// Add output definitions
 	for i, item := range outputItems {
-		def := models.NewOutputDefinition(item, i+2)
+		def := models.NewOutputDefinition(item, float64(i+2))
 		facility.AddOutputDefinition(def)
 	}
 
@@ -80,11 +88,11 @@ func (s *PipelineRepositoryTestSuite) createTestFacility(name string, inputItems
 
 // createTestPipeline creates and persists a test pipeline with the given facilities
 func (s *PipelineRepositoryTestSuite) createTestPipeline(name string, facilities []*models.Facility) *models.Pipeline {
-	pipeline := models.NewPipeline(name)
+	pipeline := models.NewPipeline(name, "")
 
 	// Create nodes for each facility
 	for i, facility := range facilities {
-		node := models.NewPipelineNode(facility)
+		node := models.NewPipelineNode(facility, 1, 1)
 		// Connect nodes sequentially if there's a next facility
 		if i < len(facilities)-1 {
 			node.AddNextNodeID(i + 2) // Reference to next node's temporary ID
@@ -122,11 +130,11 @@ func (s *PipelineRepositoryTestSuite) TestCreate() {
 				return item1, item2, facility1, facility2
 			},
 			input: func(facility1, facility2 *models.Facility) *models.Pipeline {
-				pipeline := models.NewPipeline("Test Pipeline")
-				node1 := models.NewPipelineNode(facility1)
+				pipeline := models.NewPipeline("Test Pipeline", "")
+				node1 := models.NewPipelineNode(facility1, 1, 1)
 				node1.AddNextNodeID(2)
 				pipeline.AddNode(node1)
-				node2 := models.NewPipelineNode(facility2)
+				node2 := models.NewPipelineNode(facility2, 1, 1)
 				pipeline.AddNode(node2)
 				return pipeline
 			},
@@ -140,25 +148,69 @@ func (s *PipelineRepositoryTestSuite) TestCreate() {
 				facility1 := s.createTestFacility("Facility 1", []*models.Item{item1}, []*models.Item{item2})
 				facility2 := s.createTestFacility("Facility 2", []*models.Item{item2}, []*models.Item{item1})
 
-				existingPipeline := models.NewPipeline("Test Pipeline")
-				node := models.NewPipelineNode(facility1)
+				existingPipeline := models.NewPipeline("Test Pipeline", "")
+				node := models.NewPipelineNode(facility1, 1, 1)
 				existingPipeline.AddNode(node)
 				s.NoError(s.repo.Create(s.T().Context(), existingPipeline))
 
 				return item1, item2, facility1, facility2
 			},
 			input: func(facility1, facility2 *models.Facility) *models.Pipeline {
-				pipeline := models.NewPipeline("Test Pipeline")
-				node1 := models.NewPipelineNode(facility1)
+				pipeline := models.NewPipeline("Test Pipeline", "")
+				node1 := models.NewPipelineNode(facility1, 1, 1)
 				node1.AddNextNodeID(2)
 				pipeline.AddNode(node1)
-				node2 := models.NewPipelineNode(facility2)
+				node2 := models.NewPipelineNode(facility2, 1, 1)
 				pipeline.AddNode(node2)
 				return pipeline
 			},
 			expectError: true,
 			errorMsg:    "UNIQUE constraint failed",
 		},
+		{
+			name: "rejects connections to missing nodes",
+			setup: func() (*models.Item, *models.Item, *models.Facility, *models.Facility) {
+				item1 := s.createTestItem("Item 1")
+				item2 := s.createTestItem("Item 2")
+				facility1 := s.createTestFacility("Facility 1", []*models.Item{item1}, []*models.Item{item2})
+				facility2 := s.createTestFacility("Facility 2", []*models.Item{item2}, []*models.Item{item1})
+				return item1, item2, facility1, facility2
+			},
+			input: func(facility1, facility2 *models.Facility) *models.Pipeline {
+				pipeline := models.NewPipeline("Test Pipeline", "")
+				node1 := models.NewPipelineNode(facility1, 1, 1)
+				node1.AddNextNodeID(3)
+				pipeline.AddNode(node1)
+				node2 := models.NewPipelineNode(facility2, 1, 1)
+				pipeline.AddNode(node2)
+				return pipeline
+			},
+			expectError: true,
+			errorMsg:    "does not exist",
+		},
+		{
+			name: "stores routing constraints of connections",
+			setup: func() (*models.Item, *models.Item, *models.Facility, *models.Facility) {
+				item1 := s.createTestItem("Item 1")
+				item2 := s.createTestItem("Item 2")
+				facility1 := s.createTestFacility("Facility 1", []*models.Item{item1}, []*models.Item{item2})
+				facility2 := s.createTestFacility("Facility 2", []*models.Item{item2}, []*models.Item{item1})
+				return item1, item2, facility1, facility2
+			},
+			input: func(facility1, facility2 *models.Facility) *models.Pipeline {
+				pipeline := models.NewPipeline("Test Pipeline", "")
+				transport := models.NewTransport("Yellow Belt", "", models.ItemKindSolid, 15)
+				s.NoError(s.transportRepo.Create(s.T().Context(), transport))
+				node1 := models.NewPipelineNode(facility1, 1, 1)
+				node1.AddConnection(models.NewPipelineConnection(2, facility1.OutputDefinitions()[0].Item(), 0.5, 1, 30, transport))
+				node1.SetBuffers(models.NewBufferCapacity(5, 2))
+				pipeline.AddNode(node1)
+				node2 := models.NewPipelineNode(facility2, 1, 1)
+				pipeline.AddNode(node2)
+				return pipeline
+			},
+			expectError: false,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -194,6 +246,7 @@ func (s *PipelineRepositoryTestSuite) TestCreate() {
 						}
 					}
 					s.Equal(originalNode.Facility().ID(), node.Facility().ID())
+					s.Equal(originalNode.Buffers(), node.Buffers())
 
 					if len(originalNode.NextNodeIDs()) > 0 {
 						s.Len(node.NextNodeIDs(), len(originalNode.NextNodeIDs()))
@@ -211,6 +264,24 @@ func (s *PipelineRepositoryTestSuite) TestCreate() {
 					} else {
 						s.Empty(node.NextNodeIDs())
 					}
+
+					s.Len(node.Connections(), len(originalNode.Connections()))
+					for i, conn := range originalNode.Connections() {
+						if conn.Item() == nil {
+							s.Nil(node.Connections()[i].Item())
+						} else {
+							s.Equal(conn.Item().ID(), node.Connections()[i].Item().ID())
+						}
+						s.Equal(conn.Ratio(), node.Connections()[i].Ratio())
+						s.Equal(conn.Priority(), node.Connections()[i].Priority())
+						s.Equal(conn.MaxThroughput(), node.Connections()[i].MaxThroughput())
+						if conn.Transport() == nil {
+							s.Nil(node.Connections()[i].Transport())
+						} else {
+							s.Equal(conn.Transport().ID(), node.Connections()[i].Transport().ID())
+							s.Equal(conn.Transport().ItemsPerSecond(), node.Connections()[i].Transport().ItemsPerSecond())
+						}
+					}
 				}
 			}
 		})
@@ -286,6 +357,7 @@ func (s *PipelineRepositoryTestSuite) TestGet() {
 						}
 					}
 					s.Equal(originalNode.Facility().ID(), node.Facility().ID())
+					s.Equal(originalNode.Buffers(), node.Buffers())
 
 					// Verify connections by matching facility IDs
 					if len(originalNode.NextNodeIDs()) > 0 {
@@ -391,12 +463,12 @@ func (s *PipelineRepositoryTestSuite) TestUpdate() {
 	)
 
 	// Add first node with new facility
-	node1 := models.NewPipelineNode(facilities[1])
+	node1 := models.NewPipelineNode(facilities[1], 1, 1)
 	node1.AddNextNodeID(2) // Reference to second node's temporary ID
 	updatedPipeline.AddNode(node1)
 
 	// Add second node with new facility
-	node2 := models.NewPipelineNode(facilities[2])
+	node2 := models.NewPipelineNode(facilities[2], 1, 1)
 	updatedPipeline.AddNode(node2)
 
 	err := s.repo.Update(s.T().Context(), updatedPipeline)
@@ -454,6 +526,50 @@ func (s *PipelineRepositoryTestSuite) TestUpdate() {
 	s.Equal(gorm.ErrRecordNotFound, err)
 }
 
+func (s *PipelineRepositoryTestSuite) TestSourceAndSinkNodes() {
+	ore := s.createTestItem("Ore")
+	plate := s.createTestItem("Plate")
+	furnace := s.createTestFacility("Furnace", []*models.Item{ore}, []*models.Item{plate})
+
+	pipeline := models.NewPipeline("Bounded Pipeline", "")
+	source := models.NewSourceNode(ore, 30)
+	pipeline.AddNode(source)
+	furnaceNode := models.NewPipelineNode(furnace, 1, 1)
+	pipeline.AddNode(furnaceNode)
+	sink := models.NewSinkNode(plate, 0)
+	sink.SetBuffers(models.NewBufferCapacity(10, 0))
+	pipeline.AddNode(sink)
+	source.AddNextNodeID(furnaceNode.ID())
+	furnaceNode.AddNextNodeID(sink.ID())
+	s.Require().NoError(s.repo.Create(s.T().Context(), pipeline))
+
+	result, err := s.repo.Get(s.T().Context(), pipeline.ID())
+	s.Require().NoError(err)
+	s.Require().Len(result.Nodes(), 3)
+
+	nodes := make(map[models.NodeKind]*models.PipelineNode)
+	for _, node := range result.Nodes() {
+		nodes[node.Kind()] = node
+	}
+	s.Require().Contains(nodes, models.NodeKindSource)
+	s.Require().Contains(nodes, models.NodeKindSink)
+	s.Require().Contains(nodes, models.NodeKindFacility)
+
+	s.Nil(nodes[models.NodeKindSource].Facility())
+	s.Equal(ore.ID(), nodes[models.NodeKindSource].Item().ID())
+	s.Equal(30.0, nodes[models.NodeKindSource].PerMinute())
+	s.Equal([]int{nodes[models.NodeKindFacility].ID()}, nodes[models.NodeKindSource].NextNodeIDs())
+
+	s.Equal(plate.ID(), nodes[models.NodeKindSink].Item().ID())
+	s.Zero(nodes[models.NodeKindSink].PerMinute())
+	s.Equal(models.NewBufferCapacity(10, 0), nodes[models.NodeKindSink].Buffers())
+	s.Empty(nodes[models.NodeKindSink].NextNodeIDs())
+
+	s.Equal(furnace.ID(), nodes[models.NodeKindFacility].Facility().ID())
+	s.Nil(nodes[models.NodeKindFacility].Item())
+	s.Equal([]int{nodes[models.NodeKindSink].ID()}, nodes[models.NodeKindFacility].NextNodeIDs())
+}
+
 func (s *PipelineRepositoryTestSuite) TestDelete() {
 	// Create test items and facilities
 	item := s.createTestItem("Test Item")
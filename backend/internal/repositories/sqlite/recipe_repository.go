@@ -0,0 +1,145 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fasim/backend/internal/models"
+	"github.com/fasim/backend/internal/repositories"
+	"github.com/fasim/backend/internal/repositories/db"
+	"github.com/fasim/backend/internal/repositories/entities"
+	"gorm.io/gorm"
+)
+
+// RecipeRepository implements the RecipeRepository interface using SQLite with GORM
+type RecipeRepository struct {
+	db *db.DB
+}
+
+// NewRecipeRepository creates a new SQLite-backed recipe repository
+func NewRecipeRepository(db *db.DB) repositories.RecipeRepository {
+	return &RecipeRepository{db: db}
+}
+
+// Create stores a new recipe
+func (r *RecipeRepository) Create(ctx context.Context, recipe *models.Recipe) error {
+	entity := entities.RecipeEntityFromModel(recipe)
+	if err := r.db.WithContext(ctx).Create(entity).Error; err != nil {
+		return err
+	}
+	newRecipe := entity.ToModel()
+	*recipe = *newRecipe
+	return nil
+}
+
+// Get retrieves a recipe by ID
+func (r *RecipeRepository) Get(ctx context.Context, id int) (*models.Recipe, error) {
+	var entity entities.RecipeEntity
+	if err := r.db.WithContext(ctx).
+		Preload("InputRequirements.Item").
+		Preload("OutputDefinitions.Item").
+		First(&entity, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return entity.ToModel(), nil
+}
+
+// List retrieves all recipes
+func (r *RecipeRepository) List(ctx context.Context) ([]*models.Recipe, error) {
+	var entities []entities.RecipeEntity
+	if err := r.db.WithContext(ctx).
+		Preload("InputRequirements.Item").
+		Preload("OutputDefinitions.Item").
+		Find(&entities).Error; err != nil {
+		return nil, err
+	}
+
+	recipes := make([]*models.Recipe, len(entities))
+	for i, entity := range entities {
+		recipes[i] = entity.ToModel()
+	}
+	return recipes, nil
+}
+
+// Update updates an existing recipe
+func (r *RecipeRepository) Update(ctx context.Context, recipe *models.Recipe) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Check if recipe exists
+		var count int64
+		if err := tx.Model(&entities.RecipeEntity{}).Where("id = ?", recipe.ID()).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		// Delete existing relationships
+		if err := tx.Where("recipe_id = ?", recipe.ID()).Delete(&entities.RecipeInputEntity{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("recipe_id = ?", recipe.ID()).Delete(&entities.RecipeOutputEntity{}).Error; err != nil {
+			return err
+		}
+
+		entity := entities.RecipeEntityFromModel(recipe)
+
+		if err := tx.Model(&entities.RecipeEntity{}).
+			Where("id = ?", recipe.ID()).
+			Updates(map[string]interface{}{
+				"name":          entity.Name,
+				"description":   entity.Description,
+				"crafting_time": entity.CraftingTime,
+			}).Error; err != nil {
+			return err
+		}
+
+		// Create new relationships
+		if len(entity.InputRequirements) > 0 {
+			if err := tx.Create(&entity.InputRequirements).Error; err != nil {
+				return err
+			}
+		}
+		if len(entity.OutputDefinitions) > 0 {
+			if err := tx.Create(&entity.OutputDefinitions).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Delete removes a recipe by ID. Recipes used by facilities cannot be deleted.
+func (r *RecipeRepository) Delete(ctx context.Context, id int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Check if recipe exists
+		var count int64
+		if err := tx.Model(&entities.RecipeEntity{}).Where("id = ?", id).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		var users int64
+		if err := tx.Model(&entities.FacilityEntity{}).Where("recipe_id = ?", id).Count(&users).Error; err != nil {
+			return err
+		}
+		if users > 0 {
+			return fmt.Errorf("recipe %d is used by %d facilities: %w", id, users, repositories.ErrInUse)
+		}
+
+		// Delete relationships first
+		if err := tx.Where("recipe_id = ?", id).Delete(&entities.RecipeInputEntity{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("recipe_id = ?", id).Delete(&entities.RecipeOutputEntity{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(&entities.RecipeEntity{}, id).Error
+	})
+}
@@ -0,0 +1,207 @@
+package sqlite
+
+import (
+	"testing"
+
+	"github.com/fasim/backend/internal/models"
+	"github.com/fasim/backend/internal/repositories"
+	"github.com/fasim/backend/internal/repositories/entities"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type RecipeRepositoryTestSuite struct {
+	BaseSQLiteTestSuite
+	repo            *RecipeRepository
+	itemRepo        *ItemRepository
+	machineTypeRepo *MachineTypeRepository
+	facilityRepo    *FacilityRepository
+}
+
+func TestRecipeRepositorySuite(t *testing.T) {
+	suite.Run(t, new(RecipeRepositoryTestSuite))
+}
+
+func (s *RecipeRepositoryTestSuite) SetupSuite() {
+	s.SetupDockerAndDB(
+		&entities.ItemEntity{},
+		&entities.MachineTypeEntity{},
+		&entities.RecipeEntity{},
+		&entities.RecipeInputEntity{},
+		&entities.RecipeOutputEntity{},
+		&entities.FacilityEntity{},
+		&entities.InputRequirementEntity{},
+		&entities.OutputDefinitionEntity{},
+	)
+	s.repo = &RecipeRepository{db: s.db}
+	s.itemRepo = &ItemRepository{db: s.db}
+	s.machineTypeRepo = &MachineTypeRepository{db: s.db}
+	s.facilityRepo = &FacilityRepository{db: s.db}
+}
+
+func (s *RecipeRepositoryTestSuite) TearDownSuite() {
+	s.TearDownDocker()
+}
+
+func (s *RecipeRepositoryTestSuite) SetupTest() {
+	s.NoError(s.db.Exec("DELETE FROM facilities").Error)
+	s.NoError(s.db.Exec("DELETE FROM recipes").Error)
+	s.NoError(s.db.Exec("DELETE FROM recipe_inputs").Error)
+	s.NoError(s.db.Exec("DELETE FROM recipe_outputs").Error)
+	s.NoError(s.db.Exec("DELETE FROM machine_types").Error)
+	s.NoError(s.db.Exec("DELETE FROM items").Error)
+}
+
+// createTestItem creates and persists a test item
+func (s *RecipeRepositoryTestSuite) createTestItem(name string) *models.Item {
+	item := models.NewItemFromParams(0, name, "Test Description for "+name, models.ItemKindSolid)
+	err := s.itemRepo.Create(s.T().Context(), item)
+	s.NoError(err)
+	return item
+}
+
+// createTestRecipe creates and persists a recipe turning the input item into the output item
+func (s *RecipeRepositoryTestSuite) createTestRecipe(name string, input, output *models.Item) *models.Recipe {
+	recipe := models.NewRecipe(name, "Test Description for "+name, 1000)
+	recipe.AddInputRequirement(models.NewInputRequirement(input, 2))
+	recipe.AddOutputDefinition(models.NewOutputDefinition(output, 1))
+	err := s.repo.Create(s.T().Context(), recipe)
+	s.NoError(err)
+	s.Greater(recipe.ID(), 0)
+	return recipe
+}
+
+func (s *RecipeRepositoryTestSuite) TestCreateAndGet() {
+	s.SetupTest()
+	plate := s.createTestItem("Plate")
+	gear := s.createTestItem("Gear")
+	recipe := s.createTestRecipe("Gear", plate, gear)
+
+	result, err := s.repo.Get(s.T().Context(), recipe.ID())
+	s.NoError(err)
+	s.NotNil(result)
+	s.Equal(recipe.Name(), result.Name())
+	s.Equal(recipe.Description(), result.Description())
+	s.Equal(recipe.CraftingTime(), result.CraftingTime())
+
+	s.Len(result.InputRequirements(), 1)
+	s.Equal(plate.ID(), result.InputRequirements()[0].Item().ID())
+	s.Equal(plate.Name(), result.InputRequirements()[0].Item().Name())
+	s.Equal(2.0, result.InputRequirements()[0].Quantity())
+
+	s.Len(result.OutputDefinitions(), 1)
+	s.Equal(gear.ID(), result.OutputDefinitions()[0].Item().ID())
+	s.Equal(1.0, result.OutputDefinitions()[0].Quantity())
+
+	missing, err := s.repo.Get(s.T().Context(), 999)
+	s.NoError(err)
+	s.Nil(missing)
+
+	err = s.repo.Create(s.T().Context(), models.NewRecipe("Gear", "", 1000))
+	s.Error(err)
+	s.Contains(err.Error(), "UNIQUE constraint failed")
+}
+
+func (s *RecipeRepositoryTestSuite) TestList() {
+	s.SetupTest()
+	plate := s.createTestItem("Plate")
+	gear := s.createTestItem("Gear")
+	recipes := []*models.Recipe{
+		s.createTestRecipe("Gear", plate, gear),
+		s.createTestRecipe("Recycling", gear, plate),
+	}
+
+	results, err := s.repo.List(s.T().Context())
+	s.NoError(err)
+	s.Len(results, len(recipes))
+	for i, result := range results {
+		s.Equal(recipes[i].ID(), result.ID())
+		s.Equal(recipes[i].Name(), result.Name())
+		s.Len(result.InputRequirements(), 1)
+		s.Len(result.OutputDefinitions(), 1)
+	}
+}
+
+func (s *RecipeRepositoryTestSuite) TestUpdate() {
+	s.SetupTest()
+	plate := s.createTestItem("Plate")
+	gear := s.createTestItem("Gear")
+	recipe := s.createTestRecipe("Gear", plate, gear)
+
+	updatedRecipe := models.NewRecipeFromParams(
+		recipe.ID(),
+		"Fast Gear",
+		"Updated Description",
+		[]*models.InputRequirement{models.NewInputRequirement(plate, 3)},
+		[]*models.OutputDefinition{models.NewChanceOutputDefinition(gear, 2, 0.25)},
+		400,
+	)
+	s.NoError(s.repo.Update(s.T().Context(), updatedRecipe))
+
+	result, err := s.repo.Get(s.T().Context(), recipe.ID())
+	s.NoError(err)
+	s.Equal(updatedRecipe.Name(), result.Name())
+	s.Equal(updatedRecipe.Description(), result.Description())
+	s.Equal(updatedRecipe.CraftingTime(), result.CraftingTime())
+	s.Len(result.InputRequirements(), 1)
+	s.Equal(3.0, result.InputRequirements()[0].Quantity())
+	s.Len(result.OutputDefinitions(), 1)
+	s.Equal(2.0, result.OutputDefinitions()[0].Quantity())
+	s.Equal(0.25, result.OutputDefinitions()[0].Probability())
+
+	nonExistentRecipe := models.NewRecipeFromParams(999, "Non-existent", "", []*models.InputRequirement{}, []*models.OutputDefinition{}, 100)
+	err = s.repo.Update(s.T().Context(), nonExistentRecipe)
+	s.Equal(gorm.ErrRecordNotFound, err)
+}
+
+func (s *RecipeRepositoryTestSuite) TestDelete() {
+	testCases := []struct {
+		name      string
+		setupFunc func() int
+		expectErr error
+	}{
+		{
+			name: "successfully deletes an unused recipe with its relationships",
+			setupFunc: func() int {
+				return s.createTestRecipe("Gear", s.createTestItem("Plate"), s.createTestItem("Gear")).ID()
+			},
+		},
+		{
+			name: "rejects recipes used by facilities",
+			setupFunc: func() int {
+				recipe := s.createTestRecipe("Gear", s.createTestItem("Plate"), s.createTestItem("Gear"))
+				machineType := models.NewMachineType("Assembler", "", 1)
+				s.NoError(s.machineTypeRepo.Create(s.T().Context(), machineType))
+				s.NoError(s.facilityRepo.Create(s.T().Context(), models.NewRecipeFacility("Gear Assembler", "", machineType, recipe)))
+				return recipe.ID()
+			},
+			expectErr: repositories.ErrInUse,
+		},
+		{
+			name:      "returns error when ID does not exist",
+			setupFunc: func() int { return 999 },
+			expectErr: gorm.ErrRecordNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			s.SetupTest()
+
+			id := tc.setupFunc()
+			err := s.repo.Delete(s.T().Context(), id)
+
+			if tc.expectErr != nil {
+				s.ErrorIs(err, tc.expectErr)
+			} else {
+				s.NoError(err)
+
+				var count int64
+				s.NoError(s.db.Model(&entities.RecipeEntity{}).Where("id = ?", id).Count(&count).Error)
+				s.Equal(int64(0), count)
+				s.NoError(s.db.Model(&entities.RecipeInputEntity{}).Where("recipe_id = ?", id).Count(&count).Error)
+				s.Equal(int64(0), count)
+			}
+		})
+	}
+}
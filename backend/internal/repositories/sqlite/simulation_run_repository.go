@@ -0,0 +1,171 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+
+	"github.com/fasim/backend/internal/models"
+	"github.com/fasim/backend/internal/repositories"
+	"github.com/fasim/backend/internal/repositories/db"
+	"github.com/fasim/backend/internal/repositories/entities"
+	"gorm.io/gorm"
+)
+
+// resultBatchSize limits the rows inserted per statement when storing the statistics of a
+// run, which can contain many buffer samples
+const resultBatchSize = 500
+
+// SimulationRunRepository implements the SimulationRunRepository interface using SQLite with GORM
+type SimulationRunRepository struct {
+	db *db.DB
+}
+
+// NewSimulationRunRepository creates a new SQLite-backed simulation run repository
+func NewSimulationRunRepository(db *db.DB) repositories.SimulationRunRepository {
+	return &SimulationRunRepository{db: db}
+}
+
+// preloadSimulationRun loads all relationships needed to build a simulation run model
+func preloadSimulationRun(tx *gorm.DB) *gorm.DB {
+	return tx.
+		Preload("Nodes").
+		Preload("Nodes.Items").
+		Preload("Nodes.Samples").
+		Preload("Nodes.Samples.Items").
+		Preload("Links")
+}
+
+// Create stores a new simulation run
+func (r *SimulationRunRepository) Create(ctx context.Context, run *models.SimulationRun) error {
+	entity := entities.SimulationRunEntityFromModel(run)
+	tx := r.db.WithContext(ctx).Session(&gorm.Session{CreateBatchSize: resultBatchSize})
+	if err := tx.Create(entity).Error; err != nil {
+		return err
+	}
+	newRun := entity.ToModel()
+	*run = *newRun
+	return nil
+}
+
+// Get retrieves a simulation run by ID
+func (r *SimulationRunRepository) Get(ctx context.Context, id int) (*models.SimulationRun, error) {
+	var entity entities.SimulationRunEntity
+	if err := preloadSimulationRun(r.db.WithContext(ctx)).First(&entity, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return entity.ToModel(), nil
+}
+
+// ListByPipeline retrieves the simulation runs of a pipeline, ordered by ID. The runs carry
+// their statistics without the buffer samples, which can be many.
+func (r *SimulationRunRepository) ListByPipeline(ctx context.Context, pipelineID int) ([]*models.SimulationRun, error) {
+	var entities []entities.SimulationRunEntity
+	err := r.db.WithContext(ctx).
+		Preload("Nodes").
+		Preload("Nodes.Items").
+		Preload("Links").
+		Where("pipeline_id = ?", pipelineID).
+		Order("id").
+		Find(&entities).Error
+	if err != nil {
+		return nil, err
+	}
+
+	runs := make([]*models.SimulationRun, len(entities))
+	for i, entity := range entities {
+		runs[i] = entity.ToModel()
+	}
+	return runs, nil
+}
+
+// Update stores the status of an existing simulation run and replaces its statistics
+func (r *SimulationRunRepository) Update(ctx context.Context, run *models.SimulationRun) error {
+	entity := entities.SimulationRunEntityFromModel(run)
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&entities.SimulationRunEntity{}).
+			Where("id = ?", run.ID()).
+			Updates(map[string]interface{}{
+				"status":           entity.Status,
+				"error_message":    entity.ErrorMessage,
+				"finished_at":      entity.FinishedAt,
+				"power_demand":     entity.PowerDemand,
+				"power_generation": entity.PowerGeneration,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		if err := deleteSimulationResults(tx, run.ID()); err != nil {
+			return err
+		}
+		tx = tx.Session(&gorm.Session{CreateBatchSize: resultBatchSize})
+		if len(entity.Nodes) > 0 {
+			if err := tx.Create(&entity.Nodes).Error; err != nil {
+				return err
+			}
+		}
+		if len(entity.Links) > 0 {
+			if err := tx.Create(&entity.Links).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// FailUnfinished marks every pending or running simulation run as failed with the given
+// message. Unfinished runs have no statistics, so only their status changes.
+func (r *SimulationRunRepository) FailUnfinished(ctx context.Context, message string, at time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&entities.SimulationRunEntity{}).
+		Where("status IN ?", []string{string(models.SimulationStatusPending), string(models.SimulationStatusRunning)}).
+		Updates(map[string]interface{}{
+			"status":        string(models.SimulationStatusFailed),
+			"error_message": message,
+			"finished_at":   at,
+		}).Error
+}
+
+// Delete removes a simulation run and its statistics by ID
+func (r *SimulationRunRepository) Delete(ctx context.Context, id int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := deleteSimulationResults(tx, id); err != nil {
+			return err
+		}
+
+		result := tx.Delete(&entities.SimulationRunEntity{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// deleteSimulationResults removes the statistics stored for a run
+func deleteSimulationResults(tx *gorm.DB, runID int) error {
+	nodeResults := tx.Model(&entities.SimulationNodeResultEntity{}).Select("id").Where("run_id = ?", runID)
+	samples := tx.Model(&entities.SimulationSampleEntity{}).Select("id").Where("node_result_id IN (?)", nodeResults)
+
+	if err := tx.Where("sample_id IN (?)", samples).Delete(&entities.SimulationSampleItemEntity{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("node_result_id IN (?)", nodeResults).Delete(&entities.SimulationSampleEntity{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("node_result_id IN (?)", nodeResults).Delete(&entities.SimulationItemCountEntity{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("run_id = ?", runID).Delete(&entities.SimulationNodeResultEntity{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("run_id = ?", runID).Delete(&entities.SimulationLinkResultEntity{}).Error
+}
@@ -0,0 +1,210 @@
+package sqlite
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fasim/backend/internal/models"
+	"github.com/fasim/backend/internal/repositories/entities"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type SimulationRunRepositoryTestSuite struct {
+	BaseSQLiteTestSuite
+	repo *SimulationRunRepository
+}
+
+func TestSimulationRunRepositorySuite(t *testing.T) {
+	suite.Run(t, new(SimulationRunRepositoryTestSuite))
+}
+
+func (s *SimulationRunRepositoryTestSuite) SetupSuite() {
+	s.SetupDockerAndDB(
+		&entities.SimulationRunEntity{},
+		&entities.SimulationNodeResultEntity{},
+		&entities.SimulationItemCountEntity{},
+		&entities.SimulationSampleEntity{},
+		&entities.SimulationSampleItemEntity{},
+		&entities.SimulationLinkResultEntity{},
+	)
+	s.repo = &SimulationRunRepository{db: s.db}
+}
+
+func (s *SimulationRunRepositoryTestSuite) TearDownSuite() {
+	s.TearDownDocker()
+}
+
+func (s *SimulationRunRepositoryTestSuite) SetupTest() {
+	s.NoError(s.db.Exec("DELETE FROM simulation_sample_items").Error)
+	s.NoError(s.db.Exec("DELETE FROM simulation_samples").Error)
+	s.NoError(s.db.Exec("DELETE FROM simulation_item_counts").Error)
+	s.NoError(s.db.Exec("DELETE FROM simulation_node_results").Error)
+	s.NoError(s.db.Exec("DELETE FROM simulation_link_results").Error)
+	s.NoError(s.db.Exec("DELETE FROM simulation_runs").Error)
+}
+
+// createTestRun creates and persists a pending test run
+func (s *SimulationRunRepositoryTestSuite) createTestRun(pipelineID int) *models.SimulationRun {
+	run := models.NewSimulationRun(pipelineID, models.NewSimulationSettings(time.Minute, 10*time.Second, 42, 5))
+	s.NoError(s.repo.Create(s.T().Context(), run))
+	s.Greater(run.ID(), 0)
+	return run
+}
+
+// newTestResult creates a result with a sampled node and a transport link
+func newTestResult() *models.SimulationResult {
+	nodes := []*models.SimulationNodeStats{
+		models.NewSimulationNodeStats(1, 3, 60,
+			map[int]float64{1: 60}, map[int]float64{},
+			time.Minute, 0, 0, 0,
+			[]*models.SimulationSample{
+				models.NewSimulationSample(0, map[int]float64{}, map[int]float64{}, map[int]float64{}, map[int]float64{}),
+				models.NewSimulationSample(30*time.Second, map[int]float64{}, map[int]float64{1: 1}, map[int]float64{1: 31}, map[int]float64{}),
+			}),
+		models.NewSimulationNodeStats(2, 4, 29,
+			map[int]float64{2: 29}, map[int]float64{1: 30},
+			58*time.Second, 2*time.Second, 2*time.Second, 0, nil),
+	}
+	links := []*models.SimulationLinkStats{
+		models.NewSimulationLinkStats(1, 2, 7, 30, 60, false),
+	}
+	return models.NewSimulationResult(1.5, 0, nodes, links)
+}
+
+func (s *SimulationRunRepositoryTestSuite) TestCreateAndGet() {
+	s.SetupTest()
+	run := s.createTestRun(3)
+	s.Equal(models.SimulationStatusPending, run.Status())
+	s.False(run.CreatedAt().IsZero())
+
+	result, err := s.repo.Get(s.T().Context(), run.ID())
+	s.NoError(err)
+	s.NotNil(result)
+	s.Equal(3, result.PipelineID())
+	s.Equal(run.Settings(), result.Settings())
+	s.Equal(models.SimulationStatusPending, result.Status())
+	s.True(result.FinishedAt().IsZero())
+	s.Nil(result.Result())
+
+	missing, err := s.repo.Get(s.T().Context(), 999)
+	s.NoError(err)
+	s.Nil(missing)
+}
+
+func (s *SimulationRunRepositoryTestSuite) TestUpdate() {
+	s.SetupTest()
+	run := s.createTestRun(1)
+
+	run.Start()
+	s.NoError(s.repo.Update(s.T().Context(), run))
+	result, err := s.repo.Get(s.T().Context(), run.ID())
+	s.NoError(err)
+	s.Equal(models.SimulationStatusRunning, result.Status())
+
+	finishedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	run.Complete(newTestResult(), finishedAt)
+	s.NoError(s.repo.Update(s.T().Context(), run))
+	// Storing the outcome again replaces the statistics instead of adding to them
+	s.NoError(s.repo.Update(s.T().Context(), run))
+
+	result, err = s.repo.Get(s.T().Context(), run.ID())
+	s.NoError(err)
+	s.Equal(models.SimulationStatusCompleted, result.Status())
+	s.True(finishedAt.Equal(result.FinishedAt()))
+	s.Require().NotNil(result.Result())
+	s.Equal(1.5, result.Result().PowerDemand())
+
+	nodes := result.Result().Nodes()
+	s.Require().Len(nodes, 2)
+	s.Equal(1, nodes[0].NodeID())
+	s.Equal(3, nodes[0].FacilityID())
+	s.Equal(60, nodes[0].Cycles())
+	s.Equal(map[int]float64{1: 60}, nodes[0].Produced())
+	s.Empty(nodes[0].Consumed())
+	s.Equal(time.Minute, nodes[0].BusyTime())
+	s.Require().Len(nodes[0].Samples(), 2)
+	s.Equal(30*time.Second, nodes[0].Samples()[1].Time())
+	s.Equal(map[int]float64{1: 1}, nodes[0].Samples()[1].OutputBuffer())
+	s.Equal(map[int]float64{1: 31}, nodes[0].Samples()[1].Produced())
+	s.Empty(nodes[0].Samples()[1].Consumed())
+	s.Equal(map[int]float64{1: 30}, nodes[1].Consumed())
+	s.Equal(2*time.Second, nodes[1].StarvedTime())
+	s.Empty(nodes[1].Samples())
+
+	links := result.Result().Links()
+	s.Require().Len(links, 1)
+	s.Equal(7, links[0].TransportID())
+	s.Equal(30.0, links[0].Moved())
+
+	nonExistentRun := models.NewSimulationRunFromParams(999, 1, run.Settings(), models.SimulationStatusRunning, "", time.Time{}, time.Time{}, nil)
+	err = s.repo.Update(s.T().Context(), nonExistentRun)
+	s.Equal(gorm.ErrRecordNotFound, err)
+}
+
+func (s *SimulationRunRepositoryTestSuite) TestListAndDelete() {
+	s.SetupTest()
+	runs := []*models.SimulationRun{s.createTestRun(1), s.createTestRun(2), s.createTestRun(1)}
+	runs[0].Complete(newTestResult(), time.Now())
+	s.NoError(s.repo.Update(s.T().Context(), runs[0]))
+
+	results, err := s.repo.ListByPipeline(s.T().Context(), 1)
+	s.NoError(err)
+	s.Require().Len(results, 2)
+	s.Equal(runs[0].ID(), results[0].ID())
+	s.Equal(runs[2].ID(), results[1].ID())
+	// Listed runs keep their statistics but leave out the samples
+	s.Require().NotNil(results[0].Result())
+	nodes := results[0].Result().Nodes()
+	s.Require().Len(nodes, 2)
+	s.Equal(map[int]float64{1: 60}, nodes[0].Produced())
+	s.Empty(nodes[0].Samples())
+	s.Len(results[0].Result().Links(), 1)
+
+	results, err = s.repo.ListByPipeline(s.T().Context(), 3)
+	s.NoError(err)
+	s.Empty(results)
+
+	s.NoError(s.repo.Delete(s.T().Context(), runs[0].ID()))
+	missing, err := s.repo.Get(s.T().Context(), runs[0].ID())
+	s.NoError(err)
+	s.Nil(missing)
+
+	var nodeCount, sampleCount int64
+	s.NoError(s.db.Model(&entities.SimulationNodeResultEntity{}).Where("run_id = ?", runs[0].ID()).Count(&nodeCount).Error)
+	s.NoError(s.db.Model(&entities.SimulationSampleEntity{}).Count(&sampleCount).Error)
+	s.Equal(int64(0), nodeCount)
+	s.Equal(int64(0), sampleCount)
+
+	s.Equal(gorm.ErrRecordNotFound, s.repo.Delete(s.T().Context(), 999))
+}
+
+func (s *SimulationRunRepositoryTestSuite) TestFailUnfinished() {
+	s.SetupTest()
+	pending := s.createTestRun(1)
+	running := s.createTestRun(1)
+	running.Start()
+	s.NoError(s.repo.Update(s.T().Context(), running))
+	completed := s.createTestRun(1)
+	completedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	completed.Complete(newTestResult(), completedAt)
+	s.NoError(s.repo.Update(s.T().Context(), completed))
+
+	failedAt := time.Date(2026, 2, 3, 4, 5, 6, 0, time.UTC)
+	s.NoError(s.repo.FailUnfinished(s.T().Context(), "interrupted", failedAt))
+
+	for _, run := range []*models.SimulationRun{pending, running} {
+		result, err := s.repo.Get(s.T().Context(), run.ID())
+		s.NoError(err)
+		s.Equal(models.SimulationStatusFailed, result.Status())
+		s.Equal("interrupted", result.ErrorMessage())
+		s.True(failedAt.Equal(result.FinishedAt()))
+	}
+
+	result, err := s.repo.Get(s.T().Context(), completed.ID())
+	s.NoError(err)
+	s.Equal(models.SimulationStatusCompleted, result.Status())
+	s.Empty(result.ErrorMessage())
+	s.True(completedAt.Equal(result.FinishedAt()))
+	s.NotNil(result.Result())
+}
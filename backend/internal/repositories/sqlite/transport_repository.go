@@ -0,0 +1,102 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fasim/backend/internal/models"
+	"github.com/fasim/backend/internal/repositories"
+	"github.com/fasim/backend/internal/repositories/db"
+	"github.com/fasim/backend/internal/repositories/entities"
+	"gorm.io/gorm"
+)
+
+// TransportRepository implements the TransportRepository interface using SQLite with GORM
+type TransportRepository struct {
+	db *db.DB
+}
+
+// NewTransportRepository creates a new SQLite-backed transport tier repository
+func NewTransportRepository(db *db.DB) repositories.TransportRepository {
+	return &TransportRepository{db: db}
+}
+
+// Create stores a new transport tier
+func (r *TransportRepository) Create(ctx context.Context, transport *models.Transport) error {
+	entity := entities.TransportEntityFromModel(transport)
+	if err := r.db.WithContext(ctx).Create(entity).Error; err != nil {
+		return err
+	}
+	newTransport := entity.ToModel()
+	*transport = *newTransport
+	return nil
+}
+
+// Get retrieves a transport tier by ID
+func (r *TransportRepository) Get(ctx context.Context, id int) (*models.Transport, error) {
+	var entity entities.TransportEntity
+	if err := r.db.WithContext(ctx).First(&entity, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return entity.ToModel(), nil
+}
+
+// List retrieves all transport tiers
+func (r *TransportRepository) List(ctx context.Context) ([]*models.Transport, error) {
+	var entities []entities.TransportEntity
+	if err := r.db.WithContext(ctx).Find(&entities).Error; err != nil {
+		return nil, err
+	}
+
+	transports := make([]*models.Transport, len(entities))
+	for i, entity := range entities {
+		transports[i] = entity.ToModel()
+	}
+	return transports, nil
+}
+
+// Update updates an existing transport tier
+func (r *TransportRepository) Update(ctx context.Context, transport *models.Transport) error {
+	entity := entities.TransportEntityFromModel(transport)
+	result := r.db.WithContext(ctx).Model(&entities.TransportEntity{}).
+		Where("id = ?", transport.ID()).
+		Updates(map[string]interface{}{
+			"name":             entity.Name,
+			"description":      entity.Description,
+			"kind":             entity.Kind,
+			"items_per_second": entity.ItemsPerSecond,
+		})
+
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Delete removes a transport tier by ID. Transport tiers used by pipeline connections cannot be deleted.
+func (r *TransportRepository) Delete(ctx context.Context, id int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var users int64
+		if err := tx.Model(&entities.PipelineNodeConnectionEntity{}).Where("transport_id = ?", id).Count(&users).Error; err != nil {
+			return err
+		}
+		if users > 0 {
+			return fmt.Errorf("transport tier %d is used by %d pipeline connections: %w", id, users, repositories.ErrInUse)
+		}
+
+		result := tx.Delete(&entities.TransportEntity{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
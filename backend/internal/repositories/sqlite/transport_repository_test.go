@@ -0,0 +1,233 @@
+package sqlite
+
+import (
+	"testing"
+
+	"github.com/fasim/backend/internal/models"
+	"github.com/fasim/backend/internal/repositories"
+	"github.com/fasim/backend/internal/repositories/entities"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type TransportRepositoryTestSuite struct {
+	BaseSQLiteTestSuite
+	repo         *TransportRepository
+	itemRepo     *ItemRepository
+	facilityRepo *FacilityRepository
+	pipelineRepo *PipelineRepository
+}
+
+func TestTransportRepositorySuite(t *testing.T) {
+	suite.Run(t, new(TransportRepositoryTestSuite))
+}
+
+func (s *TransportRepositoryTestSuite) SetupSuite() {
+	s.SetupDockerAndDB(
+		&entities.ItemEntity{},
+		&entities.MachineTypeEntity{},
+		&entities.RecipeEntity{},
+		&entities.RecipeInputEntity{},
+		&entities.RecipeOutputEntity{},
+		&entities.FacilityEntity{},
+		&entities.InputRequirementEntity{},
+		&entities.OutputDefinitionEntity{},
+		&entities.TransportEntity{},
+		&entities.PipelineEntity{},
+		&entities.PipelineNodeEntity{},
+		&entities.PipelineNodeConnectionEntity{},
+	)
+	s.repo = &TransportRepository{db: s.db}
+	s.itemRepo = &ItemRepository{db: s.db}
+	s.facilityRepo = &FacilityRepository{db: s.db}
+	s.pipelineRepo = &PipelineRepository{db: s.db}
+}
+
+func (s *TransportRepositoryTestSuite) TearDownSuite() {
+	s.TearDownDocker()
+}
+
+func (s *TransportRepositoryTestSuite) SetupTest() {
+	s.NoError(s.db.Exec("DELETE FROM pipeline_node_connections").Error)
+	s.NoError(s.db.Exec("DELETE FROM pipeline_nodes").Error)
+	s.NoError(s.db.Exec("DELETE FROM pipelines").Error)
+	s.NoError(s.db.Exec("DELETE FROM transports").Error)
+	s.NoError(s.db.Exec("DELETE FROM facilities").Error)
+	s.NoError(s.db.Exec("DELETE FROM input_requirements").Error)
+	s.NoError(s.db.Exec("DELETE FROM output_definitions").Error)
+	s.NoError(s.db.Exec("DELETE FROM items").Error)
+}
+
+// createTestTransport creates and persists a test transport tier
+func (s *TransportRepositoryTestSuite) createTestTransport(name string, kind models.ItemKind, itemsPerSecond float64) *models.Transport {
+	transport := models.NewTransport(name, "Test Description for "+name, kind, itemsPerSecond)
+	err := s.repo.Create(s.T().Context(), transport)
+	s.NoError(err)
+	s.Greater(transport.ID(), 0)
+	return transport
+}
+
+func (s *TransportRepositoryTestSuite) TestCreate() {
+	testCases := []struct {
+		name        string
+		setup       func()
+		input       *models.Transport
+		expectError bool
+		errorMsg    string
+	}{
+		{
+			name:        "creates a new transport tier",
+			input:       models.NewTransport("Yellow Belt", "Test Description", models.ItemKindSolid, 15),
+			expectError: false,
+		},
+		{
+			name:        "creates a pipe for fluids",
+			input:       models.NewTransport("Pipe Mk1", "Test Description", models.ItemKindFluid, 300),
+			expectError: false,
+		},
+		{
+			name: "enforces unique name constraint",
+			setup: func() {
+				s.createTestTransport("Yellow Belt", models.ItemKindSolid, 15)
+			},
+			input:       models.NewTransport("Yellow Belt", "Different Description", models.ItemKindSolid, 30),
+			expectError: true,
+			errorMsg:    "UNIQUE constraint failed",
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			s.SetupTest()
+
+			if tc.setup != nil {
+				tc.setup()
+			}
+
+			err := s.repo.Create(s.T().Context(), tc.input)
+
+			if tc.expectError {
+				s.Error(err)
+				s.Contains(err.Error(), tc.errorMsg)
+			} else {
+				s.NoError(err)
+				s.Greater(tc.input.ID(), 0)
+
+				var entity entities.TransportEntity
+				s.NoError(s.db.First(&entity, tc.input.ID()).Error)
+				s.Equal(tc.input.Name(), entity.Name)
+				s.Equal(tc.input.Description(), entity.Description)
+				s.Equal(string(tc.input.Kind()), entity.Kind)
+				s.Equal(tc.input.ItemsPerSecond(), entity.ItemsPerSecond)
+			}
+		})
+	}
+}
+
+func (s *TransportRepositoryTestSuite) TestGetAndList() {
+	s.SetupTest()
+	transports := []*models.Transport{
+		s.createTestTransport("Yellow Belt", models.ItemKindSolid, 15),
+		s.createTestTransport("Pipe", models.ItemKindFluid, 1200),
+	}
+
+	result, err := s.repo.Get(s.T().Context(), transports[1].ID())
+	s.NoError(err)
+	s.NotNil(result)
+	s.Equal(transports[1].Name(), result.Name())
+	s.Equal(models.ItemKindFluid, result.Kind())
+	s.Equal(transports[1].ItemsPerSecond(), result.ItemsPerSecond())
+
+	missing, err := s.repo.Get(s.T().Context(), 999)
+	s.NoError(err)
+	s.Nil(missing)
+
+	results, err := s.repo.List(s.T().Context())
+	s.NoError(err)
+	s.Len(results, len(transports))
+	for i, result := range results {
+		s.Equal(transports[i].ID(), result.ID())
+		s.Equal(transports[i].Name(), result.Name())
+	}
+}
+
+func (s *TransportRepositoryTestSuite) TestUpdate() {
+	s.SetupTest()
+	transport := s.createTestTransport("Belt", models.ItemKindSolid, 15)
+
+	updatedTransport := models.NewTransportFromParams(transport.ID(), "Red Belt", "Updated Description", models.ItemKindSolid, 30)
+	s.NoError(s.repo.Update(s.T().Context(), updatedTransport))
+
+	result, err := s.repo.Get(s.T().Context(), transport.ID())
+	s.NoError(err)
+	s.Equal(updatedTransport.Name(), result.Name())
+	s.Equal(updatedTransport.Description(), result.Description())
+	s.Equal(updatedTransport.ItemsPerSecond(), result.ItemsPerSecond())
+
+	nonExistentTransport := models.NewTransportFromParams(999, "Non-existent", "Non-existent", models.ItemKindSolid, 1)
+	err = s.repo.Update(s.T().Context(), nonExistentTransport)
+	s.Equal(gorm.ErrRecordNotFound, err)
+}
+
+func (s *TransportRepositoryTestSuite) TestDelete() {
+	testCases := []struct {
+		name      string
+		setupFunc func() int
+		expectErr error
+	}{
+		{
+			name: "successfully deletes an unused transport tier",
+			setupFunc: func() int {
+				return s.createTestTransport("Yellow Belt", models.ItemKindSolid, 15).ID()
+			},
+		},
+		{
+			name: "rejects transport tiers used by pipeline connections",
+			setupFunc: func() int {
+				transport := s.createTestTransport("Yellow Belt", models.ItemKindSolid, 15)
+				ore := models.NewItem("Ore", "", models.ItemKindSolid)
+				s.NoError(s.itemRepo.Create(s.T().Context(), ore))
+				miner := models.NewFacility("Miner", "", 1000)
+				miner.AddOutputDefinition(models.NewOutputDefinition(ore, 1))
+				s.NoError(s.facilityRepo.Create(s.T().Context(), miner))
+				furnace := models.NewFacility("Furnace", "", 1000)
+				furnace.AddInputRequirement(models.NewInputRequirement(ore, 1))
+				s.NoError(s.facilityRepo.Create(s.T().Context(), furnace))
+
+				pipeline := models.NewPipeline("Smelting", "")
+				minerNode := models.NewPipelineNode(miner, 1, 1)
+				pipeline.AddNode(minerNode)
+				furnaceNode := models.NewPipelineNode(furnace, 1, 1)
+				pipeline.AddNode(furnaceNode)
+				minerNode.AddConnection(models.NewPipelineConnection(furnaceNode.ID(), ore, 0, 0, 0, transport))
+				s.NoError(s.pipelineRepo.Create(s.T().Context(), pipeline))
+				return transport.ID()
+			},
+			expectErr: repositories.ErrInUse,
+		},
+		{
+			name:      "returns error when ID does not exist",
+			setupFunc: func() int { return 999 },
+			expectErr: gorm.ErrRecordNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			s.SetupTest()
+
+			id := tc.setupFunc()
+			err := s.repo.Delete(s.T().Context(), id)
+
+			if tc.expectErr != nil {
+				s.ErrorIs(err, tc.expectErr)
+			} else {
+				s.NoError(err)
+
+				var count int64
+				s.NoError(s.db.Model(&entities.TransportEntity{}).Where("id = ?", id).Count(&count).Error)
+				s.Equal(int64(0), count)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package runner
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// PlaybackState is the pace of a live run
+type PlaybackState struct {
+	Paused bool
+	// Speed is the number of seconds of simulated time played per second
+	Speed float64
+}
+
+// Playback paces a live run, so that its ticks are published as simulated time passes
+// at the chosen speed instead of as fast as the simulation can compute them
+type Playback struct {
+	mu    sync.Mutex
+	state PlaybackState
+	// changed is closed and replaced whenever the state changes, waking waiting runs
+	changed chan struct{}
+}
+
+// NewPlayback creates a playback at the given speed, which must be positive
+func NewPlayback(speed float64, paused bool) *Playback {
+	return &Playback{
+		state:   PlaybackState{Paused: paused, Speed: speed},
+		changed: make(chan struct{}),
+	}
+}
+
+// State returns the current pace
+func (p *Playback) State() PlaybackState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.state
+}
+
+// Set changes the pace and wakes the run waiting on it
+func (p *Playback) Set(state PlaybackState) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.state = state
+	close(p.changed)
+	p.changed = make(chan struct{})
+}
+
+// Wait blocks until the given span of simulated time has been played. Time does not pass
+// while the playback is paused, and changes of the speed apply to the rest of the span.
+func (p *Playback) Wait(ctx context.Context, simulated time.Duration) error {
+	for {
+		p.mu.Lock()
+		state, changed := p.state, p.changed
+		p.mu.Unlock()
+
+		if state.Paused {
+			select {
+			case <-changed:
+				continue
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		if simulated <= 0 {
+			return nil
+		}
+
+		started := time.Now()
+		timer := time.NewTimer(time.Duration(float64(simulated) / state.Speed))
+		select {
+		case <-timer.C:
+			return nil
+		case <-changed:
+			timer.Stop()
+			simulated -= time.Duration(float64(time.Since(started)) * state.Speed)
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package runner
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlaybackWait(t *testing.T) {
+	playback := NewPlayback(1000, false)
+
+	// A second of simulated time takes a millisecond at this speed
+	started := time.Now()
+	require.NoError(t, playback.Wait(context.Background(), time.Second))
+	assert.GreaterOrEqual(t, time.Since(started), time.Millisecond)
+
+	// Paused playback waits even for no simulated time until resumed
+	playback.Set(PlaybackState{Paused: true, Speed: 1000})
+	waited := make(chan error, 1)
+	go func() {
+		waited <- playback.Wait(context.Background(), 0)
+	}()
+	select {
+	case <-waited:
+		t.Fatal("wait returned while paused")
+	case <-time.After(20 * time.Millisecond):
+	}
+	playback.Set(PlaybackState{Paused: false, Speed: 1000})
+	require.NoError(t, <-waited)
+
+	// Cancelling the context stops the wait
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	playback.Set(PlaybackState{Paused: true, Speed: 1000})
+	assert.ErrorIs(t, playback.Wait(ctx, time.Second), context.Canceled)
+}
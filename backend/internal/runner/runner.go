@@ -0,0 +1,316 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/fasim/backend/internal/models"
+	"github.com/fasim/backend/internal/repositories"
+	"github.com/fasim/backend/internal/simulation"
+)
+
+var (
+	// ErrPipelineNotFound is returned when starting a run of a pipeline that does not exist
+	ErrPipelineNotFound = errors.New("pipeline not found")
+	// ErrInvalidSettings is returned when the simulation rejects the pipeline or the settings of a run
+	ErrInvalidSettings = errors.New("invalid simulation settings")
+	// ErrNotExecuting is returned when controlling a run that is not executing
+	ErrNotExecuting = errors.New("simulation run is not executing")
+	// ErrNotLive is returned when changing the playback of a run that was not started live
+	ErrNotLive = errors.New("simulation run is not live")
+)
+
+// interruptedMessage explains the failure of runs that were still going when the process stopped
+const interruptedMessage = "run was interrupted by a server restart"
+
+// subscriberBuffer is the number of events a subscriber can fall behind before it misses
+// events, so that slow subscribers never hold back a run
+const subscriberBuffer = 64
+
+// Event is an update published to the subscribers of an executing run
+type Event struct {
+	// Tick is set for the state of the pipeline at a sample time
+	Tick *simulation.Tick
+	// Playback is set when the pace of a live run changes
+	Playback *PlaybackState
+}
+
+// job tracks a run executing in the background
+type job struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	// playback paces live runs and is nil for runs executing as fast as possible
+	playback *Playback
+	// lastTick is the simulated time of the previous tick
+	lastTick time.Duration
+	// subscribers is guarded by the mutex of the runner
+	subscribers map[chan Event]struct{}
+	// done is closed once the outcome of the run has been stored
+	done chan struct{}
+}
+
+// Runner executes simulation runs in background goroutines and stores their outcome. Runs
+// are detached from the requests starting them and only stop when they finish, are
+// cancelled or the runner shuts down.
+type Runner struct {
+	pipelines repositories.PipelineRepository
+	runs      repositories.SimulationRunRepository
+	// ctx is the parent of every run and is cancelled on shutdown
+	ctx    context.Context
+	cancel context.CancelFunc
+	mu     sync.Mutex
+	jobs   map[int]*job
+	wg     sync.WaitGroup
+}
+
+// New creates a runner storing runs of the pipelines in the given repository
+func New(pipelines repositories.PipelineRepository, runs repositories.SimulationRunRepository) *Runner {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Runner{
+		pipelines: pipelines,
+		runs:      runs,
+		ctx:       ctx,
+		cancel:    cancel,
+		jobs:      make(map[int]*job),
+	}
+}
+
+// Recover marks runs that a previous process left pending or running as failed, since
+// their goroutines no longer exist
+func (r *Runner) Recover(ctx context.Context) error {
+	return r.runs.FailUnfinished(ctx, interruptedMessage, time.Now())
+}
+
+// Start stores a pending run of the pipeline and executes it in the background. The
+// pipeline and settings are checked before the run is stored, so that runs are only
+// rejected up front.
+//
+// A run given a playback is live: it publishes a tick every time step of the settings,
+// paced by the playback. Other runs publish their ticks as fast as they are computed.
+func (r *Runner) Start(ctx context.Context, pipelineID int, settings models.SimulationSettings, playback *Playback) (*models.SimulationRun, error) {
+	pipeline, err := r.pipelines.Get(ctx, pipelineID)
+	if err != nil {
+		return nil, err
+	}
+	if pipeline == nil {
+		return nil, fmt.Errorf("pipeline %d: %w", pipelineID, ErrPipelineNotFound)
+	}
+	if playback != nil {
+		if settings.TimeStep() <= 0 {
+			return nil, fmt.Errorf("%w: live runs need a positive time step", ErrInvalidSettings)
+		}
+		if playback.State().Speed <= 0 {
+			return nil, fmt.Errorf("%w: playback speed must be positive", ErrInvalidSettings)
+		}
+	}
+
+	j := &job{
+		playback:    playback,
+		subscribers: make(map[chan Event]struct{}),
+		done:        make(chan struct{}),
+	}
+	engine, err := simulation.New(pipeline, simulation.Config{
+		Duration:       settings.Duration(),
+		BufferCapacity: settings.BufferCapacity(),
+		SampleInterval: settings.TimeStep(),
+		Seed:           settings.Seed(),
+		OnTick: func(tick *simulation.Tick) error {
+			return r.tick(j, tick)
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSettings, err)
+	}
+
+	// Runs are not stored once the runner is shutting down, since nothing would execute them
+	if r.ctx.Err() != nil {
+		return nil, r.ctx.Err()
+	}
+	run := models.NewSimulationRun(pipelineID, settings)
+	if err := r.runs.Create(ctx, run); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.ctx.Err() != nil {
+		run.Cancel(time.Now())
+		_ = r.runs.Update(ctx, run)
+		return nil, r.ctx.Err()
+	}
+	j.ctx, j.cancel = context.WithCancel(r.ctx)
+	r.jobs[run.ID()] = j
+	r.wg.Add(1)
+
+	// The goroutine owns a copy so that the caller can use the returned run freely
+	background := *run
+	go r.execute(j, &background, engine)
+	return run, nil
+}
+
+// execute runs the simulation and stores its outcome. The outcome is stored without the
+// run context, which is already cancelled for cancelled runs. Subscribers are let go once
+// the outcome is stored.
+func (r *Runner) execute(j *job, run *models.SimulationRun, engine *simulation.Engine) {
+	defer r.wg.Done()
+	defer close(j.done)
+	defer func() {
+		r.mu.Lock()
+		delete(r.jobs, run.ID())
+		for events := range j.subscribers {
+			close(events)
+		}
+		j.subscribers = nil
+		r.mu.Unlock()
+		j.cancel()
+	}()
+
+	store := context.Background()
+	run.Start()
+	if err := r.runs.Update(store, run); err != nil {
+		run.Fail(fmt.Sprintf("failed to store the start: %v", err), time.Now())
+		_ = r.runs.Update(store, run)
+		return
+	}
+
+	result, err := engine.Run(j.ctx)
+	switch {
+	case err != nil && j.ctx.Err() != nil:
+		run.Cancel(time.Now())
+	case err != nil:
+		run.Fail(err.Error(), time.Now())
+	default:
+		run.Complete(result.ToModel(), time.Now())
+	}
+	if err := r.runs.Update(store, run); err != nil {
+		run.Fail(fmt.Sprintf("failed to store the outcome: %v", err), time.Now())
+		_ = r.runs.Update(store, run)
+	}
+}
+
+// tick paces a tick of a live run and publishes it to the subscribers of the run
+func (r *Runner) tick(j *job, tick *simulation.Tick) error {
+	if j.playback != nil {
+		if err := j.playback.Wait(j.ctx, tick.Time-j.lastTick); err != nil {
+			return err
+		}
+	}
+	j.lastTick = tick.Time
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.publish(j, Event{Tick: tick})
+	return nil
+}
+
+// publish sends an event to every subscriber of the job with room for it. The caller must
+// hold the mutex of the runner.
+func (r *Runner) publish(j *job, event Event) {
+	for events := range j.subscribers {
+		select {
+		case events <- event:
+		default:
+		}
+	}
+}
+
+// Subscribe returns the events of the executing run with the given ID, starting with the
+// playback of live runs, and a function to stop receiving them. The channel is closed
+// once the outcome of the run has been stored. It reports false if the run is not
+// executing.
+func (r *Runner) Subscribe(id int) (<-chan Event, func(), bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	j, ok := r.jobs[id]
+	if !ok {
+		return nil, nil, false
+	}
+
+	events := make(chan Event, subscriberBuffer)
+	if j.playback != nil {
+		state := j.playback.State()
+		events <- Event{Playback: &state}
+	}
+	j.subscribers[events] = struct{}{}
+
+	unsubscribe := func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if _, ok := j.subscribers[events]; ok {
+			delete(j.subscribers, events)
+			close(events)
+		}
+	}
+	return events, unsubscribe, true
+}
+
+// SetPlayback pauses or resumes the live run with the given ID, or changes its speed,
+// leaving the settings given as nil unchanged
+func (r *Runner) SetPlayback(id int, paused *bool, speed *float64) (PlaybackState, error) {
+	if speed != nil && *speed <= 0 {
+		return PlaybackState{}, fmt.Errorf("%w: playback speed must be positive", ErrInvalidSettings)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	j, ok := r.jobs[id]
+	if !ok {
+		return PlaybackState{}, fmt.Errorf("run %d: %w", id, ErrNotExecuting)
+	}
+	if j.playback == nil {
+		return PlaybackState{}, fmt.Errorf("run %d: %w", id, ErrNotLive)
+	}
+
+	state := j.playback.State()
+	if paused != nil {
+		state.Paused = *paused
+	}
+	if speed != nil {
+		state.Speed = *speed
+	}
+	j.playback.Set(state)
+	r.publish(j, Event{Playback: &state})
+	return state, nil
+}
+
+// Cancel stops the run with the given ID and waits until its outcome has been stored.
+// It reports false if the run is not executing.
+func (r *Runner) Cancel(ctx context.Context, id int) (bool, error) {
+	r.mu.Lock()
+	j, ok := r.jobs[id]
+	r.mu.Unlock()
+	if !ok {
+		return false, nil
+	}
+
+	j.cancel()
+	select {
+	case <-j.done:
+		return true, nil
+	case <-ctx.Done():
+		return true, ctx.Err()
+	}
+}
+
+// Shutdown cancels every executing run and waits until their outcome has been stored or
+// ctx is done
+func (r *Runner) Shutdown(ctx context.Context) error {
+	r.mu.Lock()
+	r.cancel()
+	r.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,326 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fasim/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// pipelineStore serves a fixed set of pipelines
+type pipelineStore struct {
+	pipelines map[int]*models.Pipeline
+}
+
+func (s *pipelineStore) Create(ctx context.Context, pipeline *models.Pipeline) error {
+	return nil
+}
+
+func (s *pipelineStore) Get(ctx context.Context, id int) (*models.Pipeline, error) {
+	return s.pipelines[id], nil
+}
+
+func (s *pipelineStore) List(ctx context.Context) ([]*models.Pipeline, error) {
+	return nil, nil
+}
+
+func (s *pipelineStore) Update(ctx context.Context, pipeline *models.Pipeline) error {
+	return nil
+}
+
+func (s *pipelineStore) Delete(ctx context.Context, id int) error {
+	return nil
+}
+
+// runStore keeps simulation runs in memory
+type runStore struct {
+	mu   sync.Mutex
+	runs map[int]models.SimulationRun
+	// failedUpdates is the number of updates to fail before storing them again
+	failedUpdates int
+}
+
+func newRunStore() *runStore {
+	return &runStore{runs: make(map[int]models.SimulationRun)}
+}
+
+func (s *runStore) Create(ctx context.Context, run *models.SimulationRun) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	*run = *models.NewSimulationRunFromParams(len(s.runs)+1, run.PipelineID(), run.Settings(), run.Status(), run.ErrorMessage(), time.Now(), run.FinishedAt(), run.Result())
+	s.runs[run.ID()] = *run
+	return nil
+}
+
+func (s *runStore) Get(ctx context.Context, id int) (*models.SimulationRun, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	run, ok := s.runs[id]
+	if !ok {
+		return nil, nil
+	}
+	return &run, nil
+}
+
+func (s *runStore) ListByPipeline(ctx context.Context, pipelineID int) ([]*models.SimulationRun, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var runs []*models.SimulationRun
+	for id := 1; id <= len(s.runs); id++ {
+		if run := s.runs[id]; run.PipelineID() == pipelineID {
+			runs = append(runs, &run)
+		}
+	}
+	return runs, nil
+}
+
+func (s *runStore) Update(ctx context.Context, run *models.SimulationRun) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failedUpdates > 0 {
+		s.failedUpdates--
+		return errors.New("storage is unavailable")
+	}
+	if _, ok := s.runs[run.ID()]; !ok {
+		return gorm.ErrRecordNotFound
+	}
+	s.runs[run.ID()] = *run
+	return nil
+}
+
+func (s *runStore) FailUnfinished(ctx context.Context, message string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, run := range s.runs {
+		if !run.Status().IsFinished() {
+			run.Fail(message, at)
+			s.runs[id] = run
+		}
+	}
+	return nil
+}
+
+func (s *runStore) Delete(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.runs, id)
+	return nil
+}
+
+// newTestPipeline connects a miner yielding an ore per second to a furnace
+func newTestPipeline() *models.Pipeline {
+	ore := models.NewItemFromParams(1, "Ore", "", models.ItemKindSolid)
+	plate := models.NewItemFromParams(2, "Plate", "", models.ItemKindSolid)
+	miner := models.NewFacility("Miner", "", 1000)
+	miner.AddOutputDefinition(models.NewOutputDefinition(ore, 1))
+	furnace := models.NewFacility("Furnace", "", 2000)
+	furnace.AddInputRequirement(models.NewInputRequirement(ore, 1))
+	furnace.AddOutputDefinition(models.NewOutputDefinition(plate, 1))
+
+	pipeline := models.NewPipelineFromParams(1, "Smelting", "", make(map[int]*models.PipelineNode))
+	minerNode := models.NewPipelineNode(miner, 1, 1)
+	pipeline.AddNode(minerNode)
+	furnaceNode := models.NewPipelineNode(furnace, 1, 1)
+	pipeline.AddNode(furnaceNode)
+	minerNode.AddNextNodeID(furnaceNode.ID())
+	return pipeline
+}
+
+// waitFinished polls the store until the run has finished
+func waitFinished(t *testing.T, runs *runStore, id int) *models.SimulationRun {
+	t.Helper()
+	var run *models.SimulationRun
+	require.Eventually(t, func() bool {
+		run, _ = runs.Get(context.Background(), id)
+		return run.Status().IsFinished()
+	}, 5*time.Second, 10*time.Millisecond)
+	return run
+}
+
+func TestRunnerCompletesRuns(t *testing.T) {
+	runs := newRunStore()
+	r := New(&pipelineStore{pipelines: map[int]*models.Pipeline{1: newTestPipeline()}}, runs)
+
+	settings := models.NewSimulationSettings(10*time.Second, 5*time.Second, 1, 0)
+	run, err := r.Start(context.Background(), 1, settings, nil)
+	require.NoError(t, err)
+	assert.Equal(t, models.SimulationStatusPending, run.Status())
+
+	finished := waitFinished(t, runs, run.ID())
+	assert.Equal(t, models.SimulationStatusCompleted, finished.Status())
+	assert.False(t, finished.FinishedAt().IsZero())
+	require.NotNil(t, finished.Result())
+	nodes := finished.Result().Nodes()
+	require.Len(t, nodes, 2)
+	assert.Equal(t, 10, nodes[0].Cycles())
+	assert.Equal(t, 4, nodes[1].Cycles())
+	// Samples are taken at 0, 5 and 10 seconds
+	assert.Len(t, nodes[0].Samples(), 3)
+
+	cancelled, err := r.Cancel(context.Background(), run.ID())
+	require.NoError(t, err)
+	assert.False(t, cancelled)
+	require.NoError(t, r.Shutdown(context.Background()))
+}
+
+func TestRunnerCancelsRuns(t *testing.T) {
+	runs := newRunStore()
+	r := New(&pipelineStore{pipelines: map[int]*models.Pipeline{1: newTestPipeline()}}, runs)
+
+	settings := models.NewSimulationSettings(1000000*time.Hour, 0, 1, 0)
+	first, err := r.Start(context.Background(), 1, settings, nil)
+	require.NoError(t, err)
+	second, err := r.Start(context.Background(), 1, settings, nil)
+	require.NoError(t, err)
+
+	cancelled, err := r.Cancel(context.Background(), first.ID())
+	require.NoError(t, err)
+	assert.True(t, cancelled)
+	run, err := runs.Get(context.Background(), first.ID())
+	require.NoError(t, err)
+	assert.Equal(t, models.SimulationStatusCancelled, run.Status())
+	assert.Nil(t, run.Result())
+
+	require.NoError(t, r.Shutdown(context.Background()))
+	run, err = runs.Get(context.Background(), second.ID())
+	require.NoError(t, err)
+	assert.Equal(t, models.SimulationStatusCancelled, run.Status())
+}
+
+func TestRunnerRejectsInvalidRuns(t *testing.T) {
+	runs := newRunStore()
+	r := New(&pipelineStore{pipelines: map[int]*models.Pipeline{1: newTestPipeline()}}, runs)
+
+	_, err := r.Start(context.Background(), 2, models.NewSimulationSettings(time.Second, 0, 0, 0), nil)
+	assert.ErrorIs(t, err, ErrPipelineNotFound)
+
+	_, err = r.Start(context.Background(), 1, models.NewSimulationSettings(0, 0, 0, 0), nil)
+	assert.ErrorIs(t, err, ErrInvalidSettings)
+
+	assert.Empty(t, runs.runs)
+}
+
+func TestRunnerRejectsRunsAfterShutdown(t *testing.T) {
+	runs := newRunStore()
+	r := New(&pipelineStore{pipelines: map[int]*models.Pipeline{1: newTestPipeline()}}, runs)
+	require.NoError(t, r.Shutdown(context.Background()))
+
+	_, err := r.Start(context.Background(), 1, models.NewSimulationSettings(time.Second, 0, 0, 0), nil)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, runs.runs)
+}
+
+func TestRunnerFailsRunsWhoseStartIsNotStored(t *testing.T) {
+	runs := newRunStore()
+	runs.failedUpdates = 1
+	r := New(&pipelineStore{pipelines: map[int]*models.Pipeline{1: newTestPipeline()}}, runs)
+
+	run, err := r.Start(context.Background(), 1, models.NewSimulationSettings(time.Second, 0, 0, 0), nil)
+	require.NoError(t, err)
+
+	finished := waitFinished(t, runs, run.ID())
+	assert.Equal(t, models.SimulationStatusFailed, finished.Status())
+	assert.Contains(t, finished.ErrorMessage(), "storage is unavailable")
+	assert.Nil(t, finished.Result())
+	require.NoError(t, r.Shutdown(context.Background()))
+}
+
+func TestRunnerRecoversInterruptedRuns(t *testing.T) {
+	runs := newRunStore()
+	settings := models.NewSimulationSettings(time.Second, 0, 0, 0)
+	interrupted := models.NewSimulationRun(1, settings)
+	interrupted.Start()
+	require.NoError(t, runs.Create(context.Background(), interrupted))
+	completed := models.NewSimulationRun(1, settings)
+	completed.Complete(models.NewSimulationResult(0, 0, nil, nil), time.Now())
+	require.NoError(t, runs.Create(context.Background(), completed))
+
+	r := New(&pipelineStore{}, runs)
+	require.NoError(t, r.Recover(context.Background()))
+
+	run, err := runs.Get(context.Background(), interrupted.ID())
+	require.NoError(t, err)
+	assert.Equal(t, models.SimulationStatusFailed, run.Status())
+	assert.NotEmpty(t, run.ErrorMessage())
+
+	run, err = runs.Get(context.Background(), completed.ID())
+	require.NoError(t, err)
+	assert.Equal(t, models.SimulationStatusCompleted, run.Status())
+}
+
+func TestRunnerStreamsLiveRuns(t *testing.T) {
+	runs := newRunStore()
+	r := New(&pipelineStore{pipelines: map[int]*models.Pipeline{1: newTestPipeline()}}, runs)
+
+	settings := models.NewSimulationSettings(10*time.Second, 5*time.Second, 1, 0)
+	_, err := r.Start(context.Background(), 1, settings, NewPlayback(1000, false))
+	require.NoError(t, err)
+	_, err = r.Start(context.Background(), 1, models.NewSimulationSettings(10*time.Second, 0, 1, 0), NewPlayback(1, false))
+	assert.ErrorIs(t, err, ErrInvalidSettings)
+	_, err = r.Start(context.Background(), 1, settings, NewPlayback(0, false))
+	assert.ErrorIs(t, err, ErrInvalidSettings)
+
+	run, err := r.Start(context.Background(), 1, settings, NewPlayback(1000, true))
+	require.NoError(t, err)
+	events, unsubscribe, ok := r.Subscribe(run.ID())
+	require.True(t, ok)
+	defer unsubscribe()
+
+	event := <-events
+	require.NotNil(t, event.Playback)
+	assert.True(t, event.Playback.Paused)
+
+	// The paused run publishes nothing until it is resumed
+	select {
+	case event := <-events:
+		t.Fatalf("unexpected event while paused: %+v", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	speed := 500.0
+	paused := false
+	state, err := r.SetPlayback(run.ID(), &paused, &speed)
+	require.NoError(t, err)
+	assert.Equal(t, PlaybackState{Paused: false, Speed: 500}, state)
+
+	var times []time.Duration
+	for event := range events {
+		if event.Playback != nil {
+			assert.Equal(t, state, *event.Playback)
+			continue
+		}
+		require.Len(t, event.Tick.Nodes, 2)
+		times = append(times, event.Tick.Time)
+	}
+	assert.Equal(t, []time.Duration{0, 5 * time.Second, 10 * time.Second}, times)
+	assert.Equal(t, models.SimulationStatusCompleted, waitFinished(t, runs, run.ID()).Status())
+
+	_, _, ok = r.Subscribe(run.ID())
+	assert.False(t, ok)
+	_, err = r.SetPlayback(run.ID(), &paused, nil)
+	assert.ErrorIs(t, err, ErrNotExecuting)
+	require.NoError(t, r.Shutdown(context.Background()))
+}
+
+func TestRunnerRejectsPlaybackOfRunsNotLive(t *testing.T) {
+	runs := newRunStore()
+	r := New(&pipelineStore{pipelines: map[int]*models.Pipeline{1: newTestPipeline()}}, runs)
+
+	run, err := r.Start(context.Background(), 1, models.NewSimulationSettings(1000000*time.Hour, 0, 1, 0), nil)
+	require.NoError(t, err)
+
+	paused := true
+	_, err = r.SetPlayback(run.ID(), &paused, nil)
+	assert.ErrorIs(t, err, ErrNotLive)
+	speed := -1.0
+	_, err = r.SetPlayback(run.ID(), nil, &speed)
+	assert.ErrorIs(t, err, ErrInvalidSettings)
+
+	require.NoError(t, r.Shutdown(context.Background()))
+}
@@ -0,0 +1,66 @@
+package simulation
+
+import (
+	"container/heap"
+	"time"
+)
+
+type eventKind int
+
+const (
+	// eventComplete marks the completion of a production cycle of a node
+	eventComplete eventKind = iota
+	// eventRetry lets a node retry delivering outputs held back by a throughput limit
+	eventRetry
+)
+
+// event is a scheduled change of a node
+type event struct {
+	kind eventKind
+	time time.Duration
+	seq  int
+	node *nodeState
+}
+
+// eventQueue orders events by time, falling back to scheduling order so that runs are deterministic
+type eventQueue []*event
+
+func (q eventQueue) Len() int {
+	return len(q)
+}
+
+func (q eventQueue) Less(i, j int) bool {
+	if q[i].time != q[j].time {
+		return q[i].time < q[j].time
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q eventQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+}
+
+func (q *eventQueue) Push(x any) {
+	*q = append(*q, x.(*event))
+}
+
+func (q *eventQueue) Pop() any {
+	old := *q
+	n := len(old)
+	ev := old[n-1]
+	old[n-1] = nil
+	*q = old[:n-1]
+	return ev
+}
+
+func (q *eventQueue) push(ev *event) {
+	heap.Push(q, ev)
+}
+
+func (q *eventQueue) pop() *event {
+	return heap.Pop(q).(*event)
+}
+
+func (q eventQueue) peek() *event {
+	return q[0]
+}
@@ -0,0 +1,885 @@
+package simulation
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sort"
+	"time"
+
+	"github.com/fasim/backend/internal/models"
+	"github.com/fasim/backend/internal/power"
+)
+
+// cancellationCheckInterval is the number of processed events between context checks
+const cancellationCheckInterval = 1024
+
+// epsilon absorbs floating point error accumulated by fractional quantities of fluids
+const epsilon = 1e-9
+
+// Config controls a simulation run
+type Config struct {
+	// Duration is the simulated time horizon
+	Duration time.Duration
+	// BufferCapacity limits how many units of each input item a node can hold unless the
+	// node sets its own input buffer. Zero means unlimited.
+	BufferCapacity int
+	// SampleInterval is the time between samples of the buffers of every node, starting
+	// at zero. Zero takes no samples.
+	SampleInterval time.Duration
+	// Seed initializes the random number generator deciding the outcome of outputs with a
+	// probability, so that runs with the same seed are identical
+	Seed uint64
+	// OnTick, if set, is called with the state of the pipeline at every sample time. An
+	// error stops the run and is returned by Run.
+	OnTick func(*Tick) error
+}
+
+// NodeResult holds the statistics collected for a single pipeline node
+type NodeResult struct {
+	NodeID     int
+	FacilityID int
+	// Cycles is the number of completed production cycles
+	Cycles int
+	// Produced and Consumed are keyed by item ID
+	Produced map[int]float64
+	Consumed map[int]float64
+	// BusyTime is the time spent processing; IdleTime is the rest of the horizon,
+	// which is further split into time waiting for inputs or power (StarvedTime) and time
+	// waiting for downstream nodes to accept outputs (BlockedTime). For nodes with
+	// several machines, the times are averaged over the machines.
+	BusyTime    time.Duration
+	IdleTime    time.Duration
+	StarvedTime time.Duration
+	BlockedTime time.Duration
+	// Samples records the buffers and counters at every sample interval
+	Samples []*BufferSample
+}
+
+// BufferSample is the fill of the buffers of a node at a point in time, along with the
+// units it has produced and consumed so far
+type BufferSample struct {
+	Time time.Duration
+	// InputBuffer holds the received input items and OutputBuffer the finished outputs
+	// waiting for delivery, both keyed by item ID
+	InputBuffer  map[int]float64
+	OutputBuffer map[int]float64
+	// Produced and Consumed are the totals since the start of the run, keyed by item ID
+	Produced map[int]float64
+	Consumed map[int]float64
+}
+
+// NodeStatus describes what the machines of a node are doing at a tick
+type NodeStatus string
+
+const (
+	// NodeStatusWorking means at least one machine is in a cycle. Sources and sinks without
+	// a rate limit are always working.
+	NodeStatusWorking NodeStatus = "working"
+	// NodeStatusStarved means every machine waits for inputs or power
+	NodeStatusStarved NodeStatus = "starved"
+	// NodeStatusBlocked means every machine waits for downstream nodes to accept outputs
+	NodeStatusBlocked NodeStatus = "blocked"
+)
+
+// Tick is the state of the pipeline at a sample time, reported while the run is in progress
+type Tick struct {
+	Time time.Duration
+	// Nodes is ordered by node ID and Links by source and target node ID
+	Nodes []*NodeTick
+	Links []*LinkTick
+}
+
+// NodeTick is the state of a pipeline node at a tick
+type NodeTick struct {
+	NodeID int
+	Status NodeStatus
+	// Working is the number of machines in a cycle
+	Working int
+	Cycles  int
+	// InputBuffer and OutputBuffer hold the fill of the buffers, and Produced and Consumed
+	// the totals since the start of the run, all keyed by item ID
+	InputBuffer  map[int]float64
+	OutputBuffer map[int]float64
+	Produced     map[int]float64
+	Consumed     map[int]float64
+}
+
+// LinkTick is the state of a connection at a tick
+type LinkTick struct {
+	SourceNodeID int
+	TargetNodeID int
+	// Delivered is the number of units sent along the connection since the start of the run
+	Delivered float64
+}
+
+// Utilization returns the fraction of the horizon the node spent processing
+func (r *NodeResult) Utilization() float64 {
+	total := r.BusyTime + r.IdleTime
+	if total == 0 {
+		return 0
+	}
+	return float64(r.BusyTime) / float64(total)
+}
+
+// Result holds the outcome of a simulation run
+type Result struct {
+	Duration time.Duration
+	// Nodes is keyed by pipeline node ID
+	Nodes map[int]*NodeResult
+	// PowerDemand and PowerGeneration are the average draw and generation over the
+	// horizon, in megawatts
+	PowerDemand     float64
+	PowerGeneration float64
+	// Links lists the connections with a transport, ordered by source and target node ID
+	Links []*LinkResult
+}
+
+// ToModel converts the outcome into the statistics stored for simulation runs
+func (r *Result) ToModel() *models.SimulationResult {
+	nodes := make([]*models.SimulationNodeStats, 0, len(r.Nodes))
+	for _, node := range r.Nodes {
+		samples := make([]*models.SimulationSample, len(node.Samples))
+		for i, sample := range node.Samples {
+			samples[i] = models.NewSimulationSample(sample.Time, sample.InputBuffer, sample.OutputBuffer, sample.Produced, sample.Consumed)
+		}
+		nodes = append(nodes, models.NewSimulationNodeStats(
+			node.NodeID,
+			node.FacilityID,
+			node.Cycles,
+			node.Produced,
+			node.Consumed,
+			node.BusyTime,
+			node.IdleTime,
+			node.StarvedTime,
+			node.BlockedTime,
+			samples,
+		))
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].NodeID() < nodes[j].NodeID()
+	})
+
+	links := make([]*models.SimulationLinkStats, len(r.Links))
+	for i, link := range r.Links {
+		links[i] = models.NewSimulationLinkStats(
+			link.SourceNodeID,
+			link.TargetNodeID,
+			link.TransportID,
+			link.Moved,
+			link.CapacityPerMinute,
+			link.Saturated,
+		)
+	}
+	return models.NewSimulationResult(r.PowerDemand, r.PowerGeneration, nodes, links)
+}
+
+// LinkResult holds the statistics collected for a connection carried by a transport
+type LinkResult struct {
+	SourceNodeID int
+	TargetNodeID int
+	TransportID  int
+	// Moved is the number of units carried by the transport
+	Moved             float64
+	CapacityPerMinute float64
+	// Saturated reports whether the transport held back units because it was at capacity
+	Saturated bool
+}
+
+// route is a connection to a downstream node consuming one or more outputs of a node
+type route struct {
+	conn   *models.PipelineConnection
+	source *nodeState
+	target *nodeState
+	// delivered counts the units sent along the connection, in total and by item ID
+	delivered      float64
+	deliveredItems map[int]float64
+	// moved counts the units carried by the transport of the connection
+	moved float64
+	// saturated is set once the transport holds back a unit
+	saturated bool
+}
+
+// transportCapacity returns the capacity of the transport of the connection in units per
+// minute, or zero if the connection has no transport moving items of the given kind
+func (r *route) transportCapacity(kind models.ItemKind) float64 {
+	transport := r.conn.Transport()
+	if transport == nil || !transport.Moves(kind) {
+		return 0
+	}
+	return transport.ItemsPerMinute()
+}
+
+// nextSlot reports whether a connection limited to the given units per minute can carry
+// another unit at the given time after having carried the given number of units. Otherwise
+// it returns the time the limit allows the next unit. Zero means unlimited.
+func nextSlot(perMinute float64, carried float64, now time.Duration) (time.Duration, bool) {
+	if perMinute <= 0 || carried < math.Floor(perMinute*now.Minutes())+1 {
+		return 0, true
+	}
+	minutes := carried / perMinute
+	return max(time.Duration(math.Ceil(minutes*float64(time.Minute))), now+1), false
+}
+
+// nodeState tracks the runtime state of a pipeline node
+type nodeState struct {
+	node *models.PipelineNode
+	// inventory holds received input items by item ID
+	inventory map[int]float64
+	// pending holds finished output items that have not been delivered yet
+	pending map[int]float64
+	// inputCapacity limits each item in inventory; zero means unlimited
+	inputCapacity float64
+	// outputCapacity limits each item in pending before the node is blocked
+	outputCapacity float64
+	// routes lists, per output item ID, the connections to downstream nodes that require the item
+	routes map[int][]*route
+	// nextRoute holds, per output item ID, the round-robin position among routes
+	nextRoute map[int]int
+	// delivered counts the delivered units by item ID
+	delivered map[int]float64
+	suppliers []*nodeState
+	// working is the number of machines in a cycle; the others are idle, blocked while
+	// pending outputs exceed the output buffer and starved otherwise
+	working int
+	blocked bool
+	// cycleTime is the duration of a cycle of one machine
+	cycleTime time.Duration
+	// busyTime, starvedTime and blockedTime accumulate machine time over all machines
+	busyTime    time.Duration
+	starvedTime time.Duration
+	blockedTime time.Duration
+	since       time.Duration
+	queued      bool
+	// retrying is set while a retry event is scheduled for the node at retryAt
+	retrying bool
+	retryAt  time.Duration
+	// unpowered is set while the node waits for a generator to start
+	unpowered bool
+	// onDemand is set for sources and sinks without a rate limit, which move items
+	// without running cycles
+	onDemand bool
+	result   *NodeResult
+}
+
+// Engine runs a discrete-event simulation of a pipeline. Every machine of a node starts a
+// cycle as soon as its input requirements are available, and hands its outputs to the downstream
+// nodes that consume them once the processing time has elapsed. Outputs with a probability
+// are only yielded by cycles drawing them. Outputs without a downstream consumer leave the
+// pipeline.
+//
+// Sources and sinks with a rate limit run like a single machine yielding or taking one unit
+// per cycle. Sinks without a rate limit take every unit they receive at once, and sources
+// without a rate limit keep each downstream node supplied with the inputs of one cycle
+// per machine, counting every unit as a cycle.
+//
+// Downstream nodes accept units while their input buffer has room. Outputs they cannot
+// accept wait in the output buffer of the node, and once it overflows the node is blocked:
+// its idle machines do not start new cycles until the outputs are delivered.
+//
+// Each unit goes to a connection lagging behind its ratio if there is one, and otherwise
+// round-robin to the connections of the highest priority that have free buffer space and
+// have not reached their maximum throughput or the capacity of their transport.
+//
+// In pipelines with generators, a machine drawing power runs its cycle slower when the
+// generators in a cycle cannot cover the demand at its start, in proportion to the share
+// of the demand they cover, and does not start while they generate nothing. Pipelines
+// without generators draw from an external grid and are never slowed down.
+type Engine struct {
+	config Config
+	rng    *rand.Rand
+	// grid is set when the pipeline contains generators
+	grid  bool
+	nodes []*nodeState
+	// routes is ordered by source and target node ID
+	routes []*route
+	queue  eventQueue
+	ready  []*nodeState
+	now    time.Duration
+	seq    int
+	// nextSample is the time of the next buffer sample
+	nextSample time.Duration
+}
+
+// New prepares a simulation of the given pipeline
+func New(pipeline *models.Pipeline, config Config) (*Engine, error) {
+	if config.Duration <= 0 {
+		return nil, fmt.Errorf("simulation duration must be positive")
+	}
+	if config.BufferCapacity < 0 {
+		return nil, fmt.Errorf("buffer capacity must not be negative")
+	}
+	if config.SampleInterval < 0 {
+		return nil, fmt.Errorf("sample interval must not be negative")
+	}
+
+	e := &Engine{
+		config: config,
+		rng:    rand.New(rand.NewPCG(config.Seed, config.Seed)),
+	}
+
+	states := make(map[int]*nodeState, len(pipeline.Nodes()))
+	for id, node := range pipeline.Nodes() {
+		facilityID := 0
+		if facility := node.Facility(); facility != nil {
+			if facility.ProcessingTime() <= 0 {
+				return nil, fmt.Errorf("facility %q of node %d has a non-positive processing time", facility.Name(), id)
+			}
+			facilityID = facility.ID()
+		} else if !node.IsBoundary() {
+			return nil, fmt.Errorf("node %d has no facility", id)
+		}
+		if node.InstanceCount() < 1 || node.ClockSpeed() <= 0 {
+			return nil, fmt.Errorf("node %d needs at least one instance and a positive clock speed", id)
+		}
+		// Very short cycles could round to zero and reschedule at the same time forever
+		cycleTime := time.Duration(math.Round(node.CycleTime() * float64(time.Millisecond)))
+		if node.CycleTime() > 0 && cycleTime < time.Millisecond {
+			return nil, fmt.Errorf("node %d has a cycle time below one millisecond", id)
+		}
+		buffers := node.Buffers()
+		if buffers.Input() < 0 || buffers.Output() < 0 {
+			return nil, fmt.Errorf("node %d has a negative buffer capacity", id)
+		}
+		inputCapacity := config.BufferCapacity
+		if buffers.Input() > 0 {
+			inputCapacity = buffers.Input()
+		}
+		state := &nodeState{
+			node:           node,
+			cycleTime:      cycleTime,
+			inventory:      make(map[int]float64),
+			pending:        make(map[int]float64),
+			inputCapacity:  float64(inputCapacity),
+			outputCapacity: float64(buffers.Output()),
+			routes:         make(map[int][]*route),
+			nextRoute:      make(map[int]int),
+			delivered:      make(map[int]float64),
+			onDemand:       node.IsBoundary() && node.PerMinute() == 0,
+			result: &NodeResult{
+				NodeID:     id,
+				FacilityID: facilityID,
+				Produced:   make(map[int]float64),
+				Consumed:   make(map[int]float64),
+			},
+		}
+		states[id] = state
+		e.nodes = append(e.nodes, state)
+		e.grid = e.grid || node.Power().IsGenerator()
+	}
+	sort.Slice(e.nodes, func(i, j int) bool {
+		return e.nodes[i].node.ID() < e.nodes[j].node.ID()
+	})
+
+	for _, state := range e.nodes {
+		for _, conn := range state.node.Connections() {
+			next, ok := states[conn.TargetNodeID()]
+			if !ok {
+				continue
+			}
+			r := &route{conn: conn, source: state, target: next, deliveredItems: make(map[int]float64)}
+			e.routes = append(e.routes, r)
+			for _, output := range state.node.OutputDefinitions() {
+				itemID := output.Item().ID()
+				if !conn.Carries(itemID) || !requires(next.node, itemID) {
+					continue
+				}
+				if state.onDemand && next.onDemand && conn.MaxThroughput() <= 0 && r.transportCapacity(itemKind(state.node, itemID)) <= 0 {
+					return nil, fmt.Errorf("node %d supplies node %d without limit", state.node.ID(), next.node.ID())
+				}
+				state.routes[itemID] = append(state.routes[itemID], r)
+			}
+			next.suppliers = append(next.suppliers, state)
+		}
+	}
+	sort.SliceStable(e.routes, func(i, j int) bool {
+		if e.routes[i].source.node.ID() != e.routes[j].source.node.ID() {
+			return e.routes[i].source.node.ID() < e.routes[j].source.node.ID()
+		}
+		return e.routes[i].target.node.ID() < e.routes[j].target.node.ID()
+	})
+
+	return e, nil
+}
+
+// Run executes the simulation until the configured duration elapses or ctx is cancelled
+func (e *Engine) Run(ctx context.Context) (*Result, error) {
+	for _, state := range e.nodes {
+		e.wake(state)
+	}
+	e.settle()
+
+	for processed := 0; e.queue.Len() > 0 && e.queue.peek().time <= e.config.Duration; processed++ {
+		if processed%cancellationCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+
+		ev := e.queue.pop()
+		if err := e.sampleBefore(ev.time); err != nil {
+			return nil, err
+		}
+		e.now = ev.time
+		switch ev.kind {
+		case eventComplete:
+			e.complete(ev.node)
+		case eventRetry:
+			if ev.time >= ev.node.retryAt {
+				ev.node.retrying = false
+			}
+			e.wake(ev.node)
+		}
+		e.settle()
+	}
+
+	if err := e.sampleBefore(e.config.Duration + 1); err != nil {
+		return nil, err
+	}
+	e.now = e.config.Duration
+	result := &Result{
+		Duration: e.config.Duration,
+		Nodes:    make(map[int]*NodeResult, len(e.nodes)),
+	}
+	for _, state := range e.nodes {
+		e.account(state)
+		profile := state.node.Power()
+		idleTime := e.config.Duration*time.Duration(state.node.InstanceCount()) - state.busyTime
+		result.PowerDemand += (state.busyTime.Seconds()*profile.ActiveDraw() + idleTime.Seconds()*profile.IdleDraw()) / e.config.Duration.Seconds()
+		result.PowerGeneration += state.busyTime.Seconds() * profile.Generation() / e.config.Duration.Seconds()
+
+		instances := time.Duration(state.node.InstanceCount())
+		state.result.BusyTime = state.busyTime / instances
+		state.result.StarvedTime = state.starvedTime / instances
+		state.result.BlockedTime = state.blockedTime / instances
+		state.result.IdleTime = e.config.Duration - state.result.BusyTime
+		result.Nodes[state.result.NodeID] = state.result
+	}
+
+	result.Links = make([]*LinkResult, 0)
+	for _, r := range e.routes {
+		transport := r.conn.Transport()
+		if transport == nil {
+			continue
+		}
+		result.Links = append(result.Links, &LinkResult{
+			SourceNodeID:      r.source.node.ID(),
+			TargetNodeID:      r.target.node.ID(),
+			TransportID:       transport.ID(),
+			Moved:             r.moved,
+			CapacityPerMinute: transport.ItemsPerMinute(),
+			Saturated:         r.saturated,
+		})
+	}
+	return result, nil
+}
+
+// itemKind returns whether the output of the node with the given item ID moves on belts or in pipes
+func itemKind(node *models.PipelineNode, itemID int) models.ItemKind {
+	for _, output := range node.OutputDefinitions() {
+		if output.Item().ID() == itemID && output.Item().IsFluid() {
+			return models.ItemKindFluid
+		}
+	}
+	return models.ItemKindSolid
+}
+
+func requires(node *models.PipelineNode, itemID int) bool {
+	_, ok := inputQuantity(node, itemID)
+	return ok
+}
+
+// inputQuantity returns the quantity of the item a cycle of the node consumes
+func inputQuantity(node *models.PipelineNode, itemID int) (float64, bool) {
+	for _, input := range node.InputRequirements() {
+		if input.Item().ID() == itemID {
+			return input.Quantity(), true
+		}
+	}
+	return 0, false
+}
+
+// sampleBefore records the buffers of every node at the sample times before the given
+// time, and reports a tick for each of them. Between events the buffers do not change, so
+// the samples reflect every event up to the sample time.
+func (e *Engine) sampleBefore(at time.Duration) error {
+	if e.config.SampleInterval <= 0 {
+		return nil
+	}
+	for ; e.nextSample < at && e.nextSample <= e.config.Duration; e.nextSample += e.config.SampleInterval {
+		for _, state := range e.nodes {
+			state.result.Samples = append(state.result.Samples, &BufferSample{
+				Time:         e.nextSample,
+				InputBuffer:  copyAmounts(state.inventory),
+				OutputBuffer: copyAmounts(state.pending),
+				Produced:     copyAmounts(state.result.Produced),
+				Consumed:     copyAmounts(state.result.Consumed),
+			})
+		}
+		if e.config.OnTick != nil {
+			if err := e.config.OnTick(e.tick(e.nextSample)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// tick captures the state of the pipeline for a tick at the given time
+func (e *Engine) tick(at time.Duration) *Tick {
+	tick := &Tick{
+		Time:  at,
+		Nodes: make([]*NodeTick, len(e.nodes)),
+		Links: make([]*LinkTick, len(e.routes)),
+	}
+	for i, state := range e.nodes {
+		status := NodeStatusStarved
+		switch {
+		case state.working > 0 || state.onDemand:
+			status = NodeStatusWorking
+		case state.blocked:
+			status = NodeStatusBlocked
+		}
+		tick.Nodes[i] = &NodeTick{
+			NodeID:       state.node.ID(),
+			Status:       status,
+			Working:      state.working,
+			Cycles:       state.result.Cycles,
+			InputBuffer:  copyAmounts(state.inventory),
+			OutputBuffer: copyAmounts(state.pending),
+			Produced:     copyAmounts(state.result.Produced),
+			Consumed:     copyAmounts(state.result.Consumed),
+		}
+	}
+	for i, r := range e.routes {
+		tick.Links[i] = &LinkTick{
+			SourceNodeID: r.source.node.ID(),
+			TargetNodeID: r.target.node.ID(),
+			Delivered:    r.delivered,
+		}
+	}
+	return tick
+}
+
+// copyAmounts copies the non-empty amounts of a buffer
+func copyAmounts(amounts map[int]float64) map[int]float64 {
+	copied := make(map[int]float64, len(amounts))
+	for itemID, amount := range amounts {
+		if amount > epsilon {
+			copied[itemID] = amount
+		}
+	}
+	return copied
+}
+
+// account adds the machine time spent since the last change of the node
+func (e *Engine) account(state *nodeState) {
+	elapsed := e.now - state.since
+	idle := time.Duration(state.node.InstanceCount() - state.working)
+	state.busyTime += elapsed * time.Duration(state.working)
+	if state.blocked {
+		state.blockedTime += elapsed * idle
+	} else {
+		state.starvedTime += elapsed * idle
+	}
+	state.since = e.now
+}
+
+// wake schedules a node to re-evaluate whether it can make progress
+func (e *Engine) wake(state *nodeState) {
+	if state.queued {
+		return
+	}
+	state.queued = true
+	e.ready = append(e.ready, state)
+}
+
+// settle lets woken nodes make progress until no further change is possible at the current time
+func (e *Engine) settle() {
+	for len(e.ready) > 0 {
+		state := e.ready[0]
+		e.ready = e.ready[1:]
+		state.queued = false
+
+		if state.onDemand && len(state.node.InputRequirements()) == 0 {
+			e.supply(state)
+			continue
+		}
+		if len(state.pending) > 0 {
+			e.deliver(state)
+		}
+		if blocked := !e.outputsFit(state); blocked != state.blocked {
+			e.account(state)
+			state.blocked = blocked
+		}
+		if !state.blocked {
+			e.tryStart(state)
+		}
+	}
+}
+
+func (e *Engine) complete(state *nodeState) {
+	state.result.Cycles++
+	for _, output := range state.node.OutputDefinitions() {
+		if output.Probability() < 1 && e.rng.Float64() >= output.Probability() {
+			continue
+		}
+		state.pending[output.Item().ID()] += output.Quantity()
+		state.result.Produced[output.Item().ID()] += output.Quantity()
+	}
+	e.account(state)
+	state.working--
+	e.wake(state)
+}
+
+// deliver hands pending outputs to downstream nodes as described for Engine
+func (e *Engine) deliver(state *nodeState) {
+	itemIDs := make([]int, 0, len(state.pending))
+	for itemID := range state.pending {
+		itemIDs = append(itemIDs, itemID)
+	}
+	sort.Ints(itemIDs)
+
+	for _, itemID := range itemIDs {
+		if len(state.routes[itemID]) == 0 {
+			delete(state.pending, itemID)
+			continue
+		}
+
+		// Units are delivered one at a time, followed by the fraction left of a fluid
+		kind := itemKind(state.node, itemID)
+		for state.pending[itemID] > epsilon {
+			r := e.selectRoute(state, itemID, kind)
+			if r == nil {
+				break
+			}
+			amount := math.Min(1, state.pending[itemID])
+			r.target.inventory[itemID] += amount
+			r.delivered += amount
+			r.deliveredItems[itemID] += amount
+			if r.transportCapacity(kind) > 0 {
+				r.moved += amount
+			}
+			state.delivered[itemID] += amount
+			state.pending[itemID] -= amount
+			e.wake(r.target)
+		}
+		if state.pending[itemID] <= epsilon {
+			delete(state.pending, itemID)
+		}
+	}
+}
+
+// supply delivers units from a source without a rate limit to every downstream node lacking
+// the inputs of a cycle per machine, as far as their connections allow
+func (e *Engine) supply(state *nodeState) {
+	for _, output := range state.node.OutputDefinitions() {
+		itemID := output.Item().ID()
+		kind := itemKind(state.node, itemID)
+		for _, r := range state.routes[itemID] {
+			quantity, _ := inputQuantity(r.target.node, itemID)
+			wanted := quantity * float64(r.target.node.InstanceCount())
+			for r.target.inventory[itemID] < wanted-epsilon && e.hasRoom(r.target, itemID) && e.belowCapacity(state, r, kind) {
+				r.target.inventory[itemID]++
+				r.delivered++
+				r.deliveredItems[itemID]++
+				if r.transportCapacity(kind) > 0 {
+					r.moved++
+				}
+				state.delivered[itemID]++
+				state.result.Cycles++
+				state.result.Produced[itemID]++
+				e.wake(r.target)
+			}
+		}
+	}
+}
+
+// drain takes every unit a sink without a rate limit has received, counting each as a cycle
+func (e *Engine) drain(state *nodeState) {
+	drained := false
+	for e.hasInputs(state) {
+		for _, input := range state.node.InputRequirements() {
+			state.inventory[input.Item().ID()] -= input.Quantity()
+			state.result.Consumed[input.Item().ID()] += input.Quantity()
+		}
+		state.result.Cycles++
+		drained = true
+	}
+	if drained {
+		e.wakeSuppliers(state)
+	}
+}
+
+// outputsFit reports whether the pending outputs of the node fit into its output buffer
+func (e *Engine) outputsFit(state *nodeState) bool {
+	for _, amount := range state.pending {
+		if amount > state.outputCapacity+epsilon {
+			return false
+		}
+	}
+	return true
+}
+
+// selectRoute picks the connection the next unit of the item is sent along, or returns nil
+// if no connection can accept it right now
+func (e *Engine) selectRoute(state *nodeState, itemID int, kind models.ItemKind) *route {
+	routes := state.routes[itemID]
+
+	open := make([]bool, len(routes))
+	for i, r := range routes {
+		open[i] = e.hasRoom(r.target, itemID) && e.belowCapacity(state, r, kind)
+	}
+
+	// Connections with a ratio are served first while they lag behind their share
+	var selected *route
+	largestDeficit := 0.0
+	for i, r := range routes {
+		if !open[i] || r.conn.Ratio() <= 0 {
+			continue
+		}
+		deficit := r.conn.Ratio()*(state.delivered[itemID]+1) - r.deliveredItems[itemID]
+		if deficit > largestDeficit {
+			selected = r
+			largestDeficit = deficit
+		}
+	}
+	if selected != nil {
+		return selected
+	}
+
+	index := -1
+	for i := range routes {
+		candidate := (state.nextRoute[itemID] + i) % len(routes)
+		if open[candidate] && (index < 0 || routes[candidate].conn.Priority() > routes[index].conn.Priority()) {
+			index = candidate
+		}
+	}
+	if index < 0 {
+		return nil
+	}
+	state.nextRoute[itemID] = index + 1
+	return routes[index]
+}
+
+// belowCapacity reports whether the connection can carry another unit of the given kind
+// now. Otherwise a retry is scheduled for the time both the maximum throughput and the
+// transport allow the next unit.
+func (e *Engine) belowCapacity(state *nodeState, r *route, kind models.ItemKind) bool {
+	throughputAt, belowThroughput := nextSlot(r.conn.MaxThroughput(), r.delivered, e.now)
+	transportAt, belowTransport := nextSlot(r.transportCapacity(kind), r.moved, e.now)
+	if belowThroughput && belowTransport {
+		return true
+	}
+	if !belowTransport {
+		r.saturated = true
+	}
+	at := max(throughputAt, transportAt)
+	if !state.retrying || at < state.retryAt {
+		state.retrying = true
+		state.retryAt = at
+		e.schedule(eventRetry, state, at)
+	}
+	return false
+}
+
+func (e *Engine) schedule(kind eventKind, state *nodeState, at time.Duration) {
+	e.seq++
+	e.queue.push(&event{
+		kind: kind,
+		time: at,
+		seq:  e.seq,
+		node: state,
+	})
+}
+
+func (e *Engine) hasRoom(state *nodeState, itemID int) bool {
+	return state.inputCapacity == 0 || state.inventory[itemID] < state.inputCapacity
+}
+
+// tryStart begins new cycles on idle machines as long as input requirements and power are available
+func (e *Engine) tryStart(state *nodeState) {
+	if state.onDemand {
+		e.drain(state)
+		return
+	}
+	started := false
+	for state.working < state.node.InstanceCount() && e.hasInputs(state) {
+		cycleTime, powered := e.poweredCycleTime(state)
+		if !powered {
+			state.unpowered = true
+			break
+		}
+		if !started {
+			e.account(state)
+			started = true
+		}
+		for _, input := range state.node.InputRequirements() {
+			state.inventory[input.Item().ID()] -= input.Quantity()
+			state.result.Consumed[input.Item().ID()] += input.Quantity()
+		}
+		state.working++
+		e.schedule(eventComplete, state, e.now+cycleTime)
+	}
+	if !started {
+		return
+	}
+
+	if state.node.Power().IsGenerator() {
+		for _, other := range e.nodes {
+			if other.unpowered {
+				other.unpowered = false
+				e.wake(other)
+			}
+		}
+	}
+
+	e.wakeSuppliers(state)
+}
+
+// wakeSuppliers wakes the suppliers of a node that consumed inputs, as doing so frees buffer
+// space for suppliers waiting to deliver and asks sources without a rate limit for more
+func (e *Engine) wakeSuppliers(state *nodeState) {
+	for _, supplier := range state.suppliers {
+		if len(supplier.pending) > 0 || supplier.onDemand {
+			e.wake(supplier)
+		}
+	}
+}
+
+// poweredCycleTime returns the duration of a cycle of the node starting now, stretched by
+// the share of the power demand that the generators cannot cover. It reports false if the
+// node needs power but the generators generate none.
+func (e *Engine) poweredCycleTime(state *nodeState) (time.Duration, bool) {
+	profile := state.node.Power()
+	if !e.grid || profile.IsGenerator() || profile.ActiveDraw() <= 0 {
+		return state.cycleTime, true
+	}
+
+	// The demand includes the machine about to start
+	generation := 0.0
+	demand := profile.ActiveDraw() - profile.IdleDraw()
+	for _, other := range e.nodes {
+		otherProfile := other.node.Power()
+		working := float64(other.working)
+		idle := float64(other.node.InstanceCount() - other.working)
+		generation += working * otherProfile.Generation()
+		demand += working*otherProfile.ActiveDraw() + idle*otherProfile.IdleDraw()
+	}
+
+	satisfaction := power.Satisfaction(generation, demand)
+	if satisfaction <= 0 {
+		return 0, false
+	}
+	return time.Duration(math.Round(float64(state.cycleTime) / satisfaction)), true
+}
+
+func (e *Engine) hasInputs(state *nodeState) bool {
+	for _, input := range state.node.InputRequirements() {
+		if state.inventory[input.Item().ID()] < input.Quantity()-epsilon {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,631 @@
+package simulation
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fasim/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	ore   = models.NewItemFromParams(1, "Ore", "", models.ItemKindSolid)
+	plate = models.NewItemFromParams(2, "Plate", "", models.ItemKindSolid)
+)
+
+func newMiner() *models.Facility {
+	miner := models.NewFacility("Miner", "", 1000)
+	miner.AddOutputDefinition(models.NewOutputDefinition(ore, 1))
+	return miner
+}
+
+func newFurnace() *models.Facility {
+	furnace := models.NewFacility("Furnace", "", 2000)
+	furnace.AddInputRequirement(models.NewInputRequirement(ore, 1))
+	furnace.AddOutputDefinition(models.NewOutputDefinition(plate, 1))
+	return furnace
+}
+
+// newTestPipeline connects a miner to the given number of furnaces.
+// The miner gets node ID 1 and the furnaces get IDs from 2 upwards.
+func newTestPipeline(furnaces int) *models.Pipeline {
+	pipeline := models.NewPipeline("Test Pipeline", "")
+	miner := models.NewPipelineNode(newMiner(), 1, 1)
+	pipeline.AddNode(miner)
+	for i := 0; i < furnaces; i++ {
+		furnace := models.NewPipelineNode(newFurnace(), 1, 1)
+		pipeline.AddNode(furnace)
+		miner.AddNextNodeID(furnace.ID())
+	}
+	return pipeline
+}
+
+// newRoutedPipeline connects a miner to one furnace per connection, retargeting the
+// connections so that the furnaces get node IDs from 2 upwards in the given order
+func newRoutedPipeline(conns ...*models.PipelineConnection) *models.Pipeline {
+	pipeline := models.NewPipeline("Test Pipeline", "")
+	miner := models.NewPipelineNode(newMiner(), 1, 1)
+	pipeline.AddNode(miner)
+	for _, conn := range conns {
+		furnace := models.NewPipelineNode(newFurnace(), 1, 1)
+		pipeline.AddNode(furnace)
+		miner.AddConnection(models.NewPipelineConnection(furnace.ID(), conn.Item(), conn.Ratio(), conn.Priority(), conn.MaxThroughput(), conn.Transport()))
+	}
+	return pipeline
+}
+
+func TestRun(t *testing.T) {
+	type expectedNode struct {
+		cycles   int
+		produced map[int]float64
+		consumed map[int]float64
+		busy     time.Duration
+		starved  time.Duration
+		blocked  time.Duration
+	}
+
+	testCases := []struct {
+		name     string
+		pipeline *models.Pipeline
+		config   Config
+		expected map[int]expectedNode
+	}{
+		{
+			name:     "slow consumer with unlimited buffers",
+			pipeline: newTestPipeline(1),
+			config:   Config{Duration: 10 * time.Second},
+			expected: map[int]expectedNode{
+				1: {cycles: 10, produced: map[int]float64{1: 10}, consumed: map[int]float64{}, busy: 10 * time.Second},
+				2: {cycles: 4, produced: map[int]float64{2: 4}, consumed: map[int]float64{1: 5}, busy: 9 * time.Second, starved: time.Second},
+			},
+		},
+		{
+			name:     "slow consumer blocks supplier when buffer is full",
+			pipeline: newTestPipeline(1),
+			config:   Config{Duration: 10 * time.Second, BufferCapacity: 1},
+			expected: map[int]expectedNode{
+				1: {cycles: 7, produced: map[int]float64{1: 7}, consumed: map[int]float64{}, busy: 7 * time.Second, blocked: 3 * time.Second},
+				2: {cycles: 4, produced: map[int]float64{2: 4}, consumed: map[int]float64{1: 5}, busy: 9 * time.Second, starved: time.Second},
+			},
+		},
+		{
+			name:     "parallel consumers share supply",
+			pipeline: newTestPipeline(2),
+			config:   Config{Duration: 10 * time.Second},
+			expected: map[int]expectedNode{
+				1: {cycles: 10, produced: map[int]float64{1: 10}, consumed: map[int]float64{}, busy: 10 * time.Second},
+				2: {cycles: 4, produced: map[int]float64{2: 4}, consumed: map[int]float64{1: 5}, busy: 9 * time.Second, starved: time.Second},
+				3: {cycles: 4, produced: map[int]float64{2: 4}, consumed: map[int]float64{1: 5}, busy: 8 * time.Second, starved: 2 * time.Second},
+			},
+		},
+		{
+			name: "higher priority consumer is served first",
+			pipeline: newRoutedPipeline(
+				models.NewPipelineConnection(0, nil, 0, 0, 0, nil),
+				models.NewPipelineConnection(0, nil, 0, 1, 0, nil),
+			),
+			config: Config{Duration: 10 * time.Second, BufferCapacity: 1},
+			expected: map[int]expectedNode{
+				1: {cycles: 10, produced: map[int]float64{1: 10}, consumed: map[int]float64{}, busy: 10 * time.Second},
+				2: {cycles: 3, produced: map[int]float64{2: 3}, consumed: map[int]float64{1: 4}, busy: 6 * time.Second, starved: 4 * time.Second},
+				3: {cycles: 4, produced: map[int]float64{2: 4}, consumed: map[int]float64{1: 5}, busy: 9 * time.Second, starved: time.Second},
+			},
+		},
+		{
+			name: "ratio splits supply",
+			pipeline: newRoutedPipeline(
+				models.NewPipelineConnection(0, ore, 0.2, 0, 0, nil),
+				models.NewPipelineConnection(0, ore, 0.8, 0, 0, nil),
+			),
+			config: Config{Duration: 10 * time.Second},
+			expected: map[int]expectedNode{
+				1: {cycles: 10, produced: map[int]float64{1: 10}, consumed: map[int]float64{}, busy: 10 * time.Second},
+				2: {cycles: 2, produced: map[int]float64{2: 2}, consumed: map[int]float64{1: 2}, busy: 4 * time.Second, starved: 6 * time.Second},
+				3: {cycles: 4, produced: map[int]float64{2: 4}, consumed: map[int]float64{1: 5}, busy: 9 * time.Second, starved: time.Second},
+			},
+		},
+		{
+			name: "max throughput holds back supplier",
+			pipeline: newRoutedPipeline(
+				models.NewPipelineConnection(0, nil, 0, 0, 20, nil),
+			),
+			config: Config{Duration: 10 * time.Second},
+			expected: map[int]expectedNode{
+				1: {cycles: 5, produced: map[int]float64{1: 5}, consumed: map[int]float64{}, busy: 5 * time.Second, blocked: 5 * time.Second},
+				2: {cycles: 3, produced: map[int]float64{2: 3}, consumed: map[int]float64{1: 4}, busy: 7 * time.Second, starved: 3 * time.Second},
+			},
+		},
+		{
+			name: "machines of a node work in parallel",
+			pipeline: func() *models.Pipeline {
+				pipeline := models.NewPipeline("Test Pipeline", "")
+				miner := models.NewPipelineNode(newMiner(), 1, 1)
+				pipeline.AddNode(miner)
+				furnace := models.NewPipelineNode(newFurnace(), 2, 1)
+				pipeline.AddNode(furnace)
+				miner.AddNextNodeID(furnace.ID())
+				return pipeline
+			}(),
+			config: Config{Duration: 10 * time.Second},
+			expected: map[int]expectedNode{
+				1: {cycles: 10, produced: map[int]float64{1: 10}, consumed: map[int]float64{}, busy: 10 * time.Second},
+				2: {cycles: 8, produced: map[int]float64{2: 8}, consumed: map[int]float64{1: 10}, busy: 8500 * time.Millisecond, starved: 1500 * time.Millisecond},
+			},
+		},
+		{
+			name: "clock speed shortens cycles",
+			pipeline: func() *models.Pipeline {
+				pipeline := models.NewPipeline("Test Pipeline", "")
+				miner := models.NewPipelineNode(newMiner(), 1, 1)
+				pipeline.AddNode(miner)
+				furnace := models.NewPipelineNode(newFurnace(), 1, 2)
+				pipeline.AddNode(furnace)
+				miner.AddNextNodeID(furnace.ID())
+				return pipeline
+			}(),
+			config: Config{Duration: 10 * time.Second},
+			expected: map[int]expectedNode{
+				1: {cycles: 10, produced: map[int]float64{1: 10}, consumed: map[int]float64{}, busy: 10 * time.Second},
+				2: {cycles: 9, produced: map[int]float64{2: 9}, consumed: map[int]float64{1: 10}, busy: 9 * time.Second, starved: time.Second},
+			},
+		},
+		{
+			name: "node without supply starves",
+			pipeline: func() *models.Pipeline {
+				pipeline := models.NewPipeline("Test Pipeline", "")
+				pipeline.AddNode(models.NewPipelineNode(newFurnace(), 1, 1))
+				return pipeline
+			}(),
+			config: Config{Duration: 10 * time.Second},
+			expected: map[int]expectedNode{
+				1: {produced: map[int]float64{}, consumed: map[int]float64{}, starved: 10 * time.Second},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			engine, err := New(tc.pipeline, tc.config)
+			require.NoError(t, err)
+
+			result, err := engine.Run(context.Background())
+			require.NoError(t, err)
+			require.Len(t, result.Nodes, len(tc.expected))
+
+			for nodeID, expected := range tc.expected {
+				node := result.Nodes[nodeID]
+				require.NotNil(t, node, "node %d", nodeID)
+				assert.Equal(t, expected.cycles, node.Cycles, "cycles of node %d", nodeID)
+				assert.Equal(t, expected.produced, node.Produced, "produced by node %d", nodeID)
+				assert.Equal(t, expected.consumed, node.Consumed, "consumed by node %d", nodeID)
+				assert.Equal(t, expected.busy, node.BusyTime, "busy time of node %d", nodeID)
+				assert.Equal(t, expected.starved, node.StarvedTime, "starved time of node %d", nodeID)
+				assert.Equal(t, expected.blocked, node.BlockedTime, "blocked time of node %d", nodeID)
+				assert.Equal(t, tc.config.Duration-expected.busy, node.IdleTime, "idle time of node %d", nodeID)
+			}
+		})
+	}
+}
+
+func TestRunWithBuffers(t *testing.T) {
+	testCases := []struct {
+		name          string
+		outputBuffer  int
+		minerCycles   int
+		minerBlocked  time.Duration
+		minerOutput   []map[int]float64
+		furnaceInputs []map[int]float64
+	}{
+		{
+			name:          "full input buffer downstream blocks supplier",
+			minerCycles:   7,
+			minerBlocked:  3 * time.Second,
+			minerOutput:   []map[int]float64{{}, {}, {ore.ID(): 1}},
+			furnaceInputs: []map[int]float64{{}, {ore.ID(): 1}, {ore.ID(): 1}},
+		},
+		{
+			name:          "output buffer absorbs outputs downstream cannot accept",
+			outputBuffer:  3,
+			minerCycles:   10,
+			minerOutput:   []map[int]float64{{}, {ore.ID(): 1}, {ore.ID(): 4}},
+			furnaceInputs: []map[int]float64{{}, {ore.ID(): 1}, {ore.ID(): 1}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pipeline := models.NewPipeline("Test Pipeline", "")
+			miner := models.NewPipelineNode(newMiner(), 1, 1)
+			miner.SetBuffers(models.NewBufferCapacity(0, tc.outputBuffer))
+			pipeline.AddNode(miner)
+			furnace := models.NewPipelineNode(newFurnace(), 1, 1)
+			furnace.SetBuffers(models.NewBufferCapacity(1, 0))
+			pipeline.AddNode(furnace)
+			miner.AddNextNodeID(furnace.ID())
+
+			engine, err := New(pipeline, Config{Duration: 10 * time.Second, SampleInterval: 5 * time.Second})
+			require.NoError(t, err)
+			result, err := engine.Run(context.Background())
+			require.NoError(t, err)
+
+			minerResult := result.Nodes[miner.ID()]
+			assert.Equal(t, tc.minerCycles, minerResult.Cycles)
+			assert.Equal(t, tc.minerBlocked, minerResult.BlockedTime)
+			assert.Equal(t, time.Duration(0), minerResult.StarvedTime)
+
+			furnaceResult := result.Nodes[furnace.ID()]
+			assert.Equal(t, 4, furnaceResult.Cycles)
+			assert.Equal(t, time.Second, furnaceResult.StarvedTime)
+
+			require.Len(t, minerResult.Samples, len(tc.minerOutput))
+			require.Len(t, furnaceResult.Samples, len(tc.furnaceInputs))
+			for i, sample := range minerResult.Samples {
+				assert.Equal(t, time.Duration(i)*5*time.Second, sample.Time)
+				assert.Equal(t, tc.minerOutput[i], sample.OutputBuffer, "miner output at %v", sample.Time)
+				assert.Equal(t, tc.furnaceInputs[i], furnaceResult.Samples[i].InputBuffer, "furnace input at %v", sample.Time)
+			}
+			// The last sample counts every unit of the run
+			assert.Equal(t, minerResult.Produced, minerResult.Samples[2].Produced)
+			assert.Equal(t, furnaceResult.Consumed, furnaceResult.Samples[2].Consumed)
+		})
+	}
+}
+
+func TestRunWithTransports(t *testing.T) {
+	testCases := []struct {
+		name            string
+		transport       *models.Transport
+		expectedCycles  int
+		expectedMoved   float64
+		expectSaturated bool
+	}{
+		{
+			name:            "transport holds back supplier",
+			transport:       models.NewTransport("Slow Belt", "", models.ItemKindSolid, 1.0/3),
+			expectedCycles:  3,
+			expectedMoved:   4,
+			expectSaturated: true,
+		},
+		{
+			name:           "transport with spare capacity",
+			transport:      models.NewTransport("Fast Belt", "", models.ItemKindSolid, 2),
+			expectedCycles: 4,
+			expectedMoved:  10,
+		},
+		{
+			name:           "pipe does not limit solids",
+			transport:      models.NewTransport("Pipe", "", models.ItemKindFluid, 1.0/3),
+			expectedCycles: 4,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pipeline := newRoutedPipeline(models.NewPipelineConnection(0, nil, 0, 0, 0, tc.transport))
+			engine, err := New(pipeline, Config{Duration: 10 * time.Second})
+			require.NoError(t, err)
+
+			result, err := engine.Run(context.Background())
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expectedCycles, result.Nodes[2].Cycles)
+			require.Len(t, result.Links, 1)
+			link := result.Links[0]
+			assert.Equal(t, 1, link.SourceNodeID)
+			assert.Equal(t, 2, link.TargetNodeID)
+			assert.InDelta(t, tc.expectedMoved, link.Moved, 1e-9)
+			assert.InDelta(t, tc.transport.ItemsPerMinute(), link.CapacityPerMinute, 1e-9)
+			assert.Equal(t, tc.expectSaturated, link.Saturated)
+		})
+	}
+}
+
+func TestRunWithSourcesAndSinks(t *testing.T) {
+	// newBoundedPipeline feeds a furnace from a source and hands its plates to a sink
+	newBoundedPipeline := func(sourceRate float64, sinkRate float64) *models.Pipeline {
+		pipeline := models.NewPipeline("Test Pipeline", "")
+		source := models.NewSourceNode(ore, sourceRate)
+		pipeline.AddNode(source)
+		furnace := models.NewPipelineNode(newFurnace(), 1, 1)
+		pipeline.AddNode(furnace)
+		sink := models.NewSinkNode(plate, sinkRate)
+		pipeline.AddNode(sink)
+		source.AddNextNodeID(furnace.ID())
+		furnace.AddNextNodeID(sink.ID())
+		return pipeline
+	}
+
+	testCases := []struct {
+		name           string
+		pipeline       *models.Pipeline
+		config         Config
+		expectedCycles map[int]int
+	}{
+		{
+			name:     "unlimited source and sink follow the furnace",
+			pipeline: newBoundedPipeline(0, 0),
+			config:   Config{Duration: 10 * time.Second},
+			// The source keeps one unit ahead of the furnace
+			expectedCycles: map[int]int{1: 7, 2: 5, 3: 5},
+		},
+		{
+			name:     "rate-limited source starves the furnace",
+			pipeline: newBoundedPipeline(20, 0),
+			config:   Config{Duration: 10 * time.Second},
+			// The source yields a unit every three seconds
+			expectedCycles: map[int]int{1: 3, 2: 2, 3: 2},
+		},
+		{
+			name:     "rate-limited sink blocks the furnace",
+			pipeline: newBoundedPipeline(0, 15),
+			config:   Config{Duration: 10 * time.Second, BufferCapacity: 1},
+			// The sink takes a unit every four seconds and the furnace holds no output
+			expectedCycles: map[int]int{1: 6, 2: 4, 3: 2},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			engine, err := New(tc.pipeline, tc.config)
+			require.NoError(t, err)
+
+			result, err := engine.Run(context.Background())
+			require.NoError(t, err)
+
+			for nodeID, cycles := range tc.expectedCycles {
+				assert.Equal(t, cycles, result.Nodes[nodeID].Cycles, "cycles of node %d", nodeID)
+			}
+			assert.Zero(t, result.Nodes[1].FacilityID)
+			assert.InDelta(t, float64(tc.expectedCycles[1]), result.Nodes[1].Produced[ore.ID()], 1e-9)
+		})
+	}
+}
+
+func TestRunWithPower(t *testing.T) {
+	// newPoweredPipeline pairs a miner drawing 2 MW with a generator of the given output
+	// as node 2, or with no generator for a zero output
+	newPoweredPipeline := func(generation float64) *models.Pipeline {
+		miner := newMiner()
+		miner.SetPower(models.NewPowerProfile(2, 0, 0))
+		pipeline := models.NewPipeline("Test Pipeline", "")
+		pipeline.AddNode(models.NewPipelineNode(miner, 1, 1))
+		if generation > 0 {
+			generator := models.NewFacility("Generator", "", 1000)
+			generator.SetPower(models.NewPowerProfile(0, 0, generation))
+			pipeline.AddNode(models.NewPipelineNode(generator, 1, 1))
+		}
+		return pipeline
+	}
+
+	testCases := []struct {
+		name       string
+		pipeline   *models.Pipeline
+		cycles     int
+		generation float64
+	}{
+		{
+			name:       "external grid",
+			pipeline:   newPoweredPipeline(0),
+			cycles:     10,
+			generation: 0,
+		},
+		{
+			name:       "sufficient generation",
+			pipeline:   newPoweredPipeline(2),
+			cycles:     10,
+			generation: 2,
+		},
+		{
+			name:       "half of the demand generated",
+			pipeline:   newPoweredPipeline(1),
+			cycles:     5,
+			generation: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			engine, err := New(tc.pipeline, Config{Duration: 10 * time.Second})
+			require.NoError(t, err)
+
+			result, err := engine.Run(context.Background())
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.cycles, result.Nodes[1].Cycles)
+			assert.Equal(t, 10*time.Second, result.Nodes[1].BusyTime)
+			assert.InDelta(t, 2, result.PowerDemand, 1e-9)
+			assert.InDelta(t, tc.generation, result.PowerGeneration, 1e-9)
+		})
+	}
+}
+
+func TestRunSamplesChanceOutputs(t *testing.T) {
+	// newChancePipeline feeds a furnace yielding a plate from half of its cycles
+	newChancePipeline := func() *models.Pipeline {
+		furnace := models.NewFacility("Furnace", "", 2000)
+		furnace.AddInputRequirement(models.NewInputRequirement(ore, 1))
+		furnace.AddOutputDefinition(models.NewChanceOutputDefinition(plate, 1, 0.5))
+
+		pipeline := models.NewPipeline("Test Pipeline", "")
+		miner := models.NewPipelineNode(newMiner(), 1, 1)
+		pipeline.AddNode(miner)
+		furnaceNode := models.NewPipelineNode(furnace, 1, 1)
+		pipeline.AddNode(furnaceNode)
+		miner.AddNextNodeID(furnaceNode.ID())
+		return pipeline
+	}
+
+	run := func(seed uint64) *NodeResult {
+		engine, err := New(newChancePipeline(), Config{Duration: 200 * time.Second, Seed: seed})
+		require.NoError(t, err)
+		result, err := engine.Run(context.Background())
+		require.NoError(t, err)
+		return result.Nodes[2]
+	}
+
+	first := run(42)
+	assert.Equal(t, 99, first.Cycles)
+	assert.Greater(t, first.Produced[plate.ID()], 30.0)
+	assert.Less(t, first.Produced[plate.ID()], 70.0)
+
+	// Runs with the same seed are reproducible
+	assert.Equal(t, first.Produced, run(42).Produced)
+}
+
+func TestNewRejectsInvalidInput(t *testing.T) {
+	testCases := []struct {
+		name     string
+		pipeline *models.Pipeline
+		config   Config
+	}{
+		{
+			name:     "non-positive duration",
+			pipeline: newTestPipeline(1),
+			config:   Config{},
+		},
+		{
+			name:     "negative buffer capacity",
+			pipeline: newTestPipeline(1),
+			config:   Config{Duration: time.Second, BufferCapacity: -1},
+		},
+		{
+			name:     "negative sample interval",
+			pipeline: newTestPipeline(1),
+			config:   Config{Duration: time.Second, SampleInterval: -time.Second},
+		},
+		{
+			name: "negative node buffer",
+			pipeline: func() *models.Pipeline {
+				pipeline := models.NewPipeline("Test Pipeline", "")
+				node := models.NewPipelineNode(newMiner(), 1, 1)
+				node.SetBuffers(models.NewBufferCapacity(-1, 0))
+				pipeline.AddNode(node)
+				return pipeline
+			}(),
+			config: Config{Duration: time.Second},
+		},
+		{
+			name: "node without instances",
+			pipeline: func() *models.Pipeline {
+				pipeline := models.NewPipeline("Test Pipeline", "")
+				pipeline.AddNode(models.NewPipelineNode(newMiner(), 0, 1))
+				return pipeline
+			}(),
+			config: Config{Duration: time.Second},
+		},
+		{
+			name: "non-positive processing time",
+			pipeline: func() *models.Pipeline {
+				pipeline := models.NewPipeline("Test Pipeline", "")
+				pipeline.AddNode(models.NewPipelineNode(models.NewFacility("Broken", "", 0), 1, 1))
+				return pipeline
+			}(),
+			config: Config{Duration: time.Second},
+		},
+		{
+			name: "cycle time below one millisecond",
+			pipeline: func() *models.Pipeline {
+				pipeline := models.NewPipeline("Test Pipeline", "")
+				pipeline.AddNode(models.NewSourceNode(ore, 120000))
+				return pipeline
+			}(),
+			config: Config{Duration: time.Second},
+		},
+		{
+			name: "facility node without a facility",
+			pipeline: func() *models.Pipeline {
+				pipeline := models.NewPipeline("Test Pipeline", "")
+				pipeline.AddNode(models.NewPipelineNode(nil, 1, 1))
+				return pipeline
+			}(),
+			config: Config{Duration: time.Second},
+		},
+		{
+			name: "unlimited source feeding an unlimited sink",
+			pipeline: func() *models.Pipeline {
+				pipeline := models.NewPipeline("Test Pipeline", "")
+				source := models.NewSourceNode(ore, 0)
+				pipeline.AddNode(source)
+				sink := models.NewSinkNode(ore, 0)
+				pipeline.AddNode(sink)
+				source.AddNextNodeID(sink.ID())
+				return pipeline
+			}(),
+			config: Config{Duration: time.Second},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := New(tc.pipeline, tc.config)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestRunStopsWhenCancelled(t *testing.T) {
+	engine, err := New(newTestPipeline(1), Config{Duration: time.Hour})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = engine.Run(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestRunReportsTicks(t *testing.T) {
+	var ticks []*Tick
+	engine, err := New(newTestPipeline(1), Config{
+		Duration:       4 * time.Second,
+		SampleInterval: 2 * time.Second,
+		OnTick: func(tick *Tick) error {
+			ticks = append(ticks, tick)
+			return nil
+		},
+	})
+	require.NoError(t, err)
+
+	_, err = engine.Run(context.Background())
+	require.NoError(t, err)
+	require.Len(t, ticks, 3)
+
+	assert.Equal(t, time.Duration(0), ticks[0].Time)
+	require.Len(t, ticks[0].Nodes, 2)
+	assert.Equal(t, NodeStatusWorking, ticks[0].Nodes[0].Status)
+	assert.Equal(t, NodeStatusStarved, ticks[0].Nodes[1].Status)
+
+	tick := ticks[1]
+	assert.Equal(t, 2*time.Second, tick.Time)
+	miner, furnace := tick.Nodes[0], tick.Nodes[1]
+	assert.Equal(t, 1, miner.NodeID)
+	assert.Equal(t, 2, miner.Cycles)
+	assert.Equal(t, map[int]float64{ore.ID(): 2}, miner.Produced)
+	assert.Equal(t, NodeStatusWorking, furnace.Status)
+	assert.Equal(t, 1, furnace.Working)
+	assert.Equal(t, map[int]float64{ore.ID(): 1}, furnace.InputBuffer)
+	assert.Equal(t, map[int]float64{ore.ID(): 1}, furnace.Consumed)
+	require.Len(t, tick.Links, 1)
+	assert.Equal(t, 2.0, tick.Links[0].Delivered)
+}
+
+func TestRunStopsOnTickError(t *testing.T) {
+	stop := errors.New("stop")
+	engine, err := New(newTestPipeline(1), Config{
+		Duration:       time.Hour,
+		SampleInterval: time.Minute,
+		OnTick: func(tick *Tick) error {
+			if tick.Time > 0 {
+				return stop
+			}
+			return nil
+		},
+	})
+	require.NoError(t, err)
+
+	_, err = engine.Run(context.Background())
+	assert.ErrorIs(t, err, stop)
+}
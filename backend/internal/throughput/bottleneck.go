@@ -0,0 +1,139 @@
+package throughput
+
+import (
+	"errors"
+	"math"
+	"sort"
+
+	"github.com/fasim/backend/internal/models"
+)
+
+// NodeProvisioning compares the machines of a facility node with the machines needed to
+// process everything the node is offered
+type NodeProvisioning struct {
+	NodeID        int
+	FacilityID    int
+	InstanceCount int
+	Utilization   float64
+	// RequiredInstances is the number of machines needed to process all inputs offered to
+	// the node, including the supply it holds back, or for nodes without inputs, to yield
+	// what the downstream nodes can take. Nodes without inputs whose outputs all leave the
+	// pipeline need the machines they use. It is infinite when the supply is unbounded.
+	RequiredInstances float64
+	// SpareInstances is InstanceCount minus RequiredInstances, positive for over-provisioned
+	// nodes and negative for under-provisioned ones
+	SpareInstances float64
+	// OutputGain holds the change of the pipeline output in items per minute, keyed by item
+	// ID, if the node had one more machine. Items whose output does not change are left out.
+	OutputGain map[int]float64
+}
+
+// BottleneckReport tells which node limits the output of a pipeline and how the machines
+// of every facility node match its supply and demand
+type BottleneckReport struct {
+	// LimitingNodeID is the node that bounds the output of the pipeline, as in Result
+	LimitingNodeID int
+	// Output holds the items per minute leaving the pipeline, keyed by item ID
+	Output map[int]float64
+	// Nodes lists the facility nodes ordered by node ID; sources and sinks have no machines
+	Nodes []*NodeProvisioning
+}
+
+// AnalyzeBottlenecks computes the steady-state rates of a pipeline as
+// CalculateWithCapacity does, and for every facility node recalculates them once without
+// a limit on the rate of the node, to find what it is offered, and once with an additional
+// machine, to find what the machine would gain
+func AnalyzeBottlenecks(pipeline *models.Pipeline, capacity Capacity) (*BottleneckReport, error) {
+	base, err := CalculateWithCapacity(pipeline, capacity)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &BottleneckReport{
+		LimitingNodeID: base.LimitingNodeID,
+		Output:         base.Output,
+		Nodes:          make([]*NodeProvisioning, 0),
+	}
+	for id, node := range pipeline.Nodes() {
+		if node.Facility() == nil {
+			continue
+		}
+		rate := base.Nodes[id]
+		perInstance := rate.MaxCyclesPerMinute / float64(node.InstanceCount())
+
+		required, err := requiredCycles(pipeline, capacity, node, rate)
+		if err != nil {
+			return nil, err
+		}
+
+		expanded, err := calculate(pipeline, capacity, map[int]float64{id: rate.MaxCyclesPerMinute + perInstance})
+		if err != nil {
+			return nil, err
+		}
+		gain := make(map[int]float64)
+		for itemID := range itemIDs(base.Output, expanded.Output) {
+			if delta := expanded.Output[itemID] - base.Output[itemID]; math.Abs(delta) > epsilon {
+				gain[itemID] = delta
+			}
+		}
+
+		requiredInstances := required / perInstance
+		report.Nodes = append(report.Nodes, &NodeProvisioning{
+			NodeID:            id,
+			FacilityID:        rate.FacilityID,
+			InstanceCount:     node.InstanceCount(),
+			Utilization:       rate.Utilization,
+			RequiredInstances: requiredInstances,
+			SpareInstances:    float64(node.InstanceCount()) - requiredInstances,
+			OutputGain:        gain,
+		})
+	}
+	sort.Slice(report.Nodes, func(i, j int) bool {
+		return report.Nodes[i].NodeID < report.Nodes[j].NodeID
+	})
+	return report, nil
+}
+
+// requiredCycles returns the cycles per minute the node would run if it had unlimited
+// machines, or infinity if its supply is unbounded
+func requiredCycles(pipeline *models.Pipeline, capacity Capacity, node *models.PipelineNode, rate *NodeRate) (float64, error) {
+	unbounded, err := calculate(pipeline, capacity, map[int]float64{node.ID(): math.Inf(1)})
+	if errors.Is(err, ErrUnboundedFlow) {
+		return math.Inf(1), nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if len(node.InputRequirements()) > 0 {
+		return unbounded.Nodes[node.ID()].CyclesPerMinute, nil
+	}
+
+	// Nodes without inputs run as fast as the most demanded of their outputs is taken
+	delivered := make(map[int]float64)
+	for _, edge := range unbounded.Edges {
+		if edge.SourceNodeID == node.ID() {
+			delivered[edge.ItemID] += edge.PerMinute
+		}
+	}
+	if len(delivered) == 0 {
+		return rate.CyclesPerMinute, nil
+	}
+	required := 0.0
+	for _, output := range node.OutputDefinitions() {
+		if quantity := output.ExpectedQuantity(); quantity > 0 {
+			required = math.Max(required, delivered[output.Item().ID()]/quantity)
+		}
+	}
+	return required, nil
+}
+
+// itemIDs returns the set of item IDs keying any of the rates
+func itemIDs(rates ...map[int]float64) map[int]bool {
+	ids := make(map[int]bool)
+	for _, byItem := range rates {
+		for itemID := range byItem {
+			ids[itemID] = true
+		}
+	}
+	return ids
+}
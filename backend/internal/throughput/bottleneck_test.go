@@ -0,0 +1,88 @@
+package throughput
+
+import (
+	"math"
+	"testing"
+
+	"github.com/fasim/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyzeBottlenecks(t *testing.T) {
+	type expectedNode struct {
+		required float64
+		spare    float64
+		gain     map[int]float64
+	}
+
+	// sourcedPipeline feeds a furnace from a source without a rate limit
+	sourcedPipeline := models.NewPipeline("Test Pipeline", "")
+	source := models.NewSourceNode(ore, 0)
+	sourcedPipeline.AddNode(source)
+	furnace := models.NewPipelineNode(newFurnace(), 1, 1)
+	sourcedPipeline.AddNode(furnace)
+	source.AddNextNodeID(furnace.ID())
+
+	testCases := []struct {
+		name           string
+		pipeline       *models.Pipeline
+		limitingNodeID int
+		output         map[int]float64
+		expectedNodes  map[int]expectedNode
+	}{
+		{
+			name:           "slow consumer holds back supply",
+			pipeline:       newTestPipeline(1000, 1),
+			limitingNodeID: 2,
+			output:         map[int]float64{plate.ID(): 30},
+			expectedNodes: map[int]expectedNode{
+				1: {required: 0.5, spare: 0.5, gain: map[int]float64{}},
+				2: {required: 2, spare: -1, gain: map[int]float64{plate.ID(): 30}},
+			},
+		},
+		{
+			name:           "slow supplier starves consumer",
+			pipeline:       newTestPipeline(4000, 1),
+			limitingNodeID: 1,
+			output:         map[int]float64{plate.ID(): 15},
+			expectedNodes: map[int]expectedNode{
+				1: {required: 2, spare: -1, gain: map[int]float64{plate.ID(): 15}},
+				2: {required: 0.5, spare: 0.5, gain: map[int]float64{}},
+			},
+		},
+		{
+			name:           "unlimited source calls for unlimited machines",
+			pipeline:       sourcedPipeline,
+			limitingNodeID: 2,
+			output:         map[int]float64{plate.ID(): 30},
+			expectedNodes: map[int]expectedNode{
+				2: {required: math.Inf(1), spare: math.Inf(-1), gain: map[int]float64{plate.ID(): 30}},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			report, err := AnalyzeBottlenecks(tc.pipeline, Capacity{})
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.limitingNodeID, report.LimitingNodeID)
+			assert.InDeltaMapValues(t, tc.output, report.Output, 1e-9)
+			require.Len(t, report.Nodes, len(tc.expectedNodes))
+			for _, node := range report.Nodes {
+				expected, ok := tc.expectedNodes[node.NodeID]
+				require.True(t, ok, "unexpected node %d", node.NodeID)
+				assert.Equal(t, 1, node.InstanceCount)
+				if math.IsInf(expected.required, 0) {
+					assert.Equal(t, expected.required, node.RequiredInstances, "node %d", node.NodeID)
+					assert.Equal(t, expected.spare, node.SpareInstances, "node %d", node.NodeID)
+				} else {
+					assert.InDelta(t, expected.required, node.RequiredInstances, 1e-9, "node %d", node.NodeID)
+					assert.InDelta(t, expected.spare, node.SpareInstances, 1e-9, "node %d", node.NodeID)
+				}
+				assert.InDeltaMapValues(t, expected.gain, node.OutputGain, 1e-9, "node %d", node.NodeID)
+			}
+		})
+	}
+}
@@ -0,0 +1,326 @@
+package throughput
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"sort"
+
+	"github.com/fasim/backend/internal/loops"
+	"github.com/fasim/backend/internal/models"
+)
+
+// maxLoopIterations bounds the attempts to settle the rates of the nodes in a loop
+const maxLoopIterations = 100
+
+// components returns the strongly connected components of the node graph, ordered so that
+// every component follows all components supplying it. The nodes of a component are
+// ordered by node ID.
+func components(states []*nodeState) [][]*nodeState {
+	components := loops.Components(states, func(state *nodeState) []*nodeState {
+		return state.next
+	})
+	for _, component := range components {
+		sort.Slice(component, func(i, j int) bool {
+			return component[i].rate.NodeID < component[j].rate.NodeID
+		})
+	}
+	return components
+}
+
+// isLoop reports whether the nodes of a component pass items around a cycle
+func isLoop(component []*nodeState) bool {
+	return len(component) > 1 || slices.Contains(component[0].next, component[0])
+}
+
+// flowState holds what distribute changes, so that its routing can be tried out
+type flowState struct {
+	received        map[*nodeState]map[int]float64
+	suppliers       map[*nodeState]map[int][]*EdgeFlow
+	perMinute       map[*route]float64
+	perMinuteByKind map[*route]map[models.ItemKind]float64
+}
+
+// saveFlows records the flows into the targets of the nodes
+func saveFlows(component []*nodeState) *flowState {
+	saved := &flowState{
+		received:        make(map[*nodeState]map[int]float64),
+		suppliers:       make(map[*nodeState]map[int][]*EdgeFlow),
+		perMinute:       make(map[*route]float64),
+		perMinuteByKind: make(map[*route]map[models.ItemKind]float64),
+	}
+	for _, state := range component {
+		for _, r := range state.routes {
+			saved.received[r.target] = maps.Clone(r.target.received)
+			saved.suppliers[r.target] = maps.Clone(r.target.suppliers)
+			saved.perMinute[r] = r.perMinute
+			saved.perMinuteByKind[r] = maps.Clone(r.perMinuteByKind)
+		}
+	}
+	return saved
+}
+
+// restore resets the flows into the targets of the nodes to what was saved
+func (s *flowState) restore() {
+	for target, received := range s.received {
+		target.received = maps.Clone(received)
+	}
+	for target, suppliers := range s.suppliers {
+		target.suppliers = make(map[int][]*EdgeFlow, len(suppliers))
+		for itemID, flows := range suppliers {
+			target.suppliers[itemID] = slices.Clone(flows)
+		}
+	}
+	for r, perMinute := range s.perMinute {
+		r.perMinute = perMinute
+	}
+	for r, byKind := range s.perMinuteByKind {
+		clear(r.perMinuteByKind)
+		maps.Copy(r.perMinuteByKind, byKind)
+	}
+}
+
+// loop holds the nodes of a cycle of the pipeline while their rates are settled
+type loop struct {
+	nodes []*nodeState
+	// position maps node IDs to their index in nodes
+	position map[int]int
+	capacity Capacity
+	// outside holds the flows into the targets of the loop before the loop distributes
+	outside *flowState
+	// external holds, per node, the items per minute it receives from outside the loop
+	external []map[int]float64
+	// shares[i][itemID][j] is what a cycle of node j delivers of the item to node i
+	shares []map[int][]float64
+}
+
+// balanceLoop settles the rates of the nodes of a loop and distributes their outputs.
+// Within the loop, the flows form a linear system: each node either runs at capacity or
+// at the rate its limiting input supports, which is the supply from outside the loop plus
+// the share of the production of the loop nodes that the connections route to it. Nodes
+// start at capacity, and after distributing at the current rates, the node falling
+// furthest short of or beyond what it receives switches to being limited by its scarcest
+// input or back to capacity. The system is then solved with the shares just routed, until
+// every node runs at the rate its inputs support.
+func balanceLoop(component []*nodeState, capacity Capacity) ([]*EdgeFlow, error) {
+	n := len(component)
+	l := &loop{
+		nodes:    component,
+		position: make(map[int]int, n),
+		capacity: capacity,
+		outside:  saveFlows(component),
+		external: make([]map[int]float64, n),
+		shares:   make([]map[int][]float64, n),
+	}
+	for i, state := range component {
+		l.position[state.rate.NodeID] = i
+		l.external[i] = maps.Clone(state.received)
+		l.shares[i] = make(map[int][]float64)
+	}
+
+	// limiting holds the input item ID each node is limited by, or zero at capacity. Nodes
+	// without a rate limit are limited by their first input from the start.
+	cycles := make([]float64, n)
+	limiting := make([]int, n)
+	for i, state := range component {
+		cycles[i] = state.rate.MaxCyclesPerMinute
+		if math.IsInf(cycles[i], 1) {
+			inputs := positiveInputs(state)
+			if len(inputs) == 0 {
+				return nil, fmt.Errorf("%w: node %d supplies its loop without limit", ErrUnboundedFlow, state.rate.NodeID)
+			}
+			limiting[i] = inputs[0]
+		}
+	}
+	if slices.ContainsFunc(limiting, func(itemID int) bool { return itemID != 0 }) {
+		if err := l.route(cycles); err != nil {
+			return nil, err
+		}
+		solved, err := l.solve(limiting)
+		if err != nil {
+			return nil, err
+		}
+		cycles = solved
+	}
+
+	for iteration := 0; ; iteration++ {
+		if iteration == maxLoopIterations {
+			return nil, fmt.Errorf("%w: the rates of nodes %v do not settle", ErrCyclicPipeline, nodeIDs(l.nodes))
+		}
+		if err := l.route(cycles); err != nil {
+			return nil, err
+		}
+
+		settled := true
+		worst, worstItem, worstGap := -1, 0, 0.0
+		for i, state := range component {
+			supported, itemID := math.Inf(1), 0
+			for _, inputID := range positiveInputs(state) {
+				quantity, _ := inputQuantity(state.node, inputID)
+				if rate := state.received[inputID] / quantity; rate < supported-epsilon {
+					supported, itemID = rate, inputID
+				}
+			}
+
+			maxCycles := state.rate.MaxCyclesPerMinute
+			gap, switchTo := 0.0, limiting[i]
+			switch {
+			case limiting[i] != 0 && cycles[i] > maxCycles+tolerance(maxCycles):
+				gap, switchTo = (cycles[i]-maxCycles)/cycles[i], 0
+			case supported < cycles[i]-tolerance(cycles[i]):
+				gap, switchTo = (cycles[i]-supported)/cycles[i], itemID
+			case limiting[i] != 0:
+				quantity, _ := inputQuantity(state.node, limiting[i])
+				if state.received[limiting[i]]/quantity > cycles[i]+tolerance(cycles[i]) {
+					settled = false
+				}
+			}
+			if gap > 0 {
+				settled = false
+			}
+			if switchTo != limiting[i] && gap > worstGap {
+				worst, worstItem, worstGap = i, switchTo, gap
+			}
+		}
+		if worst >= 0 {
+			limiting[worst] = worstItem
+		} else if settled {
+			break
+		}
+
+		solved, err := l.solve(limiting)
+		if err != nil {
+			return nil, err
+		}
+		cycles = solved
+	}
+
+	l.outside.restore()
+	for i, state := range component {
+		rate := state.rate
+		rate.CyclesPerMinute = clamp(cycles[i])
+		rate.LimitingItemID = limiting[i]
+		rate.LimitedByNodeID = rate.NodeID
+		record(state)
+	}
+	flows := make([]*EdgeFlow, 0)
+	for _, state := range component {
+		distributed, err := distribute(state, capacity)
+		if err != nil {
+			return nil, err
+		}
+		flows = append(flows, distributed...)
+
+		if !state.delivers {
+			continue
+		}
+		left := maps.Clone(state.rate.Produced)
+		for _, flow := range distributed {
+			left[flow.ItemID] -= flow.PerMinute
+		}
+		for itemID, perMinute := range left {
+			if perMinute > epsilon {
+				state.leftover[itemID] = perMinute
+			}
+		}
+	}
+	return flows, nil
+}
+
+// route distributes the outputs of the loop at the given rates, leaving what every node
+// receives, and updates the shares of the nodes that run. Nodes without a rate limit are
+// routed at one cycle per minute, since only their shares are needed.
+func (l *loop) route(cycles []float64) error {
+	l.outside.restore()
+	for i, state := range l.nodes {
+		state.rate.CyclesPerMinute = cycles[i]
+		if math.IsInf(cycles[i], 1) {
+			state.rate.CyclesPerMinute = 1
+		}
+	}
+	for j, state := range l.nodes {
+		flows, err := distribute(state, l.capacity)
+		if err != nil {
+			return err
+		}
+		if state.rate.CyclesPerMinute <= epsilon {
+			// Nodes at rest keep the shares they were last routed
+			continue
+		}
+		for i := range l.shares {
+			for _, byNode := range l.shares[i] {
+				byNode[j] = 0
+			}
+		}
+		for _, flow := range flows {
+			i, ok := l.position[flow.TargetNodeID]
+			if !ok {
+				continue
+			}
+			if l.shares[i][flow.ItemID] == nil {
+				l.shares[i][flow.ItemID] = make([]float64, len(l.nodes))
+			}
+			l.shares[i][flow.ItemID][j] += flow.PerMinute / state.rate.CyclesPerMinute
+		}
+	}
+	return nil
+}
+
+// solve solves the linear system of the loop for the cycles per minute of its nodes. Nodes
+// at capacity run at their maximum rate, and limited nodes consume exactly what reaches
+// them of their limiting input.
+func (l *loop) solve(limiting []int) ([]float64, error) {
+	n := len(l.nodes)
+	system := make([][]float64, n)
+	for i, state := range l.nodes {
+		system[i] = make([]float64, n+1)
+		if limiting[i] == 0 {
+			system[i][i] = 1
+			system[i][n] = state.rate.MaxCyclesPerMinute
+			continue
+		}
+		quantity, _ := inputQuantity(state.node, limiting[i])
+		system[i][i] = quantity
+		for j, share := range l.shares[i][limiting[i]] {
+			system[i][j] -= share
+		}
+		system[i][n] = l.external[i][limiting[i]]
+	}
+
+	cycles, err := loops.Solve(system)
+	if err != nil {
+		return nil, fmt.Errorf("%w: the flows around nodes %v cannot be balanced", ErrCyclicPipeline, nodeIDs(l.nodes))
+	}
+	for i := range cycles {
+		if cycles[i] < -tolerance(0) {
+			// The loop yields more than it consumes, so only machines can hold it back
+			return nil, fmt.Errorf("%w: nodes %v gain items around their loop", ErrUnboundedFlow, nodeIDs(l.nodes))
+		}
+	}
+	return cycles, nil
+}
+
+// positiveInputs returns the IDs of the items the node consumes
+func positiveInputs(state *nodeState) []int {
+	ids := make([]int, 0)
+	for _, input := range state.node.InputRequirements() {
+		if input.Quantity() > 0 && !slices.Contains(ids, input.Item().ID()) {
+			ids = append(ids, input.Item().ID())
+		}
+	}
+	return ids
+}
+
+// tolerance scales epsilon to the magnitude of a rate
+func tolerance(rate float64) float64 {
+	return epsilon * math.Max(1, math.Abs(rate))
+}
+
+func nodeIDs(component []*nodeState) []int {
+	ids := make([]int, len(component))
+	for i, state := range component {
+		ids[i] = state.rate.NodeID
+	}
+	return ids
+}
@@ -0,0 +1,605 @@
+package throughput
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/fasim/backend/internal/models"
+)
+
+// epsilon absorbs floating point error when comparing rates
+const epsilon = 1e-9
+
+// millisecondsPerMinute converts processing times into cycles per minute
+const millisecondsPerMinute = 60 * 1000
+
+var (
+	// ErrCyclicPipeline is returned when the flows around a cycle of the pipeline graph
+	// cannot be balanced
+	ErrCyclicPipeline = errors.New("pipeline cycle cannot be balanced")
+	// ErrUnboundedFlow is returned when a source without a rate limit feeds a sink without
+	// a rate limit along a connection without a capacity
+	ErrUnboundedFlow = errors.New("flow is unbounded")
+)
+
+// NodeRate describes the steady-state operation of a pipeline node
+type NodeRate struct {
+	NodeID     int
+	FacilityID int
+	// MaxCyclesPerMinute is the rate the node reaches when it is never starved, summed
+	// over all machines of the node. It is infinite for sources and sinks without a rate
+	// limit, whose cycles move a single item.
+	MaxCyclesPerMinute float64
+	CyclesPerMinute    float64
+	// Utilization is zero for nodes without a rate limit
+	Utilization float64
+	// Produced and Consumed hold items per minute keyed by item ID
+	Produced map[int]float64
+	Consumed map[int]float64
+	// LimitingItemID is the input item that keeps the node below capacity, or zero
+	// when the node runs at capacity
+	LimitingItemID int
+	// LimitedByNodeID is the node whose capacity ultimately bounds this node's rate
+	LimitedByNodeID int
+}
+
+// EdgeFlow is the rate at which an item moves along a pipeline connection
+type EdgeFlow struct {
+	SourceNodeID int
+	TargetNodeID int
+	ItemID       int
+	PerMinute    float64
+}
+
+// LinkLoad is the use of the belt or pipe capacity along a pipeline connection
+type LinkLoad struct {
+	SourceNodeID int
+	TargetNodeID int
+	// Kind tells whether the load is on the belt or in the pipe of the connection
+	Kind              models.ItemKind
+	PerMinute         float64
+	CapacityPerMinute float64
+	// Saturated reports whether the flow uses up the capacity, so that the link would
+	// hold back any additional supply
+	Saturated bool
+}
+
+// ItemBalance summarizes the production and consumption of an item across the pipeline
+type ItemBalance struct {
+	ItemID            int
+	ProducedPerMinute float64
+	ConsumedPerMinute float64
+	// SurplusPerMinute is the part of the production that no node consumes
+	SurplusPerMinute float64
+	// DeficitPerMinute is the additional supply the nodes limited by this item would
+	// consume if they ran at capacity
+	DeficitPerMinute float64
+}
+
+// Result holds the steady-state rates of a pipeline
+type Result struct {
+	// Nodes is keyed by pipeline node ID
+	Nodes map[int]*NodeRate
+	Edges []*EdgeFlow
+	// Links lists the load of every capacity-limited connection, ordered by source and target node ID
+	Links []*LinkLoad
+	// Items is keyed by item ID
+	Items map[int]*ItemBalance
+	// LimitingNodeID is the node that bounds the output of the pipeline, or zero for an empty pipeline
+	LimitingNodeID int
+	// Output holds the items per minute leaving the pipeline, keyed by item ID: the items
+	// produced by nodes delivering nothing downstream, the items taken by such sinks and
+	// the items loops produce that no connection takes
+	Output map[int]float64
+}
+
+// Capacity limits the flow along every connection of a pipeline, in units per minute.
+// Zero means unlimited.
+type Capacity struct {
+	// Belt is shared by all solid items moving along a connection
+	Belt float64
+	// Pipe is shared by all fluids moving along a connection
+	Pipe float64
+}
+
+// of returns the capacity of the transport moving items of the given kind
+func (c Capacity) of(kind models.ItemKind) float64 {
+	if kind == models.ItemKindFluid {
+		return c.Pipe
+	}
+	return c.Belt
+}
+
+// route is a connection whose target node exists in the pipeline
+type route struct {
+	conn   *models.PipelineConnection
+	target *nodeState
+	// perMinute is the total flow of all items along the connection
+	perMinute float64
+	// perMinuteByKind splits perMinute into solids on the belt and fluids in the pipe
+	perMinuteByKind map[models.ItemKind]float64
+}
+
+// capacityOf returns the capacity of the route for items of the given kind, which is set
+// by the transport of the connection when it moves such items and by the defaults otherwise
+func (r *route) capacityOf(kind models.ItemKind, defaults Capacity) float64 {
+	if transport := r.conn.Transport(); transport != nil && transport.Moves(kind) {
+		return transport.ItemsPerMinute()
+	}
+	return defaults.of(kind)
+}
+
+type nodeState struct {
+	node   *models.PipelineNode
+	rate   *NodeRate
+	next   []*nodeState
+	routes []*route
+	// received holds incoming items per minute keyed by item ID
+	received map[int]float64
+	// suppliers lists, per input item ID, the incoming flows of the item
+	suppliers map[int][]*EdgeFlow
+	// delivers reports whether any output of the node is consumed downstream
+	delivers bool
+	// leftover holds, for delivering nodes of a loop, the items per minute they produce
+	// that no connection takes, keyed by item ID
+	leftover map[int]float64
+}
+
+// Calculate computes the steady-state rates of every node, connection and item in a
+// pipeline without limiting the capacity of belts and pipes
+func Calculate(pipeline *models.Pipeline) (*Result, error) {
+	return CalculateWithCapacity(pipeline, Capacity{})
+}
+
+// CalculateWithCapacity computes the steady-state rates of every node, connection and item
+// in a pipeline. Nodes run as fast as their inputs allow, up to the rate given by
+// the processing time of their facility or the rate of sources and sinks. Sources without
+// a rate limit supply what the connected nodes can take. Outputs with a probability yield
+// their expected quantity. Outputs are routed along the connections as described for distribute; what no
+// connection takes is surplus. The given capacity applies to connections without a
+// transport for the items they move. Nodes passing items around a cycle, such as a recycling
+// loop, are solved together as described for balanceLoop.
+func CalculateWithCapacity(pipeline *models.Pipeline, capacity Capacity) (*Result, error) {
+	return calculate(pipeline, capacity, nil)
+}
+
+// calculate implements CalculateWithCapacity, replacing the maximum rate of the nodes
+// with the IDs keying maxCycles by the given rate
+func calculate(pipeline *models.Pipeline, capacity Capacity, maxCycles map[int]float64) (*Result, error) {
+	if capacity.Belt < 0 || capacity.Pipe < 0 {
+		return nil, fmt.Errorf("belt and pipe capacities must not be negative")
+	}
+
+	states, err := newNodeStates(pipeline, maxCycles)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{
+		Nodes:  make(map[int]*NodeRate, len(states)),
+		Edges:  make([]*EdgeFlow, 0),
+		Links:  make([]*LinkLoad, 0),
+		Items:  make(map[int]*ItemBalance),
+		Output: make(map[int]float64),
+	}
+	order := make([]*nodeState, 0, len(states))
+	for _, component := range components(states) {
+		order = append(order, component...)
+		if isLoop(component) {
+			flows, err := balanceLoop(component, capacity)
+			if err != nil {
+				return nil, err
+			}
+			result.Edges = append(result.Edges, flows...)
+			for _, state := range component {
+				result.Nodes[state.rate.NodeID] = state.rate
+			}
+			continue
+		}
+
+		state := component[0]
+		operate(state)
+		flows, err := distribute(state, capacity)
+		if err != nil {
+			return nil, err
+		}
+		if math.IsInf(state.rate.CyclesPerMinute, 1) {
+			supplyOnDemand(state, flows)
+		}
+		result.Edges = append(result.Edges, flows...)
+		result.Nodes[state.rate.NodeID] = state.rate
+	}
+
+	for _, state := range order {
+		for itemID, perMinute := range state.rate.Produced {
+			balance(result, itemID).ProducedPerMinute += perMinute
+		}
+		for _, input := range state.node.InputRequirements() {
+			itemID := input.Item().ID()
+			balance(result, itemID).ConsumedPerMinute += state.rate.Consumed[itemID]
+			if itemID == state.rate.LimitingItemID && !math.IsInf(state.rate.MaxCyclesPerMinute, 1) {
+				shortfall := (state.rate.MaxCyclesPerMinute - state.rate.CyclesPerMinute) * input.Quantity()
+				balance(result, itemID).DeficitPerMinute += shortfall
+			}
+		}
+	}
+	for _, item := range result.Items {
+		item.SurplusPerMinute = clamp(item.ProducedPerMinute - item.ConsumedPerMinute)
+	}
+	result.Links = linkLoads(states, capacity)
+
+	for _, state := range order {
+		for itemID, perMinute := range state.leftover {
+			result.Output[itemID] += perMinute
+		}
+		if state.delivers {
+			continue
+		}
+		left := state.rate.Produced
+		if state.node.Kind() == models.NodeKindSink {
+			left = state.rate.Consumed
+		}
+		for itemID, perMinute := range left {
+			if perMinute > epsilon {
+				result.Output[itemID] += perMinute
+			}
+		}
+	}
+
+	result.LimitingNodeID = limitingNode(order)
+	return result, nil
+}
+
+func newNodeStates(pipeline *models.Pipeline, maxCycles map[int]float64) ([]*nodeState, error) {
+	byID := make(map[int]*nodeState, len(pipeline.Nodes()))
+	states := make([]*nodeState, 0, len(pipeline.Nodes()))
+	for id, node := range pipeline.Nodes() {
+		facilityID := 0
+		if facility := node.Facility(); facility != nil {
+			if facility.ProcessingTime() <= 0 {
+				return nil, fmt.Errorf("facility %q of node %d has a non-positive processing time", facility.Name(), id)
+			}
+			facilityID = facility.ID()
+		} else if !node.IsBoundary() {
+			return nil, fmt.Errorf("node %d has no facility", id)
+		}
+		if node.InstanceCount() < 1 || node.ClockSpeed() <= 0 {
+			return nil, fmt.Errorf("node %d needs at least one instance and a positive clock speed", id)
+		}
+		maxCyclesPerMinute := math.Inf(1)
+		if node.CycleTime() > 0 {
+			maxCyclesPerMinute = float64(node.InstanceCount()) * millisecondsPerMinute / node.CycleTime()
+		}
+		if override, ok := maxCycles[id]; ok {
+			maxCyclesPerMinute = override
+		}
+		state := &nodeState{
+			node: node,
+			rate: &NodeRate{
+				NodeID:             id,
+				FacilityID:         facilityID,
+				MaxCyclesPerMinute: maxCyclesPerMinute,
+				Produced:           make(map[int]float64),
+				Consumed:           make(map[int]float64),
+			},
+			received:  make(map[int]float64),
+			suppliers: make(map[int][]*EdgeFlow),
+			leftover:  make(map[int]float64),
+		}
+		byID[id] = state
+		states = append(states, state)
+	}
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].rate.NodeID < states[j].rate.NodeID
+	})
+
+	for _, state := range states {
+		for _, nextID := range state.node.NextNodeIDs() {
+			if next, ok := byID[nextID]; ok {
+				state.next = append(state.next, next)
+			}
+		}
+		for _, conn := range state.node.Connections() {
+			if target, ok := byID[conn.TargetNodeID()]; ok {
+				state.routes = append(state.routes, &route{
+					conn:            conn,
+					target:          target,
+					perMinuteByKind: make(map[models.ItemKind]float64),
+				})
+			}
+		}
+		sort.Slice(state.next, func(i, j int) bool {
+			return state.next[i].rate.NodeID < state.next[j].rate.NodeID
+		})
+	}
+	return states, nil
+}
+
+// operate determines the rate of a node from the inputs it receives
+func operate(state *nodeState) {
+	rate := state.rate
+	rate.CyclesPerMinute = rate.MaxCyclesPerMinute
+	rate.LimitedByNodeID = rate.NodeID
+
+	node := state.node
+	for _, input := range node.InputRequirements() {
+		if input.Quantity() <= 0 {
+			continue
+		}
+		itemID := input.Item().ID()
+		supported := state.received[itemID] / input.Quantity()
+		if supported < rate.CyclesPerMinute-epsilon {
+			rate.CyclesPerMinute = supported
+			rate.LimitingItemID = itemID
+		}
+	}
+
+	record(state)
+}
+
+// record sets the utilization of a node and the items it consumes and produces from its
+// cycles per minute
+func record(state *nodeState) {
+	rate := state.rate
+	node := state.node
+	rate.Utilization = rate.CyclesPerMinute / rate.MaxCyclesPerMinute
+	if math.IsInf(rate.MaxCyclesPerMinute, 1) {
+		rate.Utilization = 0
+	}
+	for _, input := range node.InputRequirements() {
+		rate.Consumed[input.Item().ID()] += rate.CyclesPerMinute * input.Quantity()
+	}
+	for _, output := range node.OutputDefinitions() {
+		rate.Produced[output.Item().ID()] += rate.CyclesPerMinute * output.ExpectedQuantity()
+	}
+}
+
+// supplyOnDemand sets the rate of a source without a rate limit to what its connections take
+func supplyOnDemand(state *nodeState, flows []*EdgeFlow) {
+	rate := state.rate
+	rate.CyclesPerMinute = 0
+	rate.Produced = make(map[int]float64)
+	for _, flow := range flows {
+		rate.CyclesPerMinute += flow.PerMinute
+		rate.Produced[flow.ItemID] += flow.PerMinute
+	}
+}
+
+// distribute splits the outputs of a node among the connections leading to nodes that
+// consume them. Connections first receive the share of the output reserved by their ratio,
+// then the rest is offered to connections in descending priority order, splitting it within
+// a priority in proportion to the remaining demand of the downstream nodes. No connection
+// receives more than its downstream node can consume, its maximum throughput allows or fits
+// on its belt or into its pipe. Downstream nodes consuming without limit split what they are
+// offered evenly.
+func distribute(state *nodeState, transport Capacity) ([]*EdgeFlow, error) {
+	flows := make([]*EdgeFlow, 0)
+	for _, output := range state.node.OutputDefinitions() {
+		itemID := output.Item().ID()
+		kind := models.ItemKindSolid
+		if output.Item().IsFluid() {
+			kind = models.ItemKindFluid
+		}
+
+		routes := make([]*route, 0, len(state.routes))
+		for _, r := range state.routes {
+			if _, ok := inputQuantity(r.target.node, itemID); ok && r.conn.Carries(itemID) {
+				routes = append(routes, r)
+			}
+		}
+
+		produced := output.ExpectedQuantity() * state.rate.CyclesPerMinute
+		supply := produced
+		allocated := make([]float64, len(routes))
+		capacity := func(i int) float64 {
+			r := routes[i]
+			quantity, _ := inputQuantity(r.target.node, itemID)
+			limit := clamp(r.target.rate.MaxCyclesPerMinute*quantity - r.target.received[itemID] - allocated[i])
+			if r.conn.MaxThroughput() > 0 {
+				limit = math.Min(limit, clamp(r.conn.MaxThroughput()-r.perMinute-allocated[i]))
+			}
+			if limitOfKind := r.capacityOf(kind, transport); limitOfKind > 0 {
+				limit = math.Min(limit, clamp(limitOfKind-r.perMinuteByKind[kind]-allocated[i]))
+			}
+			return limit
+		}
+
+		unbounded := func(i int) error {
+			return fmt.Errorf("%w: node %d supplies node %d without limit", ErrUnboundedFlow,
+				state.rate.NodeID, routes[i].target.rate.NodeID)
+		}
+
+		for i, r := range routes {
+			if r.conn.Ratio() > 0 {
+				allocated[i] = math.Min(r.conn.Ratio()*produced, capacity(i))
+				if math.IsInf(allocated[i], 1) {
+					return nil, unbounded(i)
+				}
+				supply -= allocated[i]
+			}
+		}
+
+		for _, group := range priorityGroups(routes) {
+			capacities := make([]float64, len(group))
+			total := 0.0
+			unlimited := 0
+			for j, i := range group {
+				capacities[j] = capacity(i)
+				total += capacities[j]
+				if math.IsInf(capacities[j], 1) {
+					unlimited++
+				}
+			}
+			if total <= epsilon || supply <= epsilon {
+				continue
+			}
+			if unlimited > 0 {
+				for j, i := range group {
+					if !math.IsInf(capacities[j], 1) {
+						continue
+					}
+					if math.IsInf(supply, 1) {
+						return nil, unbounded(i)
+					}
+					allocated[i] += supply / float64(unlimited)
+				}
+				supply = 0
+				continue
+			}
+			share := 1.0
+			if !math.IsInf(supply, 1) {
+				share = math.Min(1, supply/total)
+			}
+			for j, i := range group {
+				allocated[i] += capacities[j] * share
+			}
+			supply -= total * share
+		}
+
+		for i, r := range routes {
+			flow := &EdgeFlow{
+				SourceNodeID: state.rate.NodeID,
+				TargetNodeID: r.target.rate.NodeID,
+				ItemID:       itemID,
+				PerMinute:    allocated[i],
+			}
+			r.perMinute += allocated[i]
+			r.perMinuteByKind[kind] += allocated[i]
+			r.target.received[itemID] += allocated[i]
+			r.target.suppliers[itemID] = append(r.target.suppliers[itemID], flow)
+			flows = append(flows, flow)
+		}
+	}
+	state.delivers = len(flows) > 0
+	return flows, nil
+}
+
+// linkLoads reports the load of every connection whose belt or pipe limits the flow of the
+// items it moves
+func linkLoads(states []*nodeState, defaults Capacity) []*LinkLoad {
+	links := make([]*LinkLoad, 0)
+	for _, state := range states {
+		for _, r := range state.routes {
+			for _, kind := range []models.ItemKind{models.ItemKindSolid, models.ItemKindFluid} {
+				perMinute, moved := r.perMinuteByKind[kind]
+				capacity := r.capacityOf(kind, defaults)
+				if !moved || capacity <= 0 {
+					continue
+				}
+				links = append(links, &LinkLoad{
+					SourceNodeID:      state.rate.NodeID,
+					TargetNodeID:      r.target.rate.NodeID,
+					Kind:              kind,
+					PerMinute:         perMinute,
+					CapacityPerMinute: capacity,
+					Saturated:         perMinute >= capacity-epsilon,
+				})
+			}
+		}
+	}
+	sort.SliceStable(links, func(i, j int) bool {
+		if links[i].SourceNodeID != links[j].SourceNodeID {
+			return links[i].SourceNodeID < links[j].SourceNodeID
+		}
+		return links[i].TargetNodeID < links[j].TargetNodeID
+	})
+	return links
+}
+
+// priorityGroups groups route indices by connection priority, highest priority first
+func priorityGroups(routes []*route) [][]int {
+	byPriority := make(map[int][]int)
+	priorities := make([]int, 0)
+	for i, r := range routes {
+		priority := r.conn.Priority()
+		if _, ok := byPriority[priority]; !ok {
+			priorities = append(priorities, priority)
+		}
+		byPriority[priority] = append(byPriority[priority], i)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(priorities)))
+
+	groups := make([][]int, len(priorities))
+	for i, priority := range priorities {
+		groups[i] = byPriority[priority]
+	}
+	return groups
+}
+
+// limitingNode finds the node bounding the pipeline output by following the limiting
+// inputs of the terminal nodes, which deliver nothing downstream or leave part of the
+// production of a loop over, back to their source
+func limitingNode(order []*nodeState) int {
+	byID := make(map[int]*nodeState, len(order))
+	for _, state := range order {
+		byID[state.rate.NodeID] = state
+	}
+
+	// A chain of limits leading around a loop ends at the node it started from
+	resolved := make(map[*nodeState]bool, len(order))
+	var resolve func(state *nodeState) int
+	resolve = func(state *nodeState) int {
+		rate := state.rate
+		if resolved[state] || rate.LimitingItemID == 0 {
+			return rate.LimitedByNodeID
+		}
+		resolved[state] = true
+		var main *EdgeFlow
+		for _, flow := range state.suppliers[rate.LimitingItemID] {
+			if main == nil || flow.PerMinute > main.PerMinute {
+				main = flow
+			}
+		}
+		if main != nil {
+			rate.LimitedByNodeID = resolve(byID[main.SourceNodeID])
+		}
+		return rate.LimitedByNodeID
+	}
+	for _, state := range order {
+		resolve(state)
+	}
+
+	counts := make(map[int]int)
+	limitingNodeID := 0
+	for _, state := range order {
+		if state.delivers && len(state.leftover) == 0 {
+			continue
+		}
+
+		limitedBy := state.rate.LimitedByNodeID
+		counts[limitedBy]++
+		if limitingNodeID == 0 || counts[limitedBy] > counts[limitingNodeID] ||
+			(counts[limitedBy] == counts[limitingNodeID] && limitedBy < limitingNodeID) {
+			limitingNodeID = limitedBy
+		}
+	}
+	return limitingNodeID
+}
+
+func inputQuantity(node *models.PipelineNode, itemID int) (float64, bool) {
+	for _, input := range node.InputRequirements() {
+		if input.Item().ID() == itemID {
+			return input.Quantity(), true
+		}
+	}
+	return 0, false
+}
+
+func balance(result *Result, itemID int) *ItemBalance {
+	item, ok := result.Items[itemID]
+	if !ok {
+		item = &ItemBalance{ItemID: itemID}
+		result.Items[itemID] = item
+	}
+	return item
+}
+
+// clamp rounds values within floating point error of zero, including negatives, to zero
+func clamp(value float64) float64 {
+	if value < epsilon {
+		return 0
+	}
+	return value
+}
@@ -0,0 +1,579 @@
+package throughput
+
+import (
+	"testing"
+
+	"github.com/fasim/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	ore   = models.NewItemFromParams(1, "Ore", "", models.ItemKindSolid)
+	plate = models.NewItemFromParams(2, "Plate", "", models.ItemKindSolid)
+)
+
+func newMiner(processingTime int64) *models.Facility {
+	miner := models.NewFacility("Miner", "", processingTime)
+	miner.AddOutputDefinition(models.NewOutputDefinition(ore, 1))
+	return miner
+}
+
+func newFurnace() *models.Facility {
+	furnace := models.NewFacility("Furnace", "", 2000)
+	furnace.AddInputRequirement(models.NewInputRequirement(ore, 1))
+	furnace.AddOutputDefinition(models.NewOutputDefinition(plate, 1))
+	return furnace
+}
+
+// newTestPipeline connects a miner to the given number of furnaces.
+// The miner gets node ID 1 and the furnaces get IDs from 2 upwards.
+func newTestPipeline(minerTime int64, furnaces int) *models.Pipeline {
+	pipeline := models.NewPipeline("Test Pipeline", "")
+	miner := models.NewPipelineNode(newMiner(minerTime), 1, 1)
+	pipeline.AddNode(miner)
+	for i := 0; i < furnaces; i++ {
+		furnace := models.NewPipelineNode(newFurnace(), 1, 1)
+		pipeline.AddNode(furnace)
+		miner.AddNextNodeID(furnace.ID())
+	}
+	return pipeline
+}
+
+// newRoutedPipeline connects a miner to one furnace per connection, retargeting the
+// connections so that the furnaces get node IDs from 2 upwards in the given order
+func newRoutedPipeline(minerTime int64, conns ...*models.PipelineConnection) *models.Pipeline {
+	pipeline := models.NewPipeline("Test Pipeline", "")
+	miner := models.NewPipelineNode(newMiner(minerTime), 1, 1)
+	pipeline.AddNode(miner)
+	for _, conn := range conns {
+		furnace := models.NewPipelineNode(newFurnace(), 1, 1)
+		pipeline.AddNode(furnace)
+		miner.AddConnection(models.NewPipelineConnection(furnace.ID(), conn.Item(), conn.Ratio(), conn.Priority(), conn.MaxThroughput(), conn.Transport()))
+	}
+	return pipeline
+}
+
+func TestCalculate(t *testing.T) {
+	type expectedNode struct {
+		cyclesPerMinute float64
+		utilization     float64
+		limitingItemID  int
+		limitedByNodeID int
+	}
+
+	testCases := []struct {
+		name           string
+		pipeline       *models.Pipeline
+		expectedNodes  map[int]expectedNode
+		expectedEdges  map[int]float64 // Keyed by target node ID
+		expectedItems  map[int]ItemBalance
+		limitingNodeID int
+	}{
+		{
+			name:     "fast supplier leaves surplus",
+			pipeline: newTestPipeline(1000, 1),
+			expectedNodes: map[int]expectedNode{
+				1: {cyclesPerMinute: 60, utilization: 1, limitedByNodeID: 1},
+				2: {cyclesPerMinute: 30, utilization: 1, limitedByNodeID: 2},
+			},
+			expectedEdges: map[int]float64{2: 30},
+			expectedItems: map[int]ItemBalance{
+				1: {ItemID: 1, ProducedPerMinute: 60, ConsumedPerMinute: 30, SurplusPerMinute: 30},
+				2: {ItemID: 2, ProducedPerMinute: 30, SurplusPerMinute: 30},
+			},
+			limitingNodeID: 2,
+		},
+		{
+			name:     "slow supplier starves consumer",
+			pipeline: newTestPipeline(4000, 1),
+			expectedNodes: map[int]expectedNode{
+				1: {cyclesPerMinute: 15, utilization: 1, limitedByNodeID: 1},
+				2: {cyclesPerMinute: 15, utilization: 0.5, limitingItemID: 1, limitedByNodeID: 1},
+			},
+			expectedEdges: map[int]float64{2: 15},
+			expectedItems: map[int]ItemBalance{
+				1: {ItemID: 1, ProducedPerMinute: 15, ConsumedPerMinute: 15, DeficitPerMinute: 15},
+				2: {ItemID: 2, ProducedPerMinute: 15, SurplusPerMinute: 15},
+			},
+			limitingNodeID: 1,
+		},
+		{
+			name:     "parallel consumers share supply",
+			pipeline: newTestPipeline(3000, 2),
+			expectedNodes: map[int]expectedNode{
+				1: {cyclesPerMinute: 20, utilization: 1, limitedByNodeID: 1},
+				2: {cyclesPerMinute: 10, utilization: 1.0 / 3, limitingItemID: 1, limitedByNodeID: 1},
+				3: {cyclesPerMinute: 10, utilization: 1.0 / 3, limitingItemID: 1, limitedByNodeID: 1},
+			},
+			expectedEdges: map[int]float64{2: 10, 3: 10},
+			expectedItems: map[int]ItemBalance{
+				1: {ItemID: 1, ProducedPerMinute: 20, ConsumedPerMinute: 20, DeficitPerMinute: 40},
+				2: {ItemID: 2, ProducedPerMinute: 20, SurplusPerMinute: 20},
+			},
+			limitingNodeID: 1,
+		},
+		{
+			name: "instances and clock speed scale capacity",
+			pipeline: func() *models.Pipeline {
+				pipeline := models.NewPipeline("Test Pipeline", "")
+				miner := models.NewPipelineNode(newMiner(1000), 1, 2)
+				pipeline.AddNode(miner)
+				furnace := models.NewPipelineNode(newFurnace(), 2, 1)
+				pipeline.AddNode(furnace)
+				miner.AddNextNodeID(furnace.ID())
+				return pipeline
+			}(),
+			expectedNodes: map[int]expectedNode{
+				1: {cyclesPerMinute: 120, utilization: 1, limitedByNodeID: 1},
+				2: {cyclesPerMinute: 60, utilization: 1, limitedByNodeID: 2},
+			},
+			expectedEdges: map[int]float64{2: 60},
+			expectedItems: map[int]ItemBalance{
+				1: {ItemID: 1, ProducedPerMinute: 120, ConsumedPerMinute: 60, SurplusPerMinute: 60},
+				2: {ItemID: 2, ProducedPerMinute: 60, SurplusPerMinute: 60},
+			},
+			limitingNodeID: 2,
+		},
+		{
+			name: "ratios split supply",
+			pipeline: newRoutedPipeline(1500,
+				models.NewPipelineConnection(0, ore, 0.25, 0, 0, nil),
+				models.NewPipelineConnection(0, ore, 0.75, 0, 0, nil),
+			),
+			expectedNodes: map[int]expectedNode{
+				1: {cyclesPerMinute: 40, utilization: 1, limitedByNodeID: 1},
+				2: {cyclesPerMinute: 10, utilization: 1.0 / 3, limitingItemID: 1, limitedByNodeID: 1},
+				3: {cyclesPerMinute: 30, utilization: 1, limitedByNodeID: 3},
+			},
+			expectedEdges: map[int]float64{2: 10, 3: 30},
+			expectedItems: map[int]ItemBalance{
+				1: {ItemID: 1, ProducedPerMinute: 40, ConsumedPerMinute: 40, DeficitPerMinute: 20},
+				2: {ItemID: 2, ProducedPerMinute: 40, SurplusPerMinute: 40},
+			},
+			limitingNodeID: 1,
+		},
+		{
+			name: "higher priority is served first",
+			pipeline: newRoutedPipeline(1500,
+				models.NewPipelineConnection(0, nil, 0, 0, 0, nil),
+				models.NewPipelineConnection(0, nil, 0, 1, 0, nil),
+			),
+			expectedNodes: map[int]expectedNode{
+				1: {cyclesPerMinute: 40, utilization: 1, limitedByNodeID: 1},
+				2: {cyclesPerMinute: 10, utilization: 1.0 / 3, limitingItemID: 1, limitedByNodeID: 1},
+				3: {cyclesPerMinute: 30, utilization: 1, limitedByNodeID: 3},
+			},
+			expectedEdges: map[int]float64{2: 10, 3: 30},
+			expectedItems: map[int]ItemBalance{
+				1: {ItemID: 1, ProducedPerMinute: 40, ConsumedPerMinute: 40, DeficitPerMinute: 20},
+				2: {ItemID: 2, ProducedPerMinute: 40, SurplusPerMinute: 40},
+			},
+			limitingNodeID: 1,
+		},
+		{
+			name: "max throughput caps connection",
+			pipeline: newRoutedPipeline(1000,
+				models.NewPipelineConnection(0, nil, 0, 0, 12, nil),
+				models.NewPipelineConnection(0, nil, 0, 0, 0, nil),
+			),
+			expectedNodes: map[int]expectedNode{
+				1: {cyclesPerMinute: 60, utilization: 1, limitedByNodeID: 1},
+				2: {cyclesPerMinute: 12, utilization: 0.4, limitingItemID: 1, limitedByNodeID: 1},
+				3: {cyclesPerMinute: 30, utilization: 1, limitedByNodeID: 3},
+			},
+			expectedEdges: map[int]float64{2: 12, 3: 30},
+			expectedItems: map[int]ItemBalance{
+				1: {ItemID: 1, ProducedPerMinute: 60, ConsumedPerMinute: 42, SurplusPerMinute: 18, DeficitPerMinute: 18},
+				2: {ItemID: 2, ProducedPerMinute: 42, SurplusPerMinute: 42},
+			},
+			limitingNodeID: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := Calculate(tc.pipeline)
+			require.NoError(t, err)
+
+			require.Len(t, result.Nodes, len(tc.expectedNodes))
+			for nodeID, expected := range tc.expectedNodes {
+				node := result.Nodes[nodeID]
+				assert.InDelta(t, expected.cyclesPerMinute, node.CyclesPerMinute, 1e-9, "cycles of node %d", nodeID)
+				assert.InDelta(t, expected.utilization, node.Utilization, 1e-9, "utilization of node %d", nodeID)
+				assert.Equal(t, expected.limitingItemID, node.LimitingItemID, "limiting item of node %d", nodeID)
+				assert.Equal(t, expected.limitedByNodeID, node.LimitedByNodeID, "limiting node of node %d", nodeID)
+			}
+
+			require.Len(t, result.Edges, len(tc.expectedEdges))
+			for _, edge := range result.Edges {
+				assert.Equal(t, 1, edge.SourceNodeID)
+				assert.Equal(t, ore.ID(), edge.ItemID)
+				assert.InDelta(t, tc.expectedEdges[edge.TargetNodeID], edge.PerMinute, 1e-9, "flow into node %d", edge.TargetNodeID)
+			}
+
+			require.Len(t, result.Items, len(tc.expectedItems))
+			for itemID, expected := range tc.expectedItems {
+				item := result.Items[itemID]
+				assert.InDelta(t, expected.ProducedPerMinute, item.ProducedPerMinute, 1e-9, "production of item %d", itemID)
+				assert.InDelta(t, expected.ConsumedPerMinute, item.ConsumedPerMinute, 1e-9, "consumption of item %d", itemID)
+				assert.InDelta(t, expected.SurplusPerMinute, item.SurplusPerMinute, 1e-9, "surplus of item %d", itemID)
+				assert.InDelta(t, expected.DeficitPerMinute, item.DeficitPerMinute, 1e-9, "deficit of item %d", itemID)
+			}
+
+			assert.Equal(t, tc.limitingNodeID, result.LimitingNodeID)
+		})
+	}
+}
+
+func TestCalculateWithCapacity(t *testing.T) {
+	crude := models.NewItemFromParams(3, "Crude Oil", "", models.ItemKindFluid)
+	plastic := models.NewItemFromParams(4, "Plastic", "", models.ItemKindSolid)
+
+	// newOilPipeline pumps 150 units of crude oil per minute into a refinery consuming
+	// up to 120 units per minute
+	newOilPipeline := func() *models.Pipeline {
+		pump := models.NewFacility("Pump", "", 1000)
+		pump.AddOutputDefinition(models.NewOutputDefinition(crude, 2.5))
+		refinery := models.NewFacility("Refinery", "", 5000)
+		refinery.AddInputRequirement(models.NewInputRequirement(crude, 10))
+		refinery.AddOutputDefinition(models.NewOutputDefinition(plastic, 1))
+
+		pipeline := models.NewPipeline("Oil", "")
+		pumpNode := models.NewPipelineNode(pump, 1, 1)
+		pipeline.AddNode(pumpNode)
+		refineryNode := models.NewPipelineNode(refinery, 1, 1)
+		pipeline.AddNode(refineryNode)
+		pumpNode.AddNextNodeID(refineryNode.ID())
+		return pipeline
+	}
+
+	testCases := []struct {
+		name            string
+		pipeline        *models.Pipeline
+		capacity        Capacity
+		expectedFlow    float64
+		cyclesPerMinute float64
+	}{
+		{
+			name:            "unlimited transport",
+			pipeline:        newOilPipeline(),
+			expectedFlow:    120,
+			cyclesPerMinute: 12,
+		},
+		{
+			name:            "belt capacity does not limit fluids",
+			pipeline:        newOilPipeline(),
+			capacity:        Capacity{Belt: 50},
+			expectedFlow:    120,
+			cyclesPerMinute: 12,
+		},
+		{
+			name:            "pipe capacity limits fluids",
+			pipeline:        newOilPipeline(),
+			capacity:        Capacity{Pipe: 90},
+			expectedFlow:    90,
+			cyclesPerMinute: 9,
+		},
+		{
+			name:            "belt capacity limits solids",
+			pipeline:        newTestPipeline(1000, 1),
+			capacity:        Capacity{Belt: 20, Pipe: 10},
+			expectedFlow:    20,
+			cyclesPerMinute: 20,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := CalculateWithCapacity(tc.pipeline, tc.capacity)
+			require.NoError(t, err)
+
+			require.Len(t, result.Edges, 1)
+			assert.InDelta(t, tc.expectedFlow, result.Edges[0].PerMinute, 1e-9)
+			assert.InDelta(t, tc.cyclesPerMinute, result.Nodes[2].CyclesPerMinute, 1e-9)
+		})
+	}
+}
+
+func TestCalculateWithTransports(t *testing.T) {
+	slowBelt := models.NewTransport("Slow Belt", "", models.ItemKindSolid, 0.25)
+	belt := models.NewTransport("Belt", "", models.ItemKindSolid, 0.4)
+	fastBelt := models.NewTransport("Fast Belt", "", models.ItemKindSolid, 1)
+
+	testCases := []struct {
+		name          string
+		pipeline      *models.Pipeline
+		capacity      Capacity
+		expectedFlows []float64
+		expectedLinks []LinkLoad
+	}{
+		{
+			name: "transports cap the flow and flag saturated links",
+			pipeline: newRoutedPipeline(1000,
+				models.NewPipelineConnection(0, nil, 0, 0, 0, slowBelt),
+				models.NewPipelineConnection(0, nil, 0, 0, 0, fastBelt),
+			),
+			expectedFlows: []float64{15, 30},
+			expectedLinks: []LinkLoad{
+				{SourceNodeID: 1, TargetNodeID: 2, PerMinute: 15, CapacityPerMinute: 15, Saturated: true},
+				{SourceNodeID: 1, TargetNodeID: 3, PerMinute: 30, CapacityPerMinute: 60},
+			},
+		},
+		{
+			name: "transports take precedence over the default belt capacity",
+			pipeline: newRoutedPipeline(1000,
+				models.NewPipelineConnection(0, nil, 0, 0, 0, belt),
+				models.NewPipelineConnection(0, nil, 0, 0, 0, nil),
+			),
+			capacity:      Capacity{Belt: 12},
+			expectedFlows: []float64{24, 12},
+			expectedLinks: []LinkLoad{
+				{SourceNodeID: 1, TargetNodeID: 2, PerMinute: 24, CapacityPerMinute: 24, Saturated: true},
+				{SourceNodeID: 1, TargetNodeID: 3, PerMinute: 12, CapacityPerMinute: 12, Saturated: true},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := CalculateWithCapacity(tc.pipeline, tc.capacity)
+			require.NoError(t, err)
+
+			flows := make(map[int]float64)
+			for _, edge := range result.Edges {
+				flows[edge.TargetNodeID] += edge.PerMinute
+			}
+			for i, expected := range tc.expectedFlows {
+				assert.InDelta(t, expected, flows[i+2], 1e-9, "flow into node %d", i+2)
+			}
+
+			require.Len(t, result.Links, len(tc.expectedLinks))
+			for i, expected := range tc.expectedLinks {
+				link := result.Links[i]
+				assert.Equal(t, expected.SourceNodeID, link.SourceNodeID)
+				assert.Equal(t, expected.TargetNodeID, link.TargetNodeID)
+				assert.Equal(t, models.ItemKindSolid, link.Kind)
+				assert.InDelta(t, expected.PerMinute, link.PerMinute, 1e-9)
+				assert.InDelta(t, expected.CapacityPerMinute, link.CapacityPerMinute, 1e-9)
+				assert.Equal(t, expected.Saturated, link.Saturated, "saturation of link to node %d", link.TargetNodeID)
+			}
+		})
+	}
+}
+
+func TestCalculateUsesExpectedQuantities(t *testing.T) {
+	// The furnace yields a plate from 40% of its cycles
+	furnace := models.NewFacility("Furnace", "", 2000)
+	furnace.AddInputRequirement(models.NewInputRequirement(ore, 1))
+	furnace.AddOutputDefinition(models.NewChanceOutputDefinition(plate, 1, 0.4))
+	assembler := models.NewFacility("Assembler", "", 1000)
+	assembler.AddInputRequirement(models.NewInputRequirement(plate, 1))
+
+	pipeline := models.NewPipeline("Test Pipeline", "")
+	minerNode := models.NewPipelineNode(newMiner(1000), 1, 1)
+	pipeline.AddNode(minerNode)
+	furnaceNode := models.NewPipelineNode(furnace, 1, 1)
+	pipeline.AddNode(furnaceNode)
+	assemblerNode := models.NewPipelineNode(assembler, 1, 1)
+	pipeline.AddNode(assemblerNode)
+	minerNode.AddNextNodeID(furnaceNode.ID())
+	furnaceNode.AddNextNodeID(assemblerNode.ID())
+
+	result, err := Calculate(pipeline)
+	require.NoError(t, err)
+
+	assert.InDelta(t, 30, result.Nodes[2].CyclesPerMinute, 1e-9)
+	assert.InDelta(t, 12, result.Nodes[2].Produced[plate.ID()], 1e-9)
+	assert.InDelta(t, 12, result.Nodes[3].CyclesPerMinute, 1e-9)
+	assert.InDelta(t, 0.2, result.Nodes[3].Utilization, 1e-9)
+}
+
+func TestCalculateWithSourcesAndSinks(t *testing.T) {
+	t.Run("rate-limited source feeds an unlimited sink", func(t *testing.T) {
+		pipeline := models.NewPipeline("Test Pipeline", "")
+		source := models.NewSourceNode(ore, 20)
+		pipeline.AddNode(source)
+		furnace := models.NewPipelineNode(newFurnace(), 1, 1)
+		pipeline.AddNode(furnace)
+		sink := models.NewSinkNode(plate, 0)
+		pipeline.AddNode(sink)
+		source.AddNextNodeID(furnace.ID())
+		furnace.AddNextNodeID(sink.ID())
+
+		result, err := Calculate(pipeline)
+		require.NoError(t, err)
+
+		assert.InDelta(t, 20, result.Nodes[1].CyclesPerMinute, 1e-9)
+		assert.InDelta(t, 1, result.Nodes[1].Utilization, 1e-9)
+		assert.InDelta(t, 20, result.Nodes[2].CyclesPerMinute, 1e-9)
+		assert.Equal(t, ore.ID(), result.Nodes[2].LimitingItemID)
+		assert.InDelta(t, 20, result.Nodes[3].CyclesPerMinute, 1e-9)
+		assert.Zero(t, result.Nodes[3].Utilization)
+		assert.Zero(t, result.Items[plate.ID()].SurplusPerMinute)
+		assert.Zero(t, result.Items[plate.ID()].DeficitPerMinute)
+	})
+
+	t.Run("unlimited source supplies what the furnaces take", func(t *testing.T) {
+		pipeline := models.NewPipeline("Test Pipeline", "")
+		source := models.NewSourceNode(ore, 0)
+		pipeline.AddNode(source)
+		sink := models.NewSinkNode(plate, 45)
+		for i := 0; i < 2; i++ {
+			furnace := models.NewPipelineNode(newFurnace(), 1, 1)
+			pipeline.AddNode(furnace)
+			source.AddNextNodeID(furnace.ID())
+			furnace.AddNextNodeID(4)
+		}
+		pipeline.AddNode(sink)
+
+		result, err := Calculate(pipeline)
+		require.NoError(t, err)
+
+		assert.InDelta(t, 60, result.Nodes[1].CyclesPerMinute, 1e-9)
+		assert.InDelta(t, 60, result.Nodes[1].Produced[ore.ID()], 1e-9)
+		assert.InDelta(t, 30, result.Nodes[2].CyclesPerMinute, 1e-9)
+		assert.InDelta(t, 30, result.Nodes[3].CyclesPerMinute, 1e-9)
+		assert.InDelta(t, 45, result.Nodes[4].CyclesPerMinute, 1e-9)
+		assert.InDelta(t, 15, result.Items[plate.ID()].SurplusPerMinute, 1e-9)
+	})
+
+	t.Run("demand of a sink above its supply is a deficit", func(t *testing.T) {
+		pipeline := models.NewPipeline("Test Pipeline", "")
+		miner := models.NewPipelineNode(newMiner(2000), 1, 1)
+		pipeline.AddNode(miner)
+		sink := models.NewSinkNode(ore, 50)
+		pipeline.AddNode(sink)
+		miner.AddNextNodeID(sink.ID())
+
+		result, err := Calculate(pipeline)
+		require.NoError(t, err)
+
+		assert.InDelta(t, 30, result.Nodes[2].CyclesPerMinute, 1e-9)
+		assert.InDelta(t, 0.6, result.Nodes[2].Utilization, 1e-9)
+		assert.InDelta(t, 20, result.Items[ore.ID()].DeficitPerMinute, 1e-9)
+	})
+
+	t.Run("unlimited source into unlimited sink is unbounded", func(t *testing.T) {
+		pipeline := models.NewPipeline("Test Pipeline", "")
+		source := models.NewSourceNode(ore, 0)
+		pipeline.AddNode(source)
+		sink := models.NewSinkNode(ore, 0)
+		pipeline.AddNode(sink)
+		source.AddNextNodeID(sink.ID())
+
+		_, err := Calculate(pipeline)
+		assert.ErrorIs(t, err, ErrUnboundedFlow)
+	})
+}
+
+func TestCalculateBalancesRecycleLoops(t *testing.T) {
+	slag := models.NewItemFromParams(3, "Slag", "", models.ItemKindSolid)
+
+	testCases := []struct {
+		name          string
+		recyclerTime  int64
+		smelterCycles float64
+		recycled      float64
+		slagSurplus   float64
+	}{
+		{
+			name:          "recycler keeps up with the slag",
+			recyclerTime:  500,
+			smelterCycles: 60,
+			recycled:      60,
+		},
+		{
+			name:          "slow recycler leaves slag over",
+			recyclerTime:  2000,
+			smelterCycles: 45,
+			recycled:      30,
+			slagSurplus:   15,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// The smelter needs two ore per plate and returns one as slag, which the recycler
+			// turns back into ore
+			smelter := models.NewFacility("Smelter", "", 500)
+			smelter.AddInputRequirement(models.NewInputRequirement(ore, 2))
+			smelter.AddOutputDefinition(models.NewOutputDefinition(plate, 1))
+			smelter.AddOutputDefinition(models.NewOutputDefinition(slag, 1))
+			recycler := models.NewFacility("Recycler", "", tc.recyclerTime)
+			recycler.AddInputRequirement(models.NewInputRequirement(slag, 1))
+			recycler.AddOutputDefinition(models.NewOutputDefinition(ore, 1))
+
+			pipeline := models.NewPipeline("Test Pipeline", "")
+			minerNode := models.NewPipelineNode(newMiner(1000), 1, 1)
+			pipeline.AddNode(minerNode)
+			smelterNode := models.NewPipelineNode(smelter, 1, 1)
+			pipeline.AddNode(smelterNode)
+			recyclerNode := models.NewPipelineNode(recycler, 1, 1)
+			pipeline.AddNode(recyclerNode)
+			sink := models.NewSinkNode(plate, 0)
+			pipeline.AddNode(sink)
+			minerNode.AddNextNodeID(smelterNode.ID())
+			smelterNode.AddNextNodeID(recyclerNode.ID())
+			smelterNode.AddNextNodeID(sink.ID())
+			recyclerNode.AddNextNodeID(smelterNode.ID())
+
+			result, err := Calculate(pipeline)
+			require.NoError(t, err)
+
+			assert.InDelta(t, 60, result.Nodes[1].CyclesPerMinute, 1e-9)
+			assert.InDelta(t, tc.smelterCycles, result.Nodes[2].CyclesPerMinute, 1e-9)
+			assert.Equal(t, ore.ID(), result.Nodes[2].LimitingItemID)
+			assert.InDelta(t, tc.recycled, result.Nodes[3].CyclesPerMinute, 1e-9)
+			assert.InDelta(t, tc.smelterCycles, result.Output[plate.ID()], 1e-9)
+			assert.InDelta(t, tc.slagSurplus, result.Items[slag.ID()].SurplusPerMinute, 1e-9)
+			assert.InDelta(t, tc.slagSurplus, result.Output[slag.ID()], 1e-9)
+			// The miner supplies most of the ore the smelter is short of
+			assert.Equal(t, 1, result.LimitingNodeID)
+
+			recycled := 0.0
+			for _, edge := range result.Edges {
+				if edge.SourceNodeID == 3 && edge.TargetNodeID == 2 {
+					recycled += edge.PerMinute
+				}
+			}
+			assert.InDelta(t, tc.recycled, recycled, 1e-9)
+
+			_, err = AnalyzeBottlenecks(pipeline, Capacity{})
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestCalculateRejectsInvalidPipelines(t *testing.T) {
+	testCases := []struct {
+		name     string
+		pipeline func() *models.Pipeline
+		expected error
+	}{
+		{
+			name: "non-positive processing time",
+			pipeline: func() *models.Pipeline {
+				return newTestPipeline(0, 1)
+			},
+		},
+		{
+			name: "facility node without a facility",
+			pipeline: func() *models.Pipeline {
+				pipeline := newTestPipeline(1000, 1)
+				pipeline.AddNode(models.NewPipelineNode(nil, 1, 1))
+				return pipeline
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Calculate(tc.pipeline())
+			require.Error(t, err)
+			if tc.expected != nil {
+				assert.ErrorIs(t, err, tc.expected)
+			}
+		})
+	}
+}
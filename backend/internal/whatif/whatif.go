@@ -0,0 +1,212 @@
+// Package whatif evaluates hypothetical changes to a pipeline against its current design
+// without modifying the pipeline itself
+package whatif
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/fasim/backend/internal/models"
+	"github.com/fasim/backend/internal/throughput"
+)
+
+// ErrInvalidScenario is returned when a scenario refers to facilities, nodes or connections
+// the pipeline does not have, or sets values a pipeline cannot run with
+var ErrInvalidScenario = errors.New("invalid scenario")
+
+// Connection identifies the connections from one node to another
+type Connection struct {
+	SourceNodeID int
+	TargetNodeID int
+}
+
+// Scenario lists hypothetical changes to a pipeline
+type Scenario struct {
+	// ProcessingTimes replaces the processing time in milliseconds of facilities used by
+	// the pipeline, keyed by facility ID
+	ProcessingTimes map[int]int64
+	// AddedInstances adds machines to facility nodes, keyed by node ID. Negative counts
+	// remove machines, but every node keeps at least one.
+	AddedInstances map[int]int
+	// RemovedConnections takes out every connection from the source to the target node
+	RemovedConnections []Connection
+}
+
+// Apply returns a copy of the pipeline with the changes of the scenario. The pipeline and
+// its facilities are left untouched.
+func Apply(pipeline *models.Pipeline, scenario Scenario) (*models.Pipeline, error) {
+	nodes := pipeline.Nodes()
+
+	used := make(map[int]bool)
+	for _, node := range nodes {
+		if node.Facility() != nil {
+			used[node.Facility().ID()] = true
+		}
+	}
+	for facilityID, processingTime := range scenario.ProcessingTimes {
+		if !used[facilityID] {
+			return nil, fmt.Errorf("%w: facility %d is not used by the pipeline", ErrInvalidScenario, facilityID)
+		}
+		if processingTime <= 0 {
+			return nil, fmt.Errorf("%w: processing time of facility %d must be positive", ErrInvalidScenario, facilityID)
+		}
+	}
+	for nodeID, added := range scenario.AddedInstances {
+		node, ok := nodes[nodeID]
+		if !ok || node.Facility() == nil {
+			return nil, fmt.Errorf("%w: node %d is not a facility node of the pipeline", ErrInvalidScenario, nodeID)
+		}
+		if node.InstanceCount()+added < 1 {
+			return nil, fmt.Errorf("%w: node %d must keep at least one machine", ErrInvalidScenario, nodeID)
+		}
+	}
+	removed := make(map[Connection]bool, len(scenario.RemovedConnections))
+	for _, connection := range scenario.RemovedConnections {
+		source, ok := nodes[connection.SourceNodeID]
+		if !ok || !connects(source, connection.TargetNodeID) {
+			return nil, fmt.Errorf("%w: node %d is not connected to node %d", ErrInvalidScenario, connection.SourceNodeID, connection.TargetNodeID)
+		}
+		removed[connection] = true
+	}
+
+	// Nodes sharing a facility keep sharing its replacement
+	facilities := make(map[int]*models.Facility)
+	for facilityID := range scenario.ProcessingTimes {
+		for _, node := range nodes {
+			if facility := node.Facility(); facility != nil && facility.ID() == facilityID {
+				facilities[facilityID] = facility.WithProcessingTime(scenario.ProcessingTimes[facilityID])
+				break
+			}
+		}
+	}
+
+	modified := models.NewPipelineFromParams(pipeline.ID(), pipeline.Name(), pipeline.Description(), make(map[int]*models.PipelineNode, len(nodes)))
+	for id, node := range nodes {
+		connections := make([]*models.PipelineConnection, 0, len(node.Connections()))
+		for _, conn := range node.Connections() {
+			if !removed[Connection{SourceNodeID: id, TargetNodeID: conn.TargetNodeID()}] {
+				connections = append(connections, conn)
+			}
+		}
+
+		if node.IsBoundary() {
+			modified.AddNode(models.NewBoundaryNodeFromParams(id, node.Kind(), node.Item(), node.PerMinute(), node.Buffers(), connections))
+			continue
+		}
+		facility := node.Facility()
+		if facility == nil {
+			return nil, fmt.Errorf("node %d has no facility", id)
+		}
+		if replacement, ok := facilities[facility.ID()]; ok {
+			facility = replacement
+		}
+		instanceCount := node.InstanceCount() + scenario.AddedInstances[id]
+		modified.AddNode(models.NewPipelineNodeFromParams(id, facility, instanceCount, node.ClockSpeed(), node.Buffers(), connections))
+	}
+	return modified, nil
+}
+
+// connects reports whether the node has a connection to the target node
+func connects(node *models.PipelineNode, targetNodeID int) bool {
+	for _, conn := range node.Connections() {
+		if conn.TargetNodeID() == targetNodeID {
+			return true
+		}
+	}
+	return false
+}
+
+// RateChange compares the output of an item in items per minute
+type RateChange struct {
+	ItemID   int
+	Baseline float64
+	Modified float64
+}
+
+// NodeChange compares the steady-state operation of a node
+type NodeChange struct {
+	NodeID                  int
+	BaselineCyclesPerMinute float64
+	CyclesPerMinute         float64
+	BaselineUtilization     float64
+	Utilization             float64
+	BaselineInstanceCount   int
+	InstanceCount           int
+	BaselineLimitedByNodeID int
+	LimitedByNodeID         int
+}
+
+// Report compares the steady-state rates of a pipeline before and after the changes of a
+// scenario
+type Report struct {
+	// BaselineLimitingNodeID and LimitingNodeID are the nodes bounding the output of the
+	// pipeline before and after the changes
+	BaselineLimitingNodeID int
+	LimitingNodeID         int
+	// Output compares the items leaving the pipeline, ordered by item ID
+	Output []*RateChange
+	// Nodes compares every node of the pipeline, ordered by node ID
+	Nodes []*NodeChange
+}
+
+// Analyze computes the steady-state rates of the pipeline as CalculateWithCapacity does,
+// once as it is and once with the changes of the scenario, and compares them
+func Analyze(pipeline *models.Pipeline, scenario Scenario, capacity throughput.Capacity) (*Report, error) {
+	modified, err := Apply(pipeline, scenario)
+	if err != nil {
+		return nil, err
+	}
+
+	before, err := throughput.CalculateWithCapacity(pipeline, capacity)
+	if err != nil {
+		return nil, err
+	}
+	after, err := throughput.CalculateWithCapacity(modified, capacity)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		BaselineLimitingNodeID: before.LimitingNodeID,
+		LimitingNodeID:         after.LimitingNodeID,
+		Output:                 make([]*RateChange, 0, len(after.Output)),
+		Nodes:                  make([]*NodeChange, 0, len(after.Nodes)),
+	}
+	itemIDs := make(map[int]bool)
+	for itemID := range before.Output {
+		itemIDs[itemID] = true
+	}
+	for itemID := range after.Output {
+		itemIDs[itemID] = true
+	}
+	for itemID := range itemIDs {
+		report.Output = append(report.Output, &RateChange{
+			ItemID:   itemID,
+			Baseline: before.Output[itemID],
+			Modified: after.Output[itemID],
+		})
+	}
+	sort.Slice(report.Output, func(i, j int) bool {
+		return report.Output[i].ItemID < report.Output[j].ItemID
+	})
+
+	for id, rate := range after.Nodes {
+		baseline := before.Nodes[id]
+		report.Nodes = append(report.Nodes, &NodeChange{
+			NodeID:                  id,
+			BaselineCyclesPerMinute: baseline.CyclesPerMinute,
+			CyclesPerMinute:         rate.CyclesPerMinute,
+			BaselineUtilization:     baseline.Utilization,
+			Utilization:             rate.Utilization,
+			BaselineInstanceCount:   pipeline.Nodes()[id].InstanceCount(),
+			InstanceCount:           modified.Nodes()[id].InstanceCount(),
+			BaselineLimitedByNodeID: baseline.LimitedByNodeID,
+			LimitedByNodeID:         rate.LimitedByNodeID,
+		})
+	}
+	sort.Slice(report.Nodes, func(i, j int) bool {
+		return report.Nodes[i].NodeID < report.Nodes[j].NodeID
+	})
+	return report, nil
+}
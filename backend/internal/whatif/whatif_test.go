@@ -0,0 +1,152 @@
+package whatif
+
+import (
+	"testing"
+
+	"github.com/fasim/backend/internal/models"
+	"github.com/fasim/backend/internal/throughput"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	ore   = models.NewItemFromParams(1, "Ore", "", models.ItemKindSolid)
+	plate = models.NewItemFromParams(2, "Plate", "", models.ItemKindSolid)
+)
+
+func TestAnalyze(t *testing.T) {
+	type expectedNode struct {
+		cyclesPerMinute float64
+		utilization     float64
+		instanceCount   int
+	}
+
+	testCases := []struct {
+		name           string
+		scenario       Scenario
+		limitingNodeID int
+		output         map[int]float64
+		nodes          map[int]expectedNode
+	}{
+		{
+			name:           "no changes",
+			limitingNodeID: 2,
+			output:         map[int]float64{plate.ID(): 30},
+			nodes: map[int]expectedNode{
+				1: {cyclesPerMinute: 60, utilization: 1, instanceCount: 1},
+				2: {cyclesPerMinute: 30, utilization: 1, instanceCount: 1},
+			},
+		},
+		{
+			name:           "added machines move the bottleneck upstream",
+			scenario:       Scenario{AddedInstances: map[int]int{2: 2}},
+			limitingNodeID: 1,
+			output:         map[int]float64{plate.ID(): 60},
+			nodes: map[int]expectedNode{
+				1: {cyclesPerMinute: 60, utilization: 1, instanceCount: 1},
+				2: {cyclesPerMinute: 60, utilization: 2.0 / 3, instanceCount: 3},
+			},
+		},
+		{
+			name:           "slower machines",
+			scenario:       Scenario{ProcessingTimes: map[int]int64{2: 4000}},
+			limitingNodeID: 2,
+			output:         map[int]float64{plate.ID(): 15},
+			nodes: map[int]expectedNode{
+				1: {cyclesPerMinute: 60, utilization: 1, instanceCount: 1},
+				2: {cyclesPerMinute: 15, utilization: 1, instanceCount: 1},
+			},
+		},
+		{
+			name:           "removed connection starves the furnace",
+			scenario:       Scenario{RemovedConnections: []Connection{{SourceNodeID: 1, TargetNodeID: 2}}},
+			limitingNodeID: 1,
+			output:         map[int]float64{ore.ID(): 60, plate.ID(): 0},
+			nodes: map[int]expectedNode{
+				1: {cyclesPerMinute: 60, utilization: 1, instanceCount: 1},
+				2: {cyclesPerMinute: 0, utilization: 0, instanceCount: 1},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// A miner yielding 60 ore per minute feeds a furnace smelting 30, leaving the ore
+			// the furnace cannot take as surplus
+			miner := models.NewFacilityFromParams(1, "Miner", "", nil,
+				[]*models.OutputDefinition{models.NewOutputDefinition(ore, 1)},
+				1000, nil, nil, models.PowerProfile{})
+			furnace := models.NewFacilityFromParams(2, "Furnace", "",
+				[]*models.InputRequirement{models.NewInputRequirement(ore, 1)},
+				[]*models.OutputDefinition{models.NewOutputDefinition(plate, 1)},
+				2000, nil, nil, models.PowerProfile{})
+			pipeline := models.NewPipelineFromParams(1, "Smelting", "", make(map[int]*models.PipelineNode))
+			minerNode := models.NewPipelineNode(miner, 1, 1)
+			pipeline.AddNode(minerNode)
+			furnaceNode := models.NewPipelineNode(furnace, 1, 1)
+			pipeline.AddNode(furnaceNode)
+			minerNode.AddNextNodeID(furnaceNode.ID())
+
+			report, err := Analyze(pipeline, tc.scenario, throughput.Capacity{})
+			require.NoError(t, err)
+
+			assert.Equal(t, 2, report.BaselineLimitingNodeID)
+			assert.Equal(t, tc.limitingNodeID, report.LimitingNodeID)
+
+			output := make(map[int]float64)
+			for _, change := range report.Output {
+				output[change.ItemID] = change.Modified
+				if change.ItemID == plate.ID() {
+					assert.InDelta(t, 30, change.Baseline, 1e-9)
+				}
+			}
+			assert.InDeltaMapValues(t, tc.output, output, 1e-9)
+
+			require.Len(t, report.Nodes, len(tc.nodes))
+			for _, node := range report.Nodes {
+				expected := tc.nodes[node.NodeID]
+				assert.InDelta(t, expected.cyclesPerMinute, node.CyclesPerMinute, 1e-9, "node %d", node.NodeID)
+				assert.InDelta(t, expected.utilization, node.Utilization, 1e-9, "node %d", node.NodeID)
+				assert.Equal(t, expected.instanceCount, node.InstanceCount, "node %d", node.NodeID)
+				assert.Equal(t, 1, node.BaselineInstanceCount)
+			}
+
+			// The stored design is left as it was
+			assert.Equal(t, int64(2000), pipeline.Nodes()[2].Facility().ProcessingTime())
+			assert.Equal(t, 1, pipeline.Nodes()[2].InstanceCount())
+			assert.Len(t, pipeline.Nodes()[1].Connections(), 1)
+		})
+	}
+}
+
+func TestApplyRejectsInvalidScenarios(t *testing.T) {
+	// A lone miner, whose node and facility both have ID 1
+	miner := models.NewFacilityFromParams(1, "Miner", "", nil,
+		[]*models.OutputDefinition{models.NewOutputDefinition(ore, 1)},
+		1000, nil, nil, models.PowerProfile{})
+	pipeline := models.NewPipelineFromParams(1, "Mining", "", make(map[int]*models.PipelineNode))
+	pipeline.AddNode(models.NewPipelineNode(miner, 1, 1))
+
+	testCases := []struct {
+		name     string
+		scenario Scenario
+	}{
+		{"facility not in the pipeline", Scenario{ProcessingTimes: map[int]int64{2: 1000}}},
+		{"non-positive processing time", Scenario{ProcessingTimes: map[int]int64{1: 0}}},
+		{"missing node", Scenario{AddedInstances: map[int]int{2: 1}}},
+		{"node without machines", Scenario{AddedInstances: map[int]int{1: -1}}},
+		{"missing connection", Scenario{RemovedConnections: []Connection{{SourceNodeID: 1, TargetNodeID: 2}}}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Apply(pipeline, tc.scenario)
+			assert.ErrorIs(t, err, ErrInvalidScenario)
+		})
+	}
+
+	// Nodes whose facility is gone cannot be copied
+	pipeline.AddNode(models.NewPipelineNode(nil, 1, 1))
+	_, err := Apply(pipeline, Scenario{})
+	assert.Error(t, err)
+}